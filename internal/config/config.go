@@ -1,10 +1,23 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
+
+	"cron-runner/internal/redact"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 // Config holds all configuration for the cron-runner service.
@@ -12,51 +25,347 @@ import (
 // internal/jobs/registry.go — only global service settings live here.
 type Config struct {
 	// Backend connection
-	BackendURL   string
-	PipelineAuth string
+	BackendURL       string
+	PipelineAuth     string
+	PipelineAuthFile string // optional; token is read from this file when set
+	APIBasePath      string // optional path prefix prepended to every endpoint (e.g. "/api")
+
+	// Session-cookie auth, used instead of PipelineAuth when SessionLoginPath
+	// is set: credentials are POSTed there and the returned cookie is sent
+	// with every request, logging in again on a 401
+	SessionLoginPath string
+	SessionUsername  string
+	SessionPassword  string
+
+	// Startup compatibility check (skipped when MinBackendVersion is empty)
+	MinBackendVersion  string
+	BackendVersionPath string
+
+	// Startup token check (skipped when TokenIntrospectPath is empty)
+	TokenIntrospectPath string
+	TokenRequiredScope  string
 
 	// Retry settings (used by pipeline client for all requests)
-	MaxRetries     int
-	InitialBackoff time.Duration
-	MaxBackoff     time.Duration
-	BackoffFactor  float64
+	MaxRetries          int
+	InitialBackoff      time.Duration
+	MaxBackoff          time.Duration
+	BackoffFactor       float64
+	Jitter              float64
+	BackoffStrategy     string
+	RetryMaxElapsed     time.Duration
+	RetryIdempotentOnly bool // only retry GET/HEAD or requests with an Idempotency-Key
+
+	// Retryable HTTP statuses; empty = default (429 and all 5xx)
+	RetryableStatusCodes []int
 
 	// HTTP client settings
-	RequestTimeout time.Duration
+	RequestTimeout     time.Duration
+	TLSSkipVerifyHosts []string // hosts whose TLS certificates are not verified
+	TLSMinVersion      string   // "1.2" or "1.3"
+	DisableKeepAlives  bool     // open a new connection per request, for proxies that break reused ones
+	ProxyURL           string   // PIPELINE_PROXY_URL; overrides HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+
+	// Mutual TLS and private CA for the backend (PEM file paths)
+	TLSClientCert         string // client certificate presented to the backend
+	TLSClientKey          string // private key for TLSClientCert
+	TLSCACert             string // CA bundle trusted in addition to the system roots
+	TLSInsecureSkipVerify bool   // dev only: skip backend certificate verification
+
+	// SSH tunnel to reach the backend through (empty host = direct)
+	SSHTunnelHost            string // host[:port] of the SSH server; port defaults to 22
+	SSHTunnelUser            string
+	SSHTunnelKeyFile         string // private key file (PEM / OpenSSH format)
+	SSHTunnelPassword        string // password auth, instead of or as well as the key
+	SSHTunnelKnownHosts      string // known_hosts file the server key is verified against
+	SSHTunnelInsecureHostKey bool   // dev only: accept any server host key
 
 	// Job polling settings (used by PollTask via pipeline client)
 	PollInitialInterval time.Duration
 	PollMaxInterval     time.Duration
 	PollMaxWaitTime     time.Duration
+	PollProgressTimeout time.Duration // 0 = disabled
+	PollMode            string        // "poll" (repeated short polls) or "longpoll"
+	PollLongPollTimeout time.Duration // per-request timeout in longpoll mode
+	PollRequestTimeout  time.Duration // per-request timeout for status polls in poll mode
+	PollLogInterval     time.Duration // 0 = log every poll's status
+	PollBackoffFactor   float64       // growth of the poll interval after each poll; 0 = 1.5
+	PollJitter          float64       // fraction (0–1) each poll wait is randomly shortened by
+	JobIDPattern        string        // regex a returned job ID must match before polling; empty = default
+	Treat409AsRunning   bool          // a 409 on start attaches to the running job named in the response
+
+	// A random key sent in this header on every job start, reused across its retries
+	SendIdempotencyKey   bool
+	IdempotencyKeyHeader string
+
+	// Log DNS, connect, TLS and time-to-first-byte timings of each start request
+	HTTPTimingDebug bool
+
+	// This percentage (0–100) of runs goes to CanaryBackendURL, with X-Canary: true
+	CanaryPercent    float64
+	CanaryBackendURL string
+
+	// Per-pipeline status policy: status → "pass", "warn" or "fail".
+	// Unmapped statuses keep the default ("failed" fails, anything else passes).
+	StatusPolicy map[string]string
 
 	// HTTP server
-	HTTPPort string
+	HTTPPort         string
+	BindAddr         string        // interface the server listens on; empty = all
+	TriggerAuthToken string        // bearer token required by /trigger; empty = open
+	TriggerDebounce  time.Duration // repeat /trigger calls within this share one run; 0 = off
+
+	// A -pipeline run pushes its metrics here before exiting ("" = no push)
+	PushgatewayURL     string
+	PushgatewayTimeout time.Duration
+
+	// Serve net/http/pprof on a separate port (off by default)
+	EnablePprof   bool
+	PprofPort     string
+	PprofBindAddr string // interface the pprof listener uses; loopback by default
+
+	// Unix domain socket also serving the HTTP endpoints (empty = TCP only)
+	HTTPUnixSocket string
+
+	// /ready fails after this many consecutive failed runs (0 = never)
+	MaxConsecutiveFailures int
+
+	// /health and /ready responses are reused for this long (0 = no caching)
+	ProbeCacheTTL time.Duration
 
 	// Graceful shutdown drain window
 	DrainTimeout time.Duration
 
+	// Scheduling cool-down after consecutive failures (0 = disabled)
+	CooldownAfter int
+	CooldownBase  time.Duration
+	CooldownMax   time.Duration
+
+	// Recent runs kept per job and reported by /status
+	HistorySize int
+
+	// Job state is saved here after every run and restored at startup ("" = disabled)
+	StateFile string
+
+	// A job with no success for this long is forced to run (0 = disabled)
+	WatchdogMaxInterval time.Duration
+
+	// /livez fails once a polled run shows no status change for this long
+	// (0 = disabled)
+	LivenessStuckThreshold time.Duration
+
+	// Runs are refused while free disk at ResourceDiskPath is below
+	// ResourceMinDiskFreeMB or process memory is above ResourceMaxMemoryMB
+	// (0 = not checked)
+	ResourceDiskPath      string
+	ResourceMinDiskFreeMB int
+	ResourceMaxMemoryMB   int
+
+	// Expected run duration; runs deviating by more than DurationDeviationPct
+	// percent either way are flagged (0 = disabled)
+	ExpectedDuration     time.Duration
+	DurationDeviationPct float64
+
+	// Self-initiated graceful shutdown after this long (0 = run forever)
+	MaxLifetime time.Duration
+
+	// Circuit breaker around backend calls (threshold 0 = disabled)
+	CircuitFailureThreshold int
+	CircuitCooldown         time.Duration
+
+	// Dependency polled for a 2xx before each trigger (empty = disabled)
+	WaitForURL     string
+	WaitForTimeout time.Duration
+
+	// Log the requests runs would send instead of calling the backend
+	DryRun bool
+
+	// Startup checks (backend version, token scope) retry connection
+	// failures this many times, waiting StartupCheckBackoff and doubling
+	StartupCheckRetries int
+	StartupCheckBackoff time.Duration
+
+	// Webhook POSTed when a polled pipeline run fails (empty = disabled)
+	FailureWebhookURL string
+
+	// Failed pipelines matching a route's pattern alert its URL instead of
+	// FailureWebhookURL; first match wins
+	AlertRoutes []AlertRoute
+
+	// Record each run outcome as a Kubernetes Event on the runner's pod
+	K8sEventsEnabled bool
+
+	// Write a run_completed JSON event to stdout after each run
+	RunEventsEnabled bool
+
+	// OTLP/HTTP endpoint for OpenTelemetry traces (empty = tracing off)
+	OtelEndpoint string
+
+	// Also export each run's result as an OTel log record to OtelEndpoint
+	OtelResultLogs bool
+
+	// Per-run manifest output directory (empty = disabled)
+	RunManifestDir string
+
+	// Gzip persisted run artifacts (manifests are written as .json.gz)
+	PersistCompress bool
+
+	// Extra key patterns (path.Match globs, any case) whose values are
+	// redacted from manifests and log lines, on top of authorization/token
+	RedactKeys []string
+
+	// Run metadata (RUN_METADATA plus TRIGGER_SOURCE/TRIGGER_COMMIT), attached
+	// to logs, push reports and manifests for post-hoc analysis
+	RunMetadata map[string]string
+
 	// Logging
-	LogLevel string
-	LogJSON  bool
+	LogLevel        string
+	LogJSON         bool   // legacy; only consulted when LOG_FORMAT is unset
+	LogFormat       string // "json", "console" or "logfmt"
+	LogDurationUnit string // unit for Dur log fields: "ms", "s", "us", "ns"
+	LogRunSummary   bool   // log a compact one-line summary after each polled run
 }
 
-// Load reads configuration from environment variables with sensible defaults.
+// Load reads configuration from environment variables with sensible
+// defaults, layered over the YAML file named by CONFIG_FILE if set.
 func Load() (*Config, error) {
+	return LoadFile(os.Getenv("CONFIG_FILE"))
+}
+
+// LoadFile is Load with the config file at path (empty = none). Environment
+// variables take precedence over the file, and the file over defaults.
+func LoadFile(path string) (*Config, error) {
+	var values map[string]string
+	if path != "" {
+		var err error
+		if values, err = readFile(path); err != nil {
+			return nil, err
+		}
+	}
+	getenv := layered(values)
+
 	cfg := &Config{
-		BackendURL:          getEnvOrDefault("BACKEND_URL", "https://api.courtvision.dev"),
-		PipelineAuth:        os.Getenv("PIPELINE_API_TOKEN"),
-		MaxRetries:          getEnvIntOrDefault("MAX_RETRIES", 3),
-		InitialBackoff:      getEnvDurationOrDefault("INITIAL_BACKOFF", 2*time.Second),
-		MaxBackoff:          getEnvDurationOrDefault("MAX_BACKOFF", 30*time.Second),
-		BackoffFactor:       getEnvFloatOrDefault("BACKOFF_FACTOR", 2.0),
-		RequestTimeout:      getEnvDurationOrDefault("REQUEST_TIMEOUT", 30*time.Second),
-		PollInitialInterval: getEnvDurationOrDefault("POLL_INITIAL_INTERVAL", 5*time.Second),
-		PollMaxInterval:     getEnvDurationOrDefault("POLL_MAX_INTERVAL", 30*time.Second),
-		PollMaxWaitTime:     getEnvDurationOrDefault("POLL_MAX_WAIT_TIME", 15*time.Minute),
-		HTTPPort:            getEnvOrDefault("HTTP_PORT", "8082"),
-		DrainTimeout:        getEnvDurationOrDefault("DRAIN_TIMEOUT", 30*time.Second),
-		LogLevel:            getEnvOrDefault("LOG_LEVEL", "info"),
-		LogJSON:             getEnvBoolOrDefault("LOG_JSON", true),
+		BackendURL:          getenv.orDefault("BACKEND_URL", "https://api.courtvision.dev"),
+		PipelineAuth:        getenv("PIPELINE_API_TOKEN"),
+		PipelineAuthFile:    getenv("PIPELINE_API_TOKEN_FILE"),
+		APIBasePath:         getenv("API_BASE_PATH"),
+		SessionLoginPath:    getenv("SESSION_LOGIN_ENDPOINT"),
+		SessionUsername:     getenv("SESSION_USERNAME"),
+		SessionPassword:     getenv("SESSION_PASSWORD"),
+		MinBackendVersion:   getenv("MIN_BACKEND_VERSION"),
+		BackendVersionPath:  getenv.orDefault("BACKEND_VERSION_ENDPOINT", "/version"),
+		TokenIntrospectPath: getenv("TOKEN_INTROSPECT_ENDPOINT"),
+		TokenRequiredScope:  getenv.orDefault("TOKEN_REQUIRED_SCOPE", "pipelines:trigger"),
+		MaxRetries:          getenv.intOrDefault("MAX_RETRIES", 3),
+		InitialBackoff:      getenv.durationOrDefault("INITIAL_BACKOFF", 2*time.Second),
+		MaxBackoff:          getenv.durationOrDefault("MAX_BACKOFF", 30*time.Second),
+		BackoffFactor:       getenv.floatOrDefault("BACKOFF_FACTOR", 2.0),
+		Jitter:              getenv.floatOrDefault("JITTER", 0.2),
+		RetryMaxElapsed:     getenv.durationOrDefault("RETRY_MAX_ELAPSED", 0),
+		RetryIdempotentOnly: getenv.boolOrDefault("RETRY_IDEMPOTENT_ONLY", false),
+		RequestTimeout:      getenv.durationOrDefault("REQUEST_TIMEOUT", 30*time.Second),
+		TLSSkipVerifyHosts:  getenv.list("TLS_SKIP_VERIFY_HOSTS"),
+		TLSMinVersion:       getenv.orDefault("TLS_MIN_VERSION", "1.2"),
+		PollInitialInterval: getenv.durationOrDefault("POLL_INITIAL_INTERVAL", 5*time.Second),
+		PollMaxInterval:     getenv.durationOrDefault("POLL_MAX_INTERVAL", 30*time.Second),
+		PollBackoffFactor:   getenv.floatOrDefault("POLL_BACKOFF_FACTOR", 1.5),
+		PollJitter:          getenv.floatOrDefault("POLL_JITTER", 0),
+		PollMaxWaitTime:     getenv.durationOrDefault("POLL_MAX_WAIT_TIME", 15*time.Minute),
+		PollProgressTimeout: getenv.durationOrDefault("POLL_PROGRESS_TIMEOUT", 0),
+		PollMode:            getenv.orDefault("POLL_MODE", "poll"),
+		PollLongPollTimeout: getenv.durationOrDefault("POLL_LONGPOLL_TIMEOUT", 60*time.Second),
+		PollRequestTimeout:  getenv.durationOrDefault("POLL_REQUEST_TIMEOUT", 30*time.Second),
+		PollLogInterval:     getenv.durationOrDefault("POLL_LOG_INTERVAL", 0),
+		JobIDPattern:        getenv("JOB_ID_PATTERN"),
+		Treat409AsRunning:   getenv.boolOrDefault("TREAT_409_AS_RUNNING", false),
+		StatusPolicy:        getenv.mapping("PIPELINE_STATUS_POLICY"),
+		HTTPPort:            getenv.orDefault("HTTP_PORT", "8082"),
+		TriggerAuthToken:    getenv("TRIGGER_AUTH_TOKEN"),
+		DrainTimeout:        getenv.durationOrDefault("DRAIN_TIMEOUT", 30*time.Second),
+		MaxLifetime:         getenv.durationOrDefault("MAX_LIFETIME", 0),
+		CooldownAfter:       getenv.intOrDefault("COOLDOWN_AFTER_FAILURES", 0),
+		CooldownBase:        getenv.durationOrDefault("COOLDOWN_BASE", 5*time.Minute),
+		CooldownMax:         getenv.durationOrDefault("COOLDOWN_MAX", time.Hour),
+		HistorySize:         getenv.intOrDefault("HEALTH_HISTORY_SIZE", 10),
+		StateFile:           getenv("STATE_FILE"),
+		FailureWebhookURL:   getenv("FAILURE_WEBHOOK_URL"),
+		RunManifestDir:      getenv("RUN_MANIFEST_DIR"),
+		K8sEventsEnabled:    getenv.boolOrDefault("K8S_EVENTS_ENABLED", false),
+		RunMetadata:         loadRunMetadata(getenv),
+		LogLevel:            getenv.orDefault("LOG_LEVEL", "info"),
+		LogJSON:             getenv.boolOrDefault("LOG_JSON", true),
+		LogDurationUnit:     getenv.orDefault("LOG_DURATION_UNIT", "ms"),
+		LogRunSummary:       getenv.boolOrDefault("LOG_RUN_SUMMARY", false),
+	}
+
+	cfg.MaxConsecutiveFailures = getenv.intOrDefault("MAX_CONSECUTIVE_FAILURES", 0)
+	cfg.ProbeCacheTTL = getenv.durationOrDefault("PROBE_CACHE_TTL", 0)
+	cfg.HTTPUnixSocket = getenv("HTTP_UNIX_SOCKET")
+	cfg.BindAddr = getenv("BIND_ADDR")
+	cfg.TriggerDebounce = getenv.durationOrDefault("TRIGGER_DEBOUNCE", 0)
+	cfg.PushgatewayURL = getenv("PUSHGATEWAY_URL")
+	cfg.PushgatewayTimeout = getenv.durationOrDefault("PUSHGATEWAY_TIMEOUT", 5*time.Second)
+	cfg.EnablePprof = getenv.boolOrDefault("ENABLE_PPROF", false)
+	cfg.PprofPort = getenv.orDefault("PPROF_PORT", "6060")
+	cfg.PprofBindAddr = getenv.orDefault("PPROF_BIND_ADDR", "127.0.0.1")
+	cfg.DisableKeepAlives = getenv.boolOrDefault("DISABLE_KEEPALIVES", false)
+	cfg.ProxyURL = getenv("PIPELINE_PROXY_URL")
+	cfg.AlertRoutes = loadAlertRoutes(getenv)
+	cfg.DryRun = getenv.boolOrDefault("DRY_RUN", false)
+	cfg.StartupCheckRetries = getenv.intOrDefault("STARTUP_CHECK_RETRIES", 5)
+	cfg.StartupCheckBackoff = getenv.durationOrDefault("STARTUP_CHECK_BACKOFF", 2*time.Second)
+	cfg.PersistCompress = getenv.boolOrDefault("PERSIST_COMPRESS", false)
+	cfg.RedactKeys = getenv.list("REDACT_KEYS")
+	cfg.WatchdogMaxInterval = getenv.durationOrDefault("WATCHDOG_MAX_INTERVAL", 0)
+	cfg.LivenessStuckThreshold = getenv.durationOrDefault("LIVENESS_STUCK_THRESHOLD", 0)
+	cfg.ResourceDiskPath = getenv.orDefault("RESOURCE_DISK_PATH", "/")
+	cfg.ResourceMinDiskFreeMB = getenv.intOrDefault("RESOURCE_MIN_DISK_FREE_MB", 0)
+	cfg.ResourceMaxMemoryMB = getenv.intOrDefault("RESOURCE_MAX_MEMORY_MB", 0)
+	cfg.SSHTunnelHost = getenv("SSH_TUNNEL_HOST")
+	cfg.SSHTunnelUser = getenv("SSH_TUNNEL_USER")
+	cfg.SSHTunnelKeyFile = getenv("SSH_TUNNEL_KEY_FILE")
+	cfg.SSHTunnelPassword = getenv("SSH_TUNNEL_PASSWORD")
+	cfg.SSHTunnelKnownHosts = getenv("SSH_TUNNEL_KNOWN_HOSTS")
+	cfg.SSHTunnelInsecureHostKey = getenv.boolOrDefault("SSH_TUNNEL_INSECURE_HOST_KEY", false)
+	cfg.TLSClientCert = getenv("TLS_CLIENT_CERT")
+	cfg.TLSClientKey = getenv("TLS_CLIENT_KEY")
+	cfg.TLSCACert = getenv("TLS_CA_CERT")
+	cfg.TLSInsecureSkipVerify = getenv.boolOrDefault("TLS_INSECURE_SKIP_VERIFY", false)
+	cfg.BackoffStrategy = getenv.orDefault("BACKOFF_STRATEGY", "exponential")
+	cfg.ExpectedDuration = getenv.durationOrDefault("EXPECTED_DURATION", 0)
+	cfg.DurationDeviationPct = getenv.floatOrDefault("DURATION_DEVIATION_PCT", 50)
+	cfg.RunEventsEnabled = getenv.boolOrDefault("RUN_EVENTS_ENABLED", false)
+	cfg.OtelEndpoint = getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	cfg.OtelResultLogs = getenv.boolOrDefault("OTEL_RESULT_LOGS", false)
+	cfg.CircuitFailureThreshold = getenv.intOrDefault("CIRCUIT_FAILURE_THRESHOLD", 0)
+	cfg.CircuitCooldown = getenv.durationOrDefault("CIRCUIT_COOLDOWN", time.Minute)
+	cfg.WaitForURL = getenv("WAIT_FOR_URL")
+	cfg.WaitForTimeout = getenv.durationOrDefault("WAIT_FOR_TIMEOUT", 5*time.Minute)
+	cfg.SendIdempotencyKey = getenv.boolOrDefault("SEND_IDEMPOTENCY_KEY", true)
+	cfg.IdempotencyKeyHeader = getenv.orDefault("IDEMPOTENCY_KEY_HEADER", "Idempotency-Key")
+	cfg.HTTPTimingDebug = getenv.boolOrDefault("HTTP_TIMING_DEBUG", false)
+	cfg.CanaryPercent = getenv.floatOrDefault("CANARY_PERCENT", 0)
+	cfg.CanaryBackendURL = getenv("CANARY_BACKEND_URL")
+
+	cfg.LogFormat = getenv("LOG_FORMAT")
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = "console"
+		if cfg.LogJSON {
+			cfg.LogFormat = "json"
+		}
+	}
+
+	for _, v := range getenv.list("RETRYABLE_STATUS_CODES") {
+		code, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("RETRYABLE_STATUS_CODES: invalid status code %q", v)
+		}
+		cfg.RetryableStatusCodes = append(cfg.RetryableStatusCodes, code)
+	}
+
+	if cfg.PipelineAuth == "" && cfg.PipelineAuthFile != "" {
+		if b, err := os.ReadFile(cfg.PipelineAuthFile); err == nil {
+			cfg.PipelineAuth = strings.TrimSpace(string(b))
+		}
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -66,26 +375,365 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
-// Validate checks that required configuration is present.
+// Validate checks that required configuration is present, and normalizes
+// BackendURL by stripping trailing slashes.
 func (c *Config) Validate() error {
 	if c.BackendURL == "" {
 		return errors.New("BACKEND_URL environment variable is required")
 	}
-	if c.PipelineAuth == "" {
-		return errors.New("PIPELINE_API_TOKEN environment variable is required")
+	if err := validateBackendURL("BACKEND_URL", c.BackendURL); err != nil {
+		return err
+	}
+	c.BackendURL = strings.TrimRight(c.BackendURL, "/")
+	if c.SessionLoginPath != "" {
+		if c.SessionUsername == "" || c.SessionPassword == "" {
+			return errors.New("SESSION_USERNAME and SESSION_PASSWORD are required when SESSION_LOGIN_ENDPOINT is set")
+		}
+	} else if c.PipelineAuth == "" {
+		return errors.New("PIPELINE_API_TOKEN environment variable (or a readable PIPELINE_API_TOKEN_FILE) is required")
+	}
+	if c.PollMode != "poll" && c.PollMode != "longpoll" {
+		return fmt.Errorf("POLL_MODE must be \"poll\" or \"longpoll\", got %q", c.PollMode)
+	}
+	if c.PollRequestTimeout < 0 {
+		return fmt.Errorf("POLL_REQUEST_TIMEOUT must not be negative, got %v", c.PollRequestTimeout)
+	}
+	if c.ProbeCacheTTL < 0 {
+		return fmt.Errorf("PROBE_CACHE_TTL must not be negative, got %v", c.ProbeCacheTTL)
+	}
+	if c.MaxConsecutiveFailures < 0 {
+		return fmt.Errorf("MAX_CONSECUTIVE_FAILURES must not be negative, got %d", c.MaxConsecutiveFailures)
+	}
+	if c.WatchdogMaxInterval < 0 {
+		return fmt.Errorf("WATCHDOG_MAX_INTERVAL must not be negative, got %v", c.WatchdogMaxInterval)
+	}
+	if c.ResourceMinDiskFreeMB < 0 {
+		return fmt.Errorf("RESOURCE_MIN_DISK_FREE_MB must not be negative, got %d", c.ResourceMinDiskFreeMB)
+	}
+	if c.ResourceMaxMemoryMB < 0 {
+		return fmt.Errorf("RESOURCE_MAX_MEMORY_MB must not be negative, got %d", c.ResourceMaxMemoryMB)
+	}
+	if c.StartupCheckRetries < 0 {
+		return fmt.Errorf("STARTUP_CHECK_RETRIES must not be negative, got %d", c.StartupCheckRetries)
+	}
+	if c.StartupCheckBackoff < 0 {
+		return fmt.Errorf("STARTUP_CHECK_BACKOFF must not be negative, got %v", c.StartupCheckBackoff)
+	}
+	if c.TriggerDebounce < 0 {
+		return fmt.Errorf("TRIGGER_DEBOUNCE must not be negative, got %v", c.TriggerDebounce)
+	}
+	if c.LivenessStuckThreshold < 0 {
+		return fmt.Errorf("LIVENESS_STUCK_THRESHOLD must not be negative, got %v", c.LivenessStuckThreshold)
+	}
+	if _, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(c.BindAddr, c.HTTPPort)); err != nil {
+		return fmt.Errorf("BIND_ADDR %q and HTTP_PORT %q do not form a valid listen address: %w", c.BindAddr, c.HTTPPort, err)
+	}
+	if c.OtelResultLogs && c.OtelEndpoint == "" {
+		return errors.New("OTEL_EXPORTER_OTLP_ENDPOINT is required when OTEL_RESULT_LOGS is set")
+	}
+	if c.PushgatewayURL != "" {
+		if err := validateBackendURL("PUSHGATEWAY_URL", c.PushgatewayURL); err != nil {
+			return err
+		}
+		if c.PushgatewayTimeout <= 0 {
+			return fmt.Errorf("PUSHGATEWAY_TIMEOUT must be positive, got %v", c.PushgatewayTimeout)
+		}
+	}
+	if c.EnablePprof {
+		if c.PprofPort == c.HTTPPort {
+			return fmt.Errorf("PPROF_PORT must differ from HTTP_PORT, got %q for both", c.PprofPort)
+		}
+		if _, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(c.PprofBindAddr, c.PprofPort)); err != nil {
+			return fmt.Errorf("PPROF_BIND_ADDR %q and PPROF_PORT %q do not form a valid listen address: %w", c.PprofBindAddr, c.PprofPort, err)
+		}
+	}
+	if c.SendIdempotencyKey && !validHeaderName(c.IdempotencyKeyHeader) {
+		return fmt.Errorf("IDEMPOTENCY_KEY_HEADER must be a valid header name, got %q", c.IdempotencyKeyHeader)
+	}
+	if c.CanaryPercent < 0 || c.CanaryPercent > 100 {
+		return fmt.Errorf("CANARY_PERCENT must be between 0 and 100, got %v", c.CanaryPercent)
+	}
+	if c.CanaryPercent > 0 && c.CanaryBackendURL == "" {
+		return errors.New("CANARY_BACKEND_URL is required when CANARY_PERCENT is set")
+	}
+	if c.CanaryBackendURL != "" {
+		if err := validateBackendURL("CANARY_BACKEND_URL", c.CanaryBackendURL); err != nil {
+			return err
+		}
+		c.CanaryBackendURL = strings.TrimRight(c.CanaryBackendURL, "/")
+	}
+	if err := c.validateSSHTunnel(); err != nil {
+		return err
+	}
+	if err := redact.Validate(c.RedactKeys); err != nil {
+		return fmt.Errorf("REDACT_KEYS: %w", err)
+	}
+	if c.HistorySize < 0 {
+		return fmt.Errorf("HEALTH_HISTORY_SIZE must not be negative, got %d", c.HistorySize)
+	}
+	if v := c.BackoffStrategy; v != "" && v != "exponential" && v != "decorrelated" {
+		return fmt.Errorf("BACKOFF_STRATEGY must be \"exponential\" or \"decorrelated\", got %q", v)
+	}
+	if v := c.LogFormat; v != "" && v != "json" && v != "console" && v != "logfmt" {
+		return fmt.Errorf("LOG_FORMAT must be \"json\", \"console\" or \"logfmt\", got %q", v)
+	}
+	if c.Jitter < 0 || c.Jitter > 1 {
+		return fmt.Errorf("JITTER must be between 0 and 1, got %v", c.Jitter)
+	}
+	if c.PollBackoffFactor != 0 && c.PollBackoffFactor < 1 {
+		return fmt.Errorf("POLL_BACKOFF_FACTOR must be at least 1, got %v", c.PollBackoffFactor)
+	}
+	if c.PollJitter < 0 || c.PollJitter > 1 {
+		return fmt.Errorf("POLL_JITTER must be between 0 and 1, got %v", c.PollJitter)
+	}
+	if c.CircuitFailureThreshold < 0 {
+		return fmt.Errorf("CIRCUIT_FAILURE_THRESHOLD must not be negative, got %d", c.CircuitFailureThreshold)
+	}
+	if c.CircuitFailureThreshold > 0 && c.CircuitCooldown <= 0 {
+		return fmt.Errorf("CIRCUIT_COOLDOWN must be positive, got %v", c.CircuitCooldown)
+	}
+	if c.WaitForURL != "" {
+		if u, err := url.Parse(c.WaitForURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("WAIT_FOR_URL must be an absolute http(s) URL, got %q", c.WaitForURL)
+		}
+		if c.WaitForTimeout <= 0 {
+			return fmt.Errorf("WAIT_FOR_TIMEOUT must be positive, got %v", c.WaitForTimeout)
+		}
+	}
+	if c.ExpectedDuration > 0 && c.DurationDeviationPct <= 0 {
+		return fmt.Errorf("DURATION_DEVIATION_PCT must be positive, got %v", c.DurationDeviationPct)
+	}
+	for _, h := range c.TLSSkipVerifyHosts {
+		if net.ParseIP(h) != nil {
+			return fmt.Errorf("TLS_SKIP_VERIFY_HOSTS must list host names, not IP addresses: %q", h)
+		}
+	}
+	if v := c.TLSMinVersion; v != "" && v != "1.2" && v != "1.3" {
+		return fmt.Errorf("TLS_MIN_VERSION must be \"1.2\" or \"1.3\", got %q", c.TLSMinVersion)
+	}
+	if c.ProxyURL != "" {
+		if u, err := url.Parse(c.ProxyURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("PIPELINE_PROXY_URL must be an absolute URL, got %q", c.ProxyURL)
+		}
+	}
+	for _, r := range c.AlertRoutes {
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return fmt.Errorf("ALERT_ROUTES: invalid pattern %q: %w", r.Pattern, err)
+		}
+		if u, err := url.Parse(r.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("ALERT_ROUTES: pattern %q must map to an absolute http(s) URL", r.Pattern)
+		}
+	}
+	if err := c.validateTLSFiles(); err != nil {
+		return err
+	}
+	for status, outcome := range c.StatusPolicy {
+		if outcome != "pass" && outcome != "warn" && outcome != "fail" {
+			return fmt.Errorf("PIPELINE_STATUS_POLICY: status %q must map to \"pass\", \"warn\" or \"fail\", got %q", status, outcome)
+		}
+	}
+	if c.JobIDPattern != "" {
+		if _, err := regexp.Compile(c.JobIDPattern); err != nil {
+			return fmt.Errorf("JOB_ID_PATTERN is not a valid regular expression: %w", err)
+		}
 	}
 	return nil
 }
 
-func getEnvOrDefault(key, defaultVal string) string {
-	if val := os.Getenv(key); val != "" {
+// validateBackendURL requires an absolute http(s) URL with a host, so a
+// value like "localhost:8080" fails here rather than on the first request.
+// name is the setting reported in errors.
+func validateBackendURL(name, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%s %q is not a valid URL: %w", name, raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%s %q must start with http:// or https://", name, raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%s %q has no host", name, raw)
+	}
+	return nil
+}
+
+// validateTLSFiles loads the mTLS and CA files once, so a missing or
+// malformed file fails at startup instead of at the first handshake.
+func (c *Config) validateTLSFiles() error {
+	if (c.TLSClientCert == "") != (c.TLSClientKey == "") {
+		return errors.New("TLS_CLIENT_CERT and TLS_CLIENT_KEY must be set together")
+	}
+	if c.TLSClientCert != "" {
+		if _, err := tls.LoadX509KeyPair(c.TLSClientCert, c.TLSClientKey); err != nil {
+			return fmt.Errorf("TLS_CLIENT_CERT/TLS_CLIENT_KEY: %w", err)
+		}
+	}
+	if c.TLSCACert != "" {
+		pem, err := os.ReadFile(c.TLSCACert)
+		if err != nil {
+			return fmt.Errorf("TLS_CA_CERT: %w", err)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return fmt.Errorf("TLS_CA_CERT %s contains no PEM certificates", c.TLSCACert)
+		}
+	}
+	return nil
+}
+
+// validHeaderName reports whether name is a non-empty HTTP header field
+// name (an RFC 9110 token).
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateSSHTunnel checks the SSH_TUNNEL_* settings when a tunnel is
+// configured: a user, a way to authenticate, a host key policy, and that the
+// key and known_hosts files load.
+func (c *Config) validateSSHTunnel() error {
+	if c.SSHTunnelHost == "" {
+		return nil
+	}
+	if c.SSHTunnelUser == "" {
+		return errors.New("SSH_TUNNEL_USER is required with SSH_TUNNEL_HOST")
+	}
+	if c.SSHTunnelKeyFile == "" && c.SSHTunnelPassword == "" {
+		return errors.New("SSH_TUNNEL_KEY_FILE or SSH_TUNNEL_PASSWORD is required with SSH_TUNNEL_HOST")
+	}
+	if c.SSHTunnelKnownHosts == "" && !c.SSHTunnelInsecureHostKey {
+		return errors.New("SSH_TUNNEL_KNOWN_HOSTS is required with SSH_TUNNEL_HOST (or SSH_TUNNEL_INSECURE_HOST_KEY=true)")
+	}
+	if c.SSHTunnelKeyFile != "" {
+		pem, err := os.ReadFile(c.SSHTunnelKeyFile)
+		if err != nil {
+			return fmt.Errorf("SSH_TUNNEL_KEY_FILE: %w", err)
+		}
+		if _, err := ssh.ParsePrivateKey(pem); err != nil {
+			return fmt.Errorf("SSH_TUNNEL_KEY_FILE: %w", err)
+		}
+	}
+	if c.SSHTunnelKnownHosts != "" {
+		if _, err := knownhosts.New(c.SSHTunnelKnownHosts); err != nil {
+			return fmt.Errorf("SSH_TUNNEL_KNOWN_HOSTS: %w", err)
+		}
+	}
+	return nil
+}
+
+// AlertRoute sends failure notifications for pipelines whose name matches
+// Pattern (a path.Match glob) to the webhook at URL.
+type AlertRoute struct {
+	Pattern string
+	URL     string
+}
+
+// loadAlertRoutes parses ALERT_ROUTES ("pattern=url,pattern2=url2"),
+// keeping their order. Returns nil when unset.
+func loadAlertRoutes(getenv env) []AlertRoute {
+	var routes []AlertRoute
+	for _, pair := range getenv.list("ALERT_ROUTES") {
+		pattern, u, ok := strings.Cut(pair, "=")
+		if pattern = strings.TrimSpace(pattern); ok && pattern != "" {
+			routes = append(routes, AlertRoute{Pattern: pattern, URL: strings.TrimSpace(u)})
+		}
+	}
+	return routes
+}
+
+// loadRunMetadata parses RUN_METADATA ("key=val,key2=val2") and merges in
+// TRIGGER_SOURCE and TRIGGER_COMMIT. Returns nil when nothing is set.
+func loadRunMetadata(getenv env) map[string]string {
+	md := getenv.mapping("RUN_METADATA")
+	if md == nil {
+		md = make(map[string]string)
+	}
+	if v := getenv("TRIGGER_SOURCE"); v != "" {
+		md["trigger_source"] = v
+	}
+	if v := getenv("TRIGGER_COMMIT"); v != "" {
+		md["trigger_commit"] = v
+	}
+	if len(md) == 0 {
+		return nil
+	}
+	return md
+}
+
+// Redacted returns a copy of the config with secrets masked, safe to log or persist.
+func (c *Config) Redacted() Config {
+	out := *c
+	if out.PipelineAuth != "" {
+		out.PipelineAuth = redact.Placeholder
+	}
+	if out.SessionPassword != "" {
+		out.SessionPassword = redact.Placeholder
+	}
+	if out.TriggerAuthToken != "" {
+		out.TriggerAuthToken = redact.Placeholder
+	}
+	if out.SSHTunnelPassword != "" {
+		out.SSHTunnelPassword = redact.Placeholder
+	}
+	if out.FailureWebhookURL != "" {
+		out.FailureWebhookURL = redact.Placeholder
+	}
+	if len(out.AlertRoutes) > 0 {
+		out.AlertRoutes = make([]AlertRoute, len(c.AlertRoutes))
+		for i, r := range c.AlertRoutes {
+			out.AlertRoutes[i] = AlertRoute{Pattern: r.Pattern, URL: redact.Placeholder}
+		}
+	}
+	if u, err := url.Parse(out.ProxyURL); err == nil && u.User != nil {
+		out.ProxyURL = u.Redacted()
+	}
+	if u, err := url.Parse(out.PushgatewayURL); err == nil && u.User != nil {
+		out.PushgatewayURL = u.Redacted()
+	}
+	return out
+}
+
+// Secrets returns the configured credential values, so the logger can mask
+// them wherever they show up. It covers everything Redacted masks: webhook
+// URLs, which often embed a token, and the passwords of proxy and
+// Pushgateway URLs.
+func (c *Config) Secrets() []string {
+	candidates := []string{c.PipelineAuth, c.SessionPassword, c.TriggerAuthToken, c.SSHTunnelPassword, c.FailureWebhookURL}
+	for _, r := range c.AlertRoutes {
+		candidates = append(candidates, r.URL)
+	}
+	for _, raw := range []string{c.ProxyURL, c.PushgatewayURL} {
+		if u, err := url.Parse(raw); err == nil && u.User != nil {
+			if p, ok := u.User.Password(); ok {
+				candidates = append(candidates, p)
+			}
+		}
+	}
+
+	var out []string
+	for _, s := range candidates {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (getenv env) orDefault(key, defaultVal string) string {
+	if val := getenv(key); val != "" {
 		return val
 	}
 	return defaultVal
 }
 
-func getEnvIntOrDefault(key string, defaultVal int) int {
-	if val := os.Getenv(key); val != "" {
+func (getenv env) intOrDefault(key string, defaultVal int) int {
+	if val := getenv(key); val != "" {
 		if i, err := strconv.Atoi(val); err == nil {
 			return i
 		}
@@ -93,8 +741,8 @@ func getEnvIntOrDefault(key string, defaultVal int) int {
 	return defaultVal
 }
 
-func getEnvFloatOrDefault(key string, defaultVal float64) float64 {
-	if val := os.Getenv(key); val != "" {
+func (getenv env) floatOrDefault(key string, defaultVal float64) float64 {
+	if val := getenv(key); val != "" {
 		if f, err := strconv.ParseFloat(val, 64); err == nil {
 			return f
 		}
@@ -102,8 +750,8 @@ func getEnvFloatOrDefault(key string, defaultVal float64) float64 {
 	return defaultVal
 }
 
-func getEnvDurationOrDefault(key string, defaultVal time.Duration) time.Duration {
-	if val := os.Getenv(key); val != "" {
+func (getenv env) durationOrDefault(key string, defaultVal time.Duration) time.Duration {
+	if val := getenv(key); val != "" {
 		if d, err := time.ParseDuration(val); err == nil {
 			return d
 		}
@@ -111,8 +759,35 @@ func getEnvDurationOrDefault(key string, defaultVal time.Duration) time.Duration
 	return defaultVal
 }
 
-func getEnvBoolOrDefault(key string, defaultVal bool) bool {
-	if val := os.Getenv(key); val != "" {
+// list splits a comma-separated env var, dropping empty entries.
+func (getenv env) list(key string) []string {
+	var out []string
+	for _, part := range strings.Split(getenv(key), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// mapping parses a comma-separated list of key=value pairs, dropping
+// entries without a key. Returns nil when nothing is set.
+func (getenv env) mapping(key string) map[string]string {
+	var m map[string]string
+	for _, pair := range getenv.list(key) {
+		k, v, ok := strings.Cut(pair, "=")
+		if k = strings.TrimSpace(k); ok && k != "" {
+			if m == nil {
+				m = make(map[string]string)
+			}
+			m[k] = strings.TrimSpace(v)
+		}
+	}
+	return m
+}
+
+func (getenv env) boolOrDefault(key string, defaultVal bool) bool {
+	if val := getenv(key); val != "" {
 		if b, err := strconv.ParseBool(val); err == nil {
 			return b
 		}
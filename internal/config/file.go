@@ -0,0 +1,202 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileKeys are the settings a config file may set: every environment
+// variable Load reads, written in lower case (max_retries: 5). Add new
+// variables here too, one per line in sorted order.
+var fileKeys = []string{
+	"ALERT_ROUTES",
+	"API_BASE_PATH",
+	"BACKEND_URL",
+	"BACKEND_VERSION_ENDPOINT",
+	"BACKOFF_FACTOR",
+	"BACKOFF_STRATEGY",
+	"BIND_ADDR",
+	"CANARY_BACKEND_URL",
+	"CANARY_PERCENT",
+	"CIRCUIT_COOLDOWN",
+	"CIRCUIT_FAILURE_THRESHOLD",
+	"COOLDOWN_AFTER_FAILURES",
+	"COOLDOWN_BASE",
+	"COOLDOWN_MAX",
+	"DISABLE_KEEPALIVES",
+	"DRAIN_TIMEOUT",
+	"DRY_RUN",
+	"DURATION_DEVIATION_PCT",
+	"ENABLE_PPROF",
+	"EXPECTED_DURATION",
+	"FAILURE_WEBHOOK_URL",
+	"HEALTH_HISTORY_SIZE",
+	"HTTP_PORT",
+	"HTTP_TIMING_DEBUG",
+	"HTTP_UNIX_SOCKET",
+	"IDEMPOTENCY_KEY_HEADER",
+	"INITIAL_BACKOFF",
+	"JITTER",
+	"JOB_ID_PATTERN",
+	"K8S_EVENTS_ENABLED",
+	"LIVENESS_STUCK_THRESHOLD",
+	"LOG_DURATION_UNIT",
+	"LOG_FORMAT",
+	"LOG_JSON",
+	"LOG_LEVEL",
+	"LOG_RUN_SUMMARY",
+	"MAX_BACKOFF",
+	"MAX_CONSECUTIVE_FAILURES",
+	"MAX_LIFETIME",
+	"MAX_RETRIES",
+	"MIN_BACKEND_VERSION",
+	"OTEL_EXPORTER_OTLP_ENDPOINT",
+	"OTEL_RESULT_LOGS",
+	"PERSIST_COMPRESS",
+	"PIPELINE_API_TOKEN",
+	"PIPELINE_API_TOKEN_FILE",
+	"PIPELINE_PROXY_URL",
+	"PIPELINE_STATUS_POLICY",
+	"POLL_BACKOFF_FACTOR",
+	"POLL_INITIAL_INTERVAL",
+	"POLL_JITTER",
+	"POLL_LOG_INTERVAL",
+	"POLL_LONGPOLL_TIMEOUT",
+	"POLL_MAX_INTERVAL",
+	"POLL_MAX_WAIT_TIME",
+	"POLL_MODE",
+	"POLL_PROGRESS_TIMEOUT",
+	"POLL_REQUEST_TIMEOUT",
+	"PPROF_BIND_ADDR",
+	"PPROF_PORT",
+	"PROBE_CACHE_TTL",
+	"PUSHGATEWAY_TIMEOUT",
+	"PUSHGATEWAY_URL",
+	"REDACT_KEYS",
+	"REQUEST_TIMEOUT",
+	"RESOURCE_DISK_PATH",
+	"RESOURCE_MAX_MEMORY_MB",
+	"RESOURCE_MIN_DISK_FREE_MB",
+	"RETRYABLE_STATUS_CODES",
+	"RETRY_IDEMPOTENT_ONLY",
+	"RETRY_MAX_ELAPSED",
+	"RUN_EVENTS_ENABLED",
+	"RUN_MANIFEST_DIR",
+	"RUN_METADATA",
+	"SEND_IDEMPOTENCY_KEY",
+	"SESSION_LOGIN_ENDPOINT",
+	"SESSION_PASSWORD",
+	"SESSION_USERNAME",
+	"SSH_TUNNEL_HOST",
+	"SSH_TUNNEL_INSECURE_HOST_KEY",
+	"SSH_TUNNEL_KEY_FILE",
+	"SSH_TUNNEL_KNOWN_HOSTS",
+	"SSH_TUNNEL_PASSWORD",
+	"SSH_TUNNEL_USER",
+	"STARTUP_CHECK_BACKOFF",
+	"STARTUP_CHECK_RETRIES",
+	"STATE_FILE",
+	"TLS_CA_CERT",
+	"TLS_CLIENT_CERT",
+	"TLS_CLIENT_KEY",
+	"TLS_INSECURE_SKIP_VERIFY",
+	"TLS_MIN_VERSION",
+	"TLS_SKIP_VERIFY_HOSTS",
+	"TOKEN_INTROSPECT_ENDPOINT",
+	"TOKEN_REQUIRED_SCOPE",
+	"TREAT_409_AS_RUNNING",
+	"TRIGGER_AUTH_TOKEN",
+	"TRIGGER_COMMIT",
+	"TRIGGER_DEBOUNCE",
+	"TRIGGER_SOURCE",
+	"WAIT_FOR_TIMEOUT",
+	"WAIT_FOR_URL",
+	"WATCHDOG_MAX_INTERVAL",
+}
+
+// env looks up a setting by environment variable name.
+type env func(key string) string
+
+// layered returns the lookup Load reads settings through: the environment
+// variable key, or the config file's value for it when the variable is
+// unset. Env vars win over the file, and the file wins over built-in
+// defaults.
+func layered(fileValues map[string]string) env {
+	return func(key string) string {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+		return fileValues[key]
+	}
+}
+
+// readFile parses a YAML config file into env-var-keyed string values, in
+// the same formats the environment variables use. Lists become
+// comma-separated values and mappings become key=value pairs. Unknown keys
+// are an error.
+func readFile(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	if err := dec.Decode(&raw); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	known := make(map[string]bool, len(fileKeys))
+	for _, k := range fileKeys {
+		known[k] = true
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, node := range raw {
+		envKey := strings.ToUpper(key)
+		if !known[envKey] {
+			return nil, fmt.Errorf("config file %s: unknown key %q (line %d)", path, key, node.Line)
+		}
+		v, err := nodeValue(&node)
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: key %q: %w", path, key, err)
+		}
+		values[envKey] = v
+	}
+	return values, nil
+}
+
+// nodeValue renders a YAML value in the env var format for its key.
+func nodeValue(n *yaml.Node) (string, error) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return n.Value, nil
+	case yaml.SequenceNode:
+		parts := make([]string, 0, len(n.Content))
+		for _, item := range n.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("list items must be scalars (line %d)", item.Line)
+			}
+			parts = append(parts, item.Value)
+		}
+		return strings.Join(parts, ","), nil
+	case yaml.MappingNode:
+		pairs := make([]string, 0, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if v.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("map values must be scalars (line %d)", v.Line)
+			}
+			pairs = append(pairs, k.Value+"="+v.Value)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value (line %d)", n.Line)
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cron-runner.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFileLayersEnvOverFileOverDefaults(t *testing.T) {
+	path := writeConfigFile(t, `
+pipeline_api_token: file-token
+initial_backoff: 5s
+max_retries: 7
+tls_skip_verify_hosts:
+  - a.internal
+  - b.internal
+pipeline_status_policy:
+  skipped: pass
+  warning: warn
+`)
+	t.Setenv("MAX_RETRIES", "2")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.PipelineAuth != "file-token" || cfg.InitialBackoff != 5*time.Second {
+		t.Fatalf("expected file values, got token %q backoff %v", cfg.PipelineAuth, cfg.InitialBackoff)
+	}
+	if cfg.MaxRetries != 2 {
+		t.Fatalf("expected the env var to win over the file, got MaxRetries %d", cfg.MaxRetries)
+	}
+	if cfg.MaxBackoff != 30*time.Second {
+		t.Fatalf("expected the default for unset keys, got MaxBackoff %v", cfg.MaxBackoff)
+	}
+	if len(cfg.TLSSkipVerifyHosts) != 2 || cfg.TLSSkipVerifyHosts[1] != "b.internal" {
+		t.Fatalf("expected a YAML list, got %v", cfg.TLSSkipVerifyHosts)
+	}
+	if cfg.StatusPolicy["warning"] != "warn" || cfg.StatusPolicy["skipped"] != "pass" {
+		t.Fatalf("expected a YAML map, got %v", cfg.StatusPolicy)
+	}
+}
+
+func TestLoadFileRejectsUnknownKey(t *testing.T) {
+	path := writeConfigFile(t, "pipeline_api_token: t\nmax_retry: 5\n")
+
+	_, err := LoadFile(path)
+	if err == nil || !strings.Contains(err.Error(), `unknown key "max_retry"`) {
+		t.Fatalf("expected an unknown key error, got %v", err)
+	}
+}
+
+func TestLoadFileStillValidates(t *testing.T) {
+	path := writeConfigFile(t, "poll_mode: sometimes\n")
+	t.Setenv("PIPELINE_API_TOKEN", "token")
+
+	if _, err := LoadFile(path); err == nil || !strings.Contains(err.Error(), "POLL_MODE") {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+}
+
+func TestLoadFileConcurrentCallsKeepTheirOwnValues(t *testing.T) {
+	paths := []string{
+		writeConfigFile(t, "pipeline_api_token: t\nmax_retries: 1\n"),
+		writeConfigFile(t, "pipeline_api_token: t\nmax_retries: 2\n"),
+	}
+
+	errs := make(chan error, 20)
+	for i := range 20 {
+		go func() {
+			want := i%2 + 1
+			cfg, err := LoadFile(paths[i%2])
+			if err == nil && cfg.MaxRetries != want {
+				err = fmt.Errorf("expected MaxRetries %d, got %d", want, cfg.MaxRetries)
+			}
+			errs <- err
+		}()
+	}
+	for range 20 {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -3,53 +3,71 @@ package jobs
 import (
 	"time"
 
+	"cron-runner/internal/k8sevents"
+	"cron-runner/internal/manifest"
+	"cron-runner/internal/metrics"
 	"cron-runner/internal/pipeline"
 	"cron-runner/internal/reporter"
+	"cron-runner/internal/runevent"
 	"cron-runner/internal/scheduler"
 	"cron-runner/internal/task"
 
 	"github.com/rs/zerolog"
 )
 
+// Deps are the collaborators every job's task is built with. Only Client
+// is needed to run a job; the others are optional and nil disables them, as
+// on task.TriggerTask.
+type Deps struct {
+	Client   *pipeline.Client
+	Reporter *reporter.Reporter
+	Manifest *manifest.Writer
+	Metrics  *metrics.Metrics
+	Baseline *metrics.Baseline
+	Events   *k8sevents.Recorder
+	Emitter  *runevent.Emitter
+	Log      zerolog.Logger
+}
+
 // RegisterAll returns all scheduled job definitions.
 // To add a new job, append a JobDef here — no other changes needed.
-func RegisterAll(client *pipeline.Client, rep *reporter.Reporter, log zerolog.Logger) []scheduler.JobDef {
+func RegisterAll(d Deps) []scheduler.JobDef {
 	return []scheduler.JobDef{
 		{
 			Name:      "pre-game",
 			Schedule:  "0/15 14-23,0-1 * * *",
 			Singleton: true,
 			Timeout:   20 * time.Minute,
-			Task: &task.TriggerTask{
-				Client:   client,
-				Endpoint: "/v1/internal/pipelines/pre-game",
-				Log:      log.With().Str("job", "pre-game").Logger(),
-				Reporter: rep,
-			},
+			Task:      d.trigger("pre-game", "/v1/internal/pipelines/pre-game"),
 		},
 		{
 			Name:        "live-stats",
 			Schedule:    "*/30 * 16-23,0-6 * * *",
 			WithSeconds: true,
 			Singleton:   true,
-			Task: &task.TriggerTask{
-				Client:   client,
-				Endpoint: "/v1/internal/pipelines/live-stats",
-				Log:      log.With().Str("job", "live-stats").Logger(),
-				Reporter: rep,
-			},
+			Task:        d.trigger("live-stats", "/v1/internal/pipelines/live-stats"),
 		},
 		{
 			Name:      "post-game",
 			Schedule:  "0/15 3-9 * * *",
 			Singleton: true,
 			Timeout:   20 * time.Minute,
-			Task: &task.TriggerTask{
-				Client:   client,
-				Endpoint: "/v1/internal/pipelines/post-game",
-				Log:      log.With().Str("job", "post-game").Logger(),
-				Reporter: rep,
-			},
+			Task:      d.trigger("post-game", "/v1/internal/pipelines/post-game"),
 		},
 	}
 }
+
+// trigger returns the task that triggers endpoint for the job name.
+func (d Deps) trigger(name, endpoint string) *task.TriggerTask {
+	return &task.TriggerTask{
+		Client:   d.Client,
+		Endpoint: endpoint,
+		Log:      d.Log.With().Str("job", name).Logger(),
+		Reporter: d.Reporter,
+		Manifest: d.Manifest,
+		Metrics:  d.Metrics,
+		Baseline: d.Baseline,
+		Events:   d.Events,
+		Emitter:  d.Emitter,
+	}
+}
@@ -0,0 +1,153 @@
+package k8sevents
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	sendTimeout   = 5 * time.Second
+	namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	component     = "cron-runner"
+)
+
+// Event types.
+const (
+	TypeNormal  = corev1.EventTypeNormal
+	TypeWarning = corev1.EventTypeWarning
+)
+
+// Recorder records core/v1 Events against the runner's pod through the
+// Kubernetes API, using the pod's service account via client-go. The
+// service account needs RBAC permission to create events in its namespace.
+// Like the push reporter, every call is fire-and-forget: failures are
+// logged and never block or fail a run.
+type Recorder struct {
+	client    kubernetes.Interface
+	namespace string
+	podName   string
+	podUID    string // optional; from POD_UID via the downward API
+	pending   sync.WaitGroup
+	log       zerolog.Logger
+}
+
+// InCluster builds a Recorder from the in-cluster config (rest.InClusterConfig),
+// whose service account token is re-read as the kubelet rotates it. The
+// namespace comes from the mounted service account; the pod is named by
+// POD_NAME (falling back to the hostname, which Kubernetes sets to the pod
+// name).
+func InCluster(log zerolog.Logger) (*Recorder, error) {
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Timeout = sendTimeout
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	ns, err := os.ReadFile(namespaceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account namespace: %w", err)
+	}
+
+	pod := os.Getenv("POD_NAME")
+	if pod == "" {
+		if pod, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("failed to determine pod name: %w", err)
+		}
+	}
+	return newRecorder(client, strings.TrimSpace(string(ns)), pod, os.Getenv("POD_UID"), log), nil
+}
+
+func newRecorder(client kubernetes.Interface, namespace, podName, podUID string, log zerolog.Logger) *Recorder {
+	return &Recorder{
+		client:    client,
+		namespace: namespace,
+		podName:   podName,
+		podUID:    podUID,
+		log:       log.With().Str("component", "k8s-events").Logger(),
+	}
+}
+
+// RunFinished records a RunSucceeded (Normal) or RunFailed (Warning) event
+// for a finished run of job. It never blocks the caller.
+func (r *Recorder) RunFinished(job string, success bool, d time.Duration, err error) {
+	eventType, reason := TypeNormal, "RunSucceeded"
+	msg := fmt.Sprintf("job %s succeeded in %s", job, d.Round(time.Millisecond))
+	if !success {
+		eventType, reason = TypeWarning, "RunFailed"
+		msg = fmt.Sprintf("job %s failed after %s", job, d.Round(time.Millisecond))
+		if err != nil {
+			msg += ": " + err.Error()
+		}
+	}
+	ev := r.newEvent(eventType, reason, msg)
+	r.pending.Add(1)
+	go func() {
+		defer r.pending.Done()
+		r.send(ev)
+	}()
+}
+
+// Wait blocks until all pending events are sent or given up on, or ctx
+// expires. Used before exiting so the last run's event isn't dropped.
+func (r *Recorder) Wait(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		r.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+func (r *Recorder) newEvent(eventType, reason, msg string) *corev1.Event {
+	now := metav1.NewTime(time.Now().UTC())
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: r.podName + ".",
+			Namespace:    r.namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Name:       r.podName,
+			Namespace:  r.namespace,
+			UID:        types.UID(r.podUID),
+		},
+		Reason:              reason,
+		Message:             msg,
+		Type:                eventType,
+		Source:              corev1.EventSource{Component: component},
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+		ReportingController: component,
+		ReportingInstance:   r.podName,
+	}
+}
+
+func (r *Recorder) send(ev *corev1.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	// A Forbidden error here usually means the service account lacks
+	// "create events" RBAC.
+	if _, err := r.client.CoreV1().Events(r.namespace).Create(ctx, ev, metav1.CreateOptions{}); err != nil {
+		r.log.Warn().Err(err).Str("reason", ev.Reason).Msg("k8s_event_send_failed")
+	}
+}
@@ -0,0 +1,95 @@
+package k8sevents
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// createdEvents waits for pending sends and returns the events in ns.
+func createdEvents(t *testing.T, rec *Recorder, client *fake.Clientset, ns string) []corev1.Event {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	rec.Wait(ctx)
+	list, err := client.CoreV1().Events(ns).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list.Items
+}
+
+func TestRunFinishedCreatesWarningEventOnPod(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	rec := newRecorder(client, "cron", "cron-runner-abc", "uid-1", zerolog.Nop())
+	rec.RunFinished("post-game", false, 3*time.Second, errors.New("backend unreachable"))
+
+	events := createdEvents(t, rec, client, "cron")
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %d", len(events))
+	}
+	ev := events[0]
+	if ev.Type != TypeWarning || ev.Reason != "RunFailed" {
+		t.Fatalf("expected Warning/RunFailed, got %s/%s", ev.Type, ev.Reason)
+	}
+	if !strings.Contains(ev.Message, "post-game") || !strings.Contains(ev.Message, "backend unreachable") {
+		t.Fatalf("expected job and error in message, got %q", ev.Message)
+	}
+	obj := ev.InvolvedObject
+	if obj.Kind != "Pod" || obj.Name != "cron-runner-abc" || obj.Namespace != "cron" || obj.UID != "uid-1" {
+		t.Fatalf("expected the runner pod as involved object, got %+v", obj)
+	}
+	if ev.Source.Component != component || ev.ReportingInstance != "cron-runner-abc" {
+		t.Fatalf("expected the runner as event source, got %+v / %s", ev.Source, ev.ReportingInstance)
+	}
+}
+
+func TestRunFinishedSuccessIsNormal(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	rec := newRecorder(client, "cron", "cron-runner-abc", "", zerolog.Nop())
+	rec.RunFinished("pre-game", true, time.Second, nil)
+
+	events := createdEvents(t, rec, client, "cron")
+	if len(events) != 1 || events[0].Type != TypeNormal || events[0].Reason != "RunSucceeded" {
+		t.Fatalf("expected one Normal/RunSucceeded event, got %+v", events)
+	}
+}
+
+func TestWaitBlocksForInFlightEvents(t *testing.T) {
+	release := make(chan struct{})
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "events", func(k8stesting.Action) (bool, runtime.Object, error) {
+		<-release
+		return false, nil, nil
+	})
+	rec := newRecorder(client, "cron", "cron-runner-abc", "", zerolog.Nop())
+	rec.RunFinished("pre-game", true, time.Second, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	rec.Wait(ctx)
+	if ctx.Err() == nil {
+		t.Fatalf("expected Wait to block while the event is in flight")
+	}
+
+	close(release)
+	if events := createdEvents(t, rec, client, "cron"); len(events) != 1 {
+		t.Fatalf("expected the event created once released, got %d", len(events))
+	}
+}
+
+func TestInClusterFailsOutsideCluster(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	if _, err := InCluster(zerolog.Nop()); err == nil {
+		t.Fatalf("expected an error outside a cluster")
+	}
+}
@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// logfmtWriter converts zerolog's JSON lines into logfmt (key=value) lines.
+// time, level and message are emitted first (message as "msg"); all other
+// fields follow in the order zerolog wrote them.
+type logfmtWriter struct {
+	out io.Writer
+}
+
+func newLogfmtWriter(out io.Writer) *logfmtWriter {
+	return &logfmtWriter{out: out}
+}
+
+type logfmtField struct {
+	key   string
+	value string
+}
+
+func (w *logfmtWriter) Write(p []byte) (int, error) {
+	fields, err := decodeOrdered(p)
+	if err != nil {
+		// Not a JSON object; pass it through unchanged rather than drop it.
+		return w.out.Write(p)
+	}
+
+	var buf bytes.Buffer
+	write := func(key, value string) {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		buf.WriteString(value)
+	}
+
+	for _, key := range []string{"time", "level", "message"} {
+		for _, f := range fields {
+			if f.key == key {
+				if key == "message" {
+					key = "msg"
+				}
+				write(key, f.value)
+			}
+		}
+	}
+	for _, f := range fields {
+		switch f.key {
+		case "time", "level", "message":
+			continue
+		}
+		write(f.key, f.value)
+	}
+	buf.WriteByte('\n')
+
+	if _, err := w.out.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// decodeOrdered decodes a flat JSON object into key/value pairs, preserving
+// key order. Nested objects and arrays are kept as compact JSON.
+func decodeOrdered(p []byte) ([]logfmtField, error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("not a JSON object")
+	}
+
+	var fields []logfmtField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, logfmtField{key: tok.(string), value: formatValue(value)})
+	}
+	return fields, nil
+}
+
+func formatValue(v json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(v, &s); err == nil {
+		return quoteIfNeeded(s)
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, v); err != nil {
+		return quoteIfNeeded(string(v))
+	}
+	out := compact.String()
+	if len(out) > 0 && (out[0] == '{' || out[0] == '[') {
+		return quoteIfNeeded(out)
+	}
+	return out // number, bool or null
+}
+
+func quoteIfNeeded(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+		b, _ := json.Marshal(s)
+		return string(b)
+	}
+	return s
+}
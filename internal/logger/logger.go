@@ -1,24 +1,48 @@
 package logger
 
 import (
-	"os"
+	"io"
 	"time"
 
+	"cron-runner/internal/redact"
+
 	"github.com/rs/zerolog"
 )
 
-// New creates a configured zerolog logger.
-func New(level string, jsonFormat bool) zerolog.Logger {
+// New creates a configured zerolog logger writing to out.
+// format is "json" (default), "console" or "logfmt".
+// durationUnit controls how Dur fields are rendered: "ms" (default), "s", "us" or "ns".
+// Any string field whose key is on deny (nil = redact.DefaultKeys), and any
+// occurrence of one of the given secrets, is masked with redact.Mask before a line
+// is written.
+func New(out io.Writer, level, format, durationUnit string, deny *redact.Denylist, secrets ...string) zerolog.Logger {
+	return newLogger(out, level, format, durationUnit, deny, secrets)
+}
+
+func newLogger(out io.Writer, level, format, durationUnit string, deny *redact.Denylist, secrets []string) zerolog.Logger {
 	var logger zerolog.Logger
 
-	if jsonFormat {
-		logger = zerolog.New(os.Stdout)
-	} else {
-		logger = zerolog.New(zerolog.ConsoleWriter{
-			Out:        os.Stdout,
+	switch durationUnit {
+	case "s":
+		zerolog.DurationFieldUnit = time.Second
+	case "us":
+		zerolog.DurationFieldUnit = time.Microsecond
+	case "ns":
+		zerolog.DurationFieldUnit = time.Nanosecond
+	default:
+		zerolog.DurationFieldUnit = time.Millisecond
+	}
+
+	switch format {
+	case "console":
+		out = zerolog.ConsoleWriter{
+			Out:        out,
 			TimeFormat: time.RFC3339,
-		})
+		}
+	case "logfmt":
+		out = newLogfmtWriter(out)
 	}
+	logger = zerolog.New(redact.NewWriter(out, deny, secrets...))
 
 	logger = logger.With().
 		Timestamp().
@@ -40,3 +64,15 @@ func New(level string, jsonFormat bool) zerolog.Logger {
 
 	return logger
 }
+
+// WithMetadata attaches run metadata to every log line under "run_metadata".
+func WithMetadata(logger zerolog.Logger, metadata map[string]string) zerolog.Logger {
+	if len(metadata) == 0 {
+		return logger
+	}
+	dict := zerolog.Dict()
+	for k, v := range metadata {
+		dict = dict.Str(k, v)
+	}
+	return logger.With().Dict("run_metadata", dict).Logger()
+}
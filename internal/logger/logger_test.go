@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"cron-runner/internal/redact"
+
+	"github.com/rs/zerolog"
+)
+
+func TestLogfmtWriter(t *testing.T) {
+	var buf bytes.Buffer
+	log := zerolog.New(newLogfmtWriter(&buf))
+
+	log.Info().
+		Str("job", "pre-game").
+		Int("attempts", 2).
+		Bool("singleton", true).
+		Str("error", `status 500: "boom"`).
+		Msg("job started")
+
+	want := `level=info msg="job started" job=pre-game attempts=2 singleton=true error="status 500: \"boom\""` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("unexpected logfmt output:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestLogfmtWriterPassesThroughNonJSON(t *testing.T) {
+	var buf bytes.Buffer
+	w := newLogfmtWriter(&buf)
+
+	if _, err := w.Write([]byte("plain text\n")); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "plain text\n" {
+		t.Fatalf("expected passthrough, got %q", buf.String())
+	}
+}
+
+func TestWithMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	log := WithMetadata(zerolog.New(&buf), map[string]string{"trigger_source": "ci"})
+
+	log.Info().Msg("job_started")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	md, ok := entry["run_metadata"].(map[string]any)
+	if !ok || md["trigger_source"] != "ci" {
+		t.Fatalf("expected run_metadata.trigger_source=ci, got %v", entry["run_metadata"])
+	}
+}
+
+func TestLoggerScrubsSecrets(t *testing.T) {
+	const token = "sk_live_0123456789abcdef"
+	for _, format := range []string{"json", "logfmt", "console"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := newLogger(&buf, "info", format, "ms", nil, []string{token})
+
+			log.Info().
+				Str("error", "backend rejected "+token).
+				Str("Authorization", "Bearer other-secret-value").
+				Dict("headers", zerolog.Dict().Str("token", "nested-secret-value")).
+				Msg("request failed")
+
+			out := buf.String()
+			for _, leaked := range []string{token, "other-secret-value", "nested-secret-value"} {
+				if strings.Contains(out, leaked) {
+					t.Fatalf("log leaked %q:\n%s", leaked, out)
+				}
+			}
+			if !strings.Contains(out, "sk_l…(24 chars)") {
+				t.Fatalf("expected masked token in log:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestLoggerScrubsDenylistedKeys(t *testing.T) {
+	var buf bytes.Buffer
+	log := newLogger(&buf, "info", "json", "ms", redact.New([]string{"*_key"}), nil)
+
+	log.Info().Str("api_key", "k-1234567890").Str("job", "post-game").Msg("request sent")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("scrubbed line is not JSON: %v\n%s", err, buf.String())
+	}
+	if entry["api_key"] != "k-12…(12 chars)" || entry["job"] != "post-game" {
+		t.Fatalf("unexpected scrubbed entry %v", entry)
+	}
+}
@@ -0,0 +1,126 @@
+package manifest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"cron-runner/internal/config"
+	"cron-runner/internal/pipeline"
+	"cron-runner/internal/redact"
+
+	"github.com/rs/zerolog"
+)
+
+// Manifest is the complete per-run artifact written for compliance: the
+// redacted config the run used plus its outcome and per-pipeline results.
+type Manifest struct {
+	JobName     string                             `json:"job_name"`
+	Endpoint    string                             `json:"endpoint"`
+	BackendURL  string                             `json:"backend_url"`
+	JobID       string                             `json:"job_id,omitempty"`
+	TriggeredAt time.Time                          `json:"triggered_at"`
+	CompletedAt time.Time                          `json:"completed_at"`
+	DurationMs  int64                              `json:"duration_ms"`
+	Result      string                             `json:"result"` // "success" | "degraded" | "failure"
+	HTTPStatus  int                                `json:"http_status,omitempty"`
+	Attempts    int                                `json:"attempts"`
+	Error       string                             `json:"error,omitempty"`
+	Pipelines   map[string]pipeline.PipelineResult `json:"pipelines,omitempty"`
+	Metadata    map[string]string                  `json:"metadata,omitempty"`
+	Config      config.Config                      `json:"config"`
+}
+
+// Writer writes one timestamped JSON manifest per run into a directory.
+// Write failures are logged and never fail the run.
+type Writer struct {
+	dir        string
+	backendURL string
+	cfg        config.Config
+	compress   bool // PERSIST_COMPRESS: gzip manifests as .json.gz
+	deny       *redact.Denylist
+	log        zerolog.Logger
+}
+
+// New creates a Writer for dir. The config snapshot is redacted once here;
+// each manifest is also passed through the REDACT_KEYS denylist on write.
+func New(dir string, cfg *config.Config, log zerolog.Logger) *Writer {
+	return &Writer{
+		dir:        dir,
+		backendURL: cfg.BackendURL,
+		cfg:        cfg.Redacted(),
+		compress:   cfg.PersistCompress,
+		deny:       redact.New(cfg.RedactKeys),
+		log:        log.With().Str("component", "manifest").Logger(),
+	}
+}
+
+// Write fills in the config snapshot and writes m to
+// <dir>/<job>-<triggered_at>.json, or .json.gz gzipped when compression is
+// on. The file is written to a temp name and renamed so readers never see a
+// partial manifest.
+func (w *Writer) Write(m Manifest) {
+	m.BackendURL = w.backendURL
+	m.Metadata = w.cfg.RunMetadata
+	m.Config = w.cfg
+
+	body, err := json.Marshal(m)
+	if err == nil {
+		body, err = w.deny.JSON(body)
+	}
+	if err != nil {
+		w.log.Warn().Err(err).Str("job", m.JobName).Msg("manifest_marshal_failed")
+		return
+	}
+
+	name := fmt.Sprintf("%s-%s.json", m.JobName, m.TriggeredAt.UTC().Format("20060102T150405.000000000Z"))
+	if w.compress {
+		if body, err = gzipBytes(body); err != nil {
+			w.log.Warn().Err(err).Str("job", m.JobName).Msg("manifest_compress_failed")
+			return
+		}
+		name += ".gz"
+	}
+	path := filepath.Join(w.dir, name)
+	if err := writeFileAtomic(path, body); err != nil {
+		w.log.Warn().Err(err).Str("job", m.JobName).Str("path", path).Msg("manifest_write_failed")
+		return
+	}
+	w.log.Debug().Str("job", m.JobName).Str("path", path).Msg("manifest_written")
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".manifest-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import "time"
+
+// Deviation labels for runs outside the expected duration band.
+const (
+	DurationTooFast = "too_fast"
+	DurationTooSlow = "too_slow"
+)
+
+// Baseline is a run's expected duration with a tolerance band of
+// DeviationPct percent either side. A run well under it may have been a
+// no-op; a run well over it points at trouble upstream.
+type Baseline struct {
+	Expected     time.Duration
+	DeviationPct float64
+}
+
+// Deviation classifies d against the band: DurationTooFast, DurationTooSlow,
+// or "" when d is within it (or no baseline is set).
+func (b Baseline) Deviation(d time.Duration) string {
+	if b.Expected <= 0 {
+		return ""
+	}
+	margin := time.Duration(float64(b.Expected) * b.DeviationPct / 100)
+	switch {
+	case d < b.Expected-margin:
+		return DurationTooFast
+	case d > b.Expected+margin:
+		return DurationTooSlow
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBaselineDeviation(t *testing.T) {
+	b := Baseline{Expected: 10 * time.Minute, DeviationPct: 50}
+
+	tests := []struct {
+		name string
+		d    time.Duration
+		want string
+	}{
+		{"within band", 12 * time.Minute, ""},
+		{"band edge", 15 * time.Minute, ""},
+		{"too fast", 2 * time.Minute, DurationTooFast},
+		{"too slow", 16 * time.Minute, DurationTooSlow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.Deviation(tt.d); got != tt.want {
+				t.Fatalf("Deviation(%v) = %q, want %q", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaselineUnsetNeverDeviates(t *testing.T) {
+	if got := (Baseline{}).Deviation(time.Hour); got != "" {
+		t.Fatalf("expected no deviation without a baseline, got %q", got)
+	}
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BuildInfo identifies the running binary, exported as the labels of
+// cron_runner_build_info.
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	GoVersion string
+}
+
+// readBuildInfo returns what the Go toolchain embedded in the binary: the
+// module version and, for builds from a git checkout, the VCS revision.
+func readBuildInfo() BuildInfo {
+	bi := BuildInfo{Version: "unknown", Commit: "unknown", GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return bi
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		bi.Version = v
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && s.Value != "" {
+			bi.Commit = s.Value
+		}
+	}
+	return bi
+}
+
+// registerProcessInfo registers the build info and process start time
+// gauges.
+func (m *Metrics) registerProcessInfo() {
+	m.buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cron_runner_build_info",
+		Help: "Build information of the running binary; always 1.",
+	}, []string{"version", "commit", "go_version"})
+	m.buildInfo.WithLabelValues(m.build.Version, m.build.Commit, m.build.GoVersion).Set(1)
+
+	started := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cron_runner_start_time_seconds",
+		Help: "Start time of the process since the Unix epoch in seconds.",
+	})
+	started.Set(float64(time.Now().Unix()))
+
+	m.reg.MustRegister(m.buildInfo, started)
+}
+
+// SetBuildInfo overrides the version and commit read from the binary, e.g.
+// with values stamped in via -ldflags. Empty arguments keep the current
+// value.
+func (m *Metrics) SetBuildInfo(version, commit string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if version != "" {
+		m.build.Version = version
+	}
+	if commit != "" {
+		m.build.Commit = commit
+	}
+	m.buildInfo.Reset()
+	m.buildInfo.WithLabelValues(m.build.Version, m.build.Commit, m.build.GoVersion).Set(1)
+}
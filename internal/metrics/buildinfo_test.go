@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteRendersBuildInfoAndStartTime(t *testing.T) {
+	before := time.Now().Unix()
+	m := New()
+	m.SetBuildInfo("v1.4.0", "abc1234")
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	want := []string{
+		"# TYPE cron_runner_build_info gauge",
+		fmt.Sprintf(`cron_runner_build_info{commit="abc1234",go_version=%q,version="v1.4.0"} 1`, runtime.Version()),
+		"# TYPE cron_runner_start_time_seconds gauge",
+	}
+	for _, w := range want {
+		if !strings.Contains(out, w+"\n") {
+			t.Fatalf("expected line %q in output:\n%s", w, out)
+		}
+	}
+
+	var started int64
+	for _, line := range strings.Split(out, "\n") {
+		if v, ok := strings.CutPrefix(line, "cron_runner_start_time_seconds "); ok {
+			var f float64
+			fmt.Sscan(v, &f)
+			started = int64(f)
+		}
+	}
+	if started < before || started > time.Now().Unix() {
+		t.Fatalf("expected start time around %d, got %d", before, started)
+	}
+}
+
+func TestSetBuildInfoKeepsDefaultsForEmptyValues(t *testing.T) {
+	m := New()
+	m.SetBuildInfo("", "")
+	if m.build.Version == "" || m.build.Commit == "" || m.build.GoVersion != runtime.Version() {
+		t.Fatalf("expected defaults kept, got %+v", m.build)
+	}
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the run duration histogram.
+var durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800}
+
+// Metrics collects per-job run counters for the Prometheus /metrics endpoint,
+// in its own client_golang registry alongside the Go runtime and process
+// collectors. All methods are safe for concurrent use.
+type Metrics struct {
+	reg *prometheus.Registry
+
+	runs       *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	deviations *prometheus.CounterVec
+	retries    *prometheus.CounterVec
+	inProgress *prometheus.GaugeVec
+	buildInfo  *prometheus.GaugeVec
+
+	mu      sync.Mutex
+	running map[string]int // job → runs in progress; the gauge is 0 or 1
+	build   BuildInfo
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		reg: prometheus.NewRegistry(),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cron_runner_runs_total",
+			Help: "Completed job runs by result.",
+		}, []string{"job", "result"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cron_runner_run_duration_seconds",
+			Help:    "Duration of completed job runs.",
+			Buckets: durationBuckets,
+		}, []string{"job"}),
+		deviations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cron_runner_run_duration_deviations_total",
+			Help: "Runs whose duration fell outside the expected band.",
+		}, []string{"job", "deviation"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cron_runner_retries_total",
+			Help: "Request retries made while triggering jobs.",
+		}, []string{"job"}),
+		inProgress: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cron_runner_run_in_progress",
+			Help: "Whether a run of the job is currently in progress.",
+		}, []string{"job"}),
+		running: make(map[string]int),
+		build:   readBuildInfo(),
+	}
+	m.registerProcessInfo()
+	m.reg.MustRegister(
+		m.runs, m.duration, m.deviations, m.retries, m.inProgress,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.reg, promhttp.HandlerOpts{})
+}
+
+// Write renders all metrics in the Prometheus text exposition format.
+func (m *Metrics) Write(w io.Writer) error {
+	mfs, err := m.reg.Gather()
+	if err != nil {
+		return err
+	}
+	enc := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seen exports every series of job at zero on its first run, so a result
+// or deviation that has not happened yet reads 0 rather than missing.
+// Callers hold m.mu.
+func (m *Metrics) seen(job string) {
+	if _, ok := m.running[job]; ok {
+		return
+	}
+	m.running[job] = 0
+	m.runs.WithLabelValues(job, "success")
+	m.runs.WithLabelValues(job, "failure")
+	m.deviations.WithLabelValues(job, DurationTooFast)
+	m.deviations.WithLabelValues(job, DurationTooSlow)
+	m.retries.WithLabelValues(job)
+	m.duration.WithLabelValues(job)
+	m.inProgress.WithLabelValues(job).Set(0)
+}
+
+// setRunning updates the in-progress count of job by delta, never below 0.
+// Callers hold m.mu.
+func (m *Metrics) setRunning(job string, delta int) {
+	m.seen(job)
+	n := max(m.running[job]+delta, 0)
+	m.running[job] = n
+	v := 0.0
+	if n > 0 {
+		v = 1
+	}
+	m.inProgress.WithLabelValues(job).Set(v)
+}
+
+// RunStarted marks a run of job as in progress.
+func (m *Metrics) RunStarted(job string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setRunning(job, 1)
+}
+
+// RunSkipped clears the in-progress mark of a run that never started.
+func (m *Metrics) RunSkipped(job string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setRunning(job, -1)
+}
+
+// RunFinished records a finished run of job: its result, duration and how
+// many attempts the trigger took (attempts beyond the first count as retries).
+func (m *Metrics) RunFinished(job string, success bool, d time.Duration, attempts int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setRunning(job, -1)
+
+	result := "success"
+	if !success {
+		result = "failure"
+	}
+	m.runs.WithLabelValues(job, result).Inc()
+	m.duration.WithLabelValues(job).Observe(d.Seconds())
+	if attempts > 1 {
+		m.retries.WithLabelValues(job).Add(float64(attempts - 1))
+	}
+}
+
+// RunDeviated records a run of job whose duration fell outside the expected
+// band; deviation is DurationTooFast or DurationTooSlow.
+func (m *Metrics) RunDeviated(job, deviation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seen(job)
+	m.deviations.WithLabelValues(job, deviation).Inc()
+}
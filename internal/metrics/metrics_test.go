@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteRendersRecordedRuns(t *testing.T) {
+	m := New()
+	m.RunStarted("post-game")
+	m.RunFinished("post-game", true, 3*time.Second, 1)
+	m.RunStarted("post-game")
+	m.RunFinished("post-game", false, 90*time.Second, 3)
+	m.RunDeviated("post-game", DurationTooSlow)
+	m.RunStarted("pre-game")
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	want := []string{
+		`cron_runner_runs_total{job="post-game",result="success"} 1`,
+		`cron_runner_runs_total{job="post-game",result="failure"} 1`,
+		`cron_runner_run_duration_seconds_bucket{job="post-game",le="1"} 0`,
+		`cron_runner_run_duration_seconds_bucket{job="post-game",le="5"} 1`,
+		`cron_runner_run_duration_seconds_bucket{job="post-game",le="120"} 2`,
+		`cron_runner_run_duration_seconds_bucket{job="post-game",le="+Inf"} 2`,
+		`cron_runner_run_duration_seconds_sum{job="post-game"} 93`,
+		`cron_runner_run_duration_seconds_count{job="post-game"} 2`,
+		`cron_runner_run_duration_deviations_total{deviation="too_fast",job="post-game"} 0`,
+		`cron_runner_run_duration_deviations_total{deviation="too_slow",job="post-game"} 1`,
+		`cron_runner_retries_total{job="post-game"} 2`,
+		`cron_runner_run_in_progress{job="post-game"} 0`,
+		`cron_runner_run_in_progress{job="pre-game"} 1`,
+	}
+	for _, w := range want {
+		if !strings.Contains(out, w+"\n") {
+			t.Fatalf("expected line %q in output:\n%s", w, out)
+		}
+	}
+}
+
+func TestRunSkippedClearsInProgress(t *testing.T) {
+	m := New()
+	m.RunStarted("poll")
+	m.RunSkipped("poll")
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `cron_runner_run_in_progress{job="poll"} 0`) {
+		t.Fatalf("expected no run in progress, got:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `cron_runner_runs_total{job="poll",result="success"} 0`) {
+		t.Fatalf("expected a skipped run not to be counted, got:\n%s", buf.String())
+	}
+}
+
+func TestNewTwiceDoesNotPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("constructing metrics twice panicked: %v", r)
+		}
+	}()
+	a, b := New(), New()
+	a.SetBuildInfo("1.0.0", "abc123")
+	b.SetBuildInfo("1.0.1", "def456")
+	a.RunStarted("post-game")
+	b.RunStarted("post-game")
+
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `version="1.0.1"`) || strings.Contains(buf.String(), `version="1.0.0"`) {
+		t.Fatalf("expected each instance to keep its own registry, got:\n%s", buf.String())
+	}
+}
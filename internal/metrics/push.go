@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Push sends the current metrics to the Prometheus Pushgateway at gatewayURL,
+// replacing the group of job, so a one-shot run that exits before any scrape
+// is still observable. Only the series of job are pushed, without their job
+// label: the Pushgateway sets it from the grouping key.
+func (m *Metrics) Push(ctx context.Context, gatewayURL, job string) error {
+	return push.New(gatewayURL, job).
+		Gatherer(jobGatherer(m.reg, job)).
+		Format(expfmt.NewFormat(expfmt.TypeTextPlain)).
+		PushContext(ctx)
+}
+
+// jobGatherer gathers g, keeping series labeled job="job" (with that label
+// dropped) and series without a job label.
+func jobGatherer(g prometheus.Gatherer, job string) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		mfs, err := g.Gather()
+		if err != nil {
+			return nil, err
+		}
+		out := mfs[:0]
+		for _, mf := range mfs {
+			kept := mf.Metric[:0]
+			for _, metric := range mf.Metric {
+				if labels, ok := withoutJob(metric.Label, job); ok {
+					metric.Label = labels
+					kept = append(kept, metric)
+				}
+			}
+			if len(kept) > 0 {
+				mf.Metric = kept
+				out = append(out, mf)
+			}
+		}
+		return out, nil
+	})
+}
+
+// withoutJob drops the job label from labels. It reports false when the
+// job label is present with another value.
+func withoutJob(labels []*dto.LabelPair, job string) ([]*dto.LabelPair, bool) {
+	for i, l := range labels {
+		if l.GetName() == "job" {
+			if l.GetValue() != job {
+				return nil, false
+			}
+			return append(labels[:i:i], labels[i+1:]...), true
+		}
+	}
+	return labels, true
+}
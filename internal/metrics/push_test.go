@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushReplacesJobGroup(t *testing.T) {
+	var method, path, body string
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.EscapedPath(), string(b)
+	}))
+	defer gw.Close()
+
+	m := New()
+	m.RunStarted("post game")
+	m.RunFinished("post game", true, 3*time.Second, 1)
+	m.RunStarted("pre-game")
+	if err := m.Push(context.Background(), gw.URL+"/", "post game"); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut || path != "/metrics/job/post+game" {
+		t.Fatalf("expected PUT /metrics/job/post+game, got %s %s", method, path)
+	}
+	if !strings.Contains(body, `cron_runner_runs_total{result="success"} 1`) {
+		t.Fatalf("expected the run counter without its job label in the pushed body, got:\n%s", body)
+	}
+	if strings.Contains(body, "pre-game") || !strings.Contains(body, "cron_runner_build_info{") {
+		t.Fatalf("expected only the pushed job's series and the process-wide ones, got:\n%s", body)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad metrics", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	if err := m.Push(context.Background(), failing.URL, "post game"); err == nil || !strings.Contains(err.Error(), "bad metrics") {
+		t.Fatalf("expected the gateway's error, got %v", err)
+	}
+}
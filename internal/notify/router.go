@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"context"
+	"path"
+
+	"github.com/rs/zerolog"
+)
+
+// Notifier delivers failure notifications in the background. Both Webhook
+// and Router implement it.
+type Notifier interface {
+	Notify(f Failure)
+	Wait(ctx context.Context)
+}
+
+// Route sends failures of pipelines whose name matches Pattern, a path.Match
+// glob such as "billing-*", to the webhook at URL.
+type Route struct {
+	Pattern string
+	URL     string
+}
+
+type route struct {
+	pattern string
+	hook    *Webhook
+}
+
+// Router splits a failure by failed pipeline, so each team is alerted only
+// about its own pipelines. Each failed pipeline goes to the first route that
+// matches it; routes sharing a webhook get one notification listing all of
+// their pipelines. Pipelines no route matches, and failures without
+// per-pipeline results (e.g. the job never started), go to the fallback.
+type Router struct {
+	routes   []route
+	fallback *Webhook // nil = unrouted failures are only logged
+	log      zerolog.Logger
+}
+
+// NewRouter creates a Router for routes, tried in order. fallbackURL may be
+// empty.
+func NewRouter(routes []Route, fallbackURL string, log zerolog.Logger) *Router {
+	r := &Router{log: log.With().Str("component", "notify").Logger()}
+	hooks := make(map[string]*Webhook)
+	for _, rt := range routes {
+		if hooks[rt.URL] == nil {
+			hooks[rt.URL] = New(rt.URL, log)
+		}
+		r.routes = append(r.routes, route{pattern: rt.Pattern, hook: hooks[rt.URL]})
+	}
+	if fallbackURL != "" {
+		r.fallback = New(fallbackURL, log)
+	}
+	return r
+}
+
+// Notify sends f, split by route, asynchronously. It never blocks the caller.
+func (r *Router) Notify(f Failure) {
+	var hooks []*Webhook
+	routed := make(map[*Webhook][]string)
+	var unrouted []string
+	for _, p := range f.FailedPipelines {
+		hook := r.match(p)
+		if hook == nil {
+			unrouted = append(unrouted, p)
+			continue
+		}
+		if routed[hook] == nil {
+			hooks = append(hooks, hook)
+		}
+		routed[hook] = append(routed[hook], p)
+	}
+
+	for _, hook := range hooks {
+		g := f
+		g.FailedPipelines = routed[hook]
+		hook.Notify(g)
+	}
+
+	if len(f.FailedPipelines) > 0 && len(unrouted) == 0 {
+		return
+	}
+	if r.fallback == nil {
+		r.log.Warn().
+			Str("job_id", f.JobID).
+			Strs("pipelines", unrouted).
+			Msg("notify_unrouted_no_fallback")
+		return
+	}
+	g := f
+	g.FailedPipelines = unrouted
+	r.fallback.Notify(g)
+}
+
+func (r *Router) match(pipeline string) *Webhook {
+	for _, rt := range r.routes {
+		if ok, _ := path.Match(rt.pattern, pipeline); ok {
+			return rt.hook
+		}
+	}
+	return nil
+}
+
+// Wait blocks until every route's pending notifications are delivered or
+// given up on, or ctx expires.
+func (r *Router) Wait(ctx context.Context) {
+	for _, rt := range r.routes {
+		rt.hook.Wait(ctx)
+	}
+	if r.fallback != nil {
+		r.fallback.Wait(ctx)
+	}
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// target is a mock webhook recording the failed pipelines of each payload.
+type target struct {
+	*httptest.Server
+	mu  sync.Mutex
+	got [][]string
+}
+
+func newTarget(t *testing.T) *target {
+	tg := &target{}
+	tg.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var f Failure
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		tg.mu.Lock()
+		tg.got = append(tg.got, f.FailedPipelines)
+		tg.mu.Unlock()
+	}))
+	t.Cleanup(tg.Close)
+	return tg
+}
+
+func (tg *target) payloads() [][]string {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	return tg.got
+}
+
+func TestRouterRoutesEachFailedPipeline(t *testing.T) {
+	billing, analytics, fallback := newTarget(t), newTarget(t), newTarget(t)
+	r := NewRouter([]Route{
+		{Pattern: "billing-*", URL: billing.URL},
+		{Pattern: "analytics", URL: analytics.URL},
+		{Pattern: "billing-*-eu", URL: analytics.URL}, // shadowed by the first route
+	}, fallback.URL, zerolog.Nop())
+
+	r.Notify(Failure{JobID: "job-1", FailedPipelines: []string{"billing-invoices", "analytics", "billing-refunds-eu", "games"}})
+	r.Wait(context.Background())
+
+	if got, want := billing.payloads(), [][]string{{"billing-invoices", "billing-refunds-eu"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("billing: expected %v, got %v", want, got)
+	}
+	if got, want := analytics.payloads(), [][]string{{"analytics"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("analytics: expected %v, got %v", want, got)
+	}
+	if got, want := fallback.payloads(), [][]string{{"games"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("fallback: expected %v, got %v", want, got)
+	}
+}
+
+func TestRouterSendsUnroutableFailuresToFallback(t *testing.T) {
+	billing, fallback := newTarget(t), newTarget(t)
+	r := NewRouter([]Route{{Pattern: "billing-*", URL: billing.URL}}, fallback.URL, zerolog.Nop())
+
+	// The job never started, so there are no per-pipeline results to route.
+	r.Notify(Failure{Error: "unexpected status 500"})
+	// Every failed pipeline is routed, so the fallback stays quiet.
+	r.Notify(Failure{FailedPipelines: []string{"billing-invoices"}})
+	r.Wait(context.Background())
+
+	if got := fallback.payloads(); len(got) != 1 || got[0] != nil {
+		t.Fatalf("fallback: expected one payload without pipelines, got %v", got)
+	}
+	if got := billing.payloads(); len(got) != 1 {
+		t.Fatalf("billing: expected one payload, got %v", got)
+	}
+
+	// Without a fallback, unroutable failures are dropped.
+	NewRouter(nil, "", zerolog.Nop()).Notify(Failure{FailedPipelines: []string{"games"}})
+}
@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"cron-runner/internal/retry"
+
+	"github.com/rs/zerolog"
+)
+
+const webhookTimeout = 5 * time.Second
+
+// webhookRetry keeps delivery short: a dead webhook gives up within seconds.
+var webhookRetry = retry.Config{
+	MaxRetries:     2,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	BackoffFactor:  2,
+}
+
+// Failure is the payload POSTed to the webhook when a pipeline run fails.
+type Failure struct {
+	JobID           string            `json:"job_id,omitempty"`
+	Endpoint        string            `json:"endpoint"`
+	Attempts        int               `json:"attempts"`
+	DurationMs      int64             `json:"duration_ms"`
+	StatusCode      int               `json:"status_code,omitempty"`
+	Error           string            `json:"error,omitempty"`
+	FailedPipelines []string          `json:"failed_pipelines,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"` // RUN_METADATA
+}
+
+// Webhook posts failure notifications to a URL (e.g. a Slack incoming webhook
+// relay). Delivery is best-effort: it runs in the background with its own
+// timeout and retries, and failures are only logged.
+type Webhook struct {
+	url     string
+	client  *http.Client
+	retry   retry.Config
+	log     zerolog.Logger
+	pending sync.WaitGroup
+}
+
+func New(url string, log zerolog.Logger) *Webhook {
+	return &Webhook{
+		url:    url,
+		client: &http.Client{Timeout: webhookTimeout},
+		retry:  webhookRetry,
+		log:    log.With().Str("component", "notify").Logger(),
+	}
+}
+
+// Notify sends f asynchronously. It never blocks the caller.
+func (w *Webhook) Notify(f Failure) {
+	w.pending.Add(1)
+	go func() {
+		defer w.pending.Done()
+		w.send(f)
+	}()
+}
+
+// Wait blocks until all pending notifications are delivered or given up on,
+// or ctx expires. Used before exiting so a last notification isn't dropped.
+func (w *Webhook) Wait(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		w.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+func (w *Webhook) send(f Failure) {
+	body, err := json.Marshal(f)
+	if err != nil {
+		w.log.Warn().Err(err).Msg("notify_marshal_failed")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		w.log.Warn().Err(err).Msg("notify_request_create_failed")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	result := retry.Do(ctx, w.client, req, w.retry, w.log)
+	if result.FinalError != nil {
+		w.log.Error().
+			Err(result.FinalError).
+			Str("job_id", f.JobID).
+			Int("attempts", result.Attempts).
+			Msg("notify_delivery_failed")
+		return
+	}
+	defer result.Response.Body.Close()
+
+	if result.Response.StatusCode >= 300 {
+		w.log.Error().
+			Int("status", result.Response.StatusCode).
+			Str("job_id", f.JobID).
+			Int("attempts", result.Attempts).
+			Msg("notify_delivery_failed")
+		return
+	}
+	w.log.Info().Str("job_id", f.JobID).Msg("notify_delivered")
+}
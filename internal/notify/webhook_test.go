@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func fastWebhook(url string, log zerolog.Logger) *Webhook {
+	w := New(url, log)
+	w.retry.InitialBackoff = time.Millisecond
+	w.retry.MaxBackoff = time.Millisecond
+	return w
+}
+
+func TestNotifyRetriesAndDelivers(t *testing.T) {
+	var calls int
+	var got Failure
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("invalid payload %q: %v", body, err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	w := fastWebhook(srv.URL, zerolog.Nop())
+	w.Notify(Failure{
+		JobID:           "job-1",
+		Attempts:        2,
+		Error:           "job failed",
+		FailedPipelines: []string{"games"},
+	})
+	w.Wait(context.Background())
+
+	if calls != 2 {
+		t.Fatalf("expected delivery on the second attempt, got %d calls", calls)
+	}
+	if got.JobID != "job-1" || got.Error != "job failed" || len(got.FailedPipelines) != 1 {
+		t.Fatalf("unexpected payload %+v", got)
+	}
+}
+
+func TestNotifyLogsUndeliverable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	w := fastWebhook(srv.URL, zerolog.New(&buf))
+
+	start := time.Now()
+	w.Notify(Failure{JobID: "job-1"})
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatalf("expected Notify not to block")
+	}
+	w.Wait(context.Background())
+
+	if !strings.Contains(buf.String(), `"notify_delivery_failed"`) {
+		t.Fatalf("expected a delivery failure log, got:\n%s", buf.String())
+	}
+}
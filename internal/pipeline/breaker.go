@@ -0,0 +1,173 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"cron-runner/internal/retry"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrCircuitOpen is returned without contacting the backend while the
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open: backend failing, not sending request")
+
+// Circuit breaker states.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitState is a snapshot of the circuit breaker for /health.
+type CircuitState struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"` // set while open
+}
+
+// outcome is how a backend call counts toward the circuit breaker.
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeIgnored // cancelled by the caller; says nothing about the backend
+)
+
+// circuitBreaker stops calls to a backend that keeps failing. After threshold
+// consecutive failures it opens and fails calls fast for cooldown, then lets
+// a single half-open probe through: a success closes it, a failure reopens it
+// for another cooldown. Any success resets the failure count.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	log       zerolog.Logger
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+}
+
+// newCircuitBreaker returns nil when threshold is 0; a nil breaker allows
+// every call.
+func newCircuitBreaker(threshold int, cooldown time.Duration, log zerolog.Logger) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		log:       log,
+		now:       time.Now,
+		state:     CircuitClosed,
+	}
+}
+
+// allow reports whether a call may be made, moving an open breaker whose
+// cooldown has passed to half-open for one probe.
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+		b.log.Info().Msg("circuit breaker half-open, sending probe")
+		return nil
+	case CircuitHalfOpen:
+		return ErrCircuitOpen // a probe is already in flight
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of an allowed call.
+func (b *circuitBreaker) record(o outcome) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch o {
+	case outcomeSuccess:
+		if b.state != CircuitClosed {
+			b.log.Info().Int("failures", b.failures).Msg("circuit breaker closed")
+		}
+		b.state, b.failures = CircuitClosed, 0
+	case outcomeFailure:
+		b.failures++
+		if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+			if b.state != CircuitOpen {
+				b.log.Warn().
+					Int("failures", b.failures).
+					Dur("cooldown", b.cooldown).
+					Msg("circuit breaker opened")
+			}
+			b.state, b.openedAt = CircuitOpen, b.now()
+		}
+	case outcomeIgnored:
+		// Give the probe slot back; the cooldown has already elapsed, so the
+		// next call probes again.
+		if b.state == CircuitHalfOpen {
+			b.state = CircuitOpen
+		}
+	}
+}
+
+func (b *circuitBreaker) snapshot() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := CircuitState{State: b.state, ConsecutiveFailures: b.failures}
+	if b.state == CircuitOpen {
+		until := b.openedAt.Add(b.cooldown)
+		s.OpenUntil = &until
+	}
+	return s
+}
+
+// CircuitState returns the circuit breaker's state; ok is false when
+// CIRCUIT_FAILURE_THRESHOLD is unset and there is no breaker.
+func (c *Client) CircuitState() (state CircuitState, ok bool) {
+	if c.breaker == nil {
+		return CircuitState{}, false
+	}
+	return c.breaker.snapshot(), true
+}
+
+// do sends req through retry.Do behind the circuit breaker. Network errors
+// and 5xx responses left after retrying count as failures; any other
+// response means the backend is reachable and counts as a success.
+func (c *Client) do(ctx context.Context, req *http.Request, rc retry.Config, log zerolog.Logger) retry.Result {
+	if err := c.breaker.allow(); err != nil {
+		log.Warn().
+			Str("host", req.URL.Host).
+			Msg("circuit breaker open, failing fast")
+		return retry.Result{FinalError: err}
+	}
+
+	result := retry.Do(ctx, c.httpClient, req, rc, log)
+	switch {
+	case ctx.Err() != nil:
+		c.breaker.record(outcomeIgnored)
+	case result.FinalError != nil:
+		c.breaker.record(outcomeFailure)
+	case result.Response != nil && result.Response.StatusCode >= 500:
+		c.breaker.record(outcomeFailure)
+	default:
+		c.breaker.record(outcomeSuccess)
+	}
+	return result
+}
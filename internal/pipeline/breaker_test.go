@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestCircuitBreakerOpensAfterThresholdAndFailsFast(t *testing.T) {
+	var calls int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(http.StatusBadGateway, "down"), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.breaker = newCircuitBreaker(2, time.Hour, zerolog.Nop())
+
+	for i := 0; i < 2; i++ {
+		client.TriggerEndpoint(context.Background(), "/v1/internal/alerts")
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 backend calls before opening, got %d", calls)
+	}
+	if cs, _ := client.CircuitState(); cs.State != CircuitOpen || cs.OpenUntil == nil {
+		t.Fatalf("expected open breaker with open_until, got %+v", cs)
+	}
+
+	result := client.TriggerEndpoint(context.Background(), "/v1/internal/alerts")
+	if !errors.Is(result.Error, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", result.Error)
+	}
+	if calls != 2 {
+		t.Fatalf("expected no backend call while open, got %d calls", calls)
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(1, time.Minute, zerolog.Nop())
+	b.now = func() time.Time { return now }
+
+	b.record(outcomeFailure)
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open breaker to reject, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected a probe after the cooldown, got %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected only one probe while half-open, got %v", err)
+	}
+
+	// A failed probe reopens for a fresh cooldown.
+	b.record(outcomeFailure)
+	if s := b.snapshot(); s.State != CircuitOpen || !s.OpenUntil.Equal(now.Add(time.Minute)) {
+		t.Fatalf("expected reopened breaker, got %+v", s)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected a second probe, got %v", err)
+	}
+	b.record(outcomeSuccess)
+	if s := b.snapshot(); s.State != CircuitClosed || s.ConsecutiveFailures != 0 {
+		t.Fatalf("expected closed breaker with reset count, got %+v", s)
+	}
+}
+
+func TestCircuitBreakerResetsOnSuccess(t *testing.T) {
+	b := newCircuitBreaker(3, time.Minute, zerolog.Nop())
+	b.record(outcomeFailure)
+	b.record(outcomeFailure)
+	b.record(outcomeSuccess)
+	b.record(outcomeFailure)
+	b.record(outcomeFailure)
+	if s := b.snapshot(); s.State != CircuitClosed || s.ConsecutiveFailures != 2 {
+		t.Fatalf("expected closed breaker with 2 failures, got %+v", s)
+	}
+}
+
+func TestCircuitBreakerIgnoresClientErrorsAndCancellation(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusNotFound, "nope"), nil
+	})
+	client := newTestClient("http://example.test", transport)
+	client.breaker = newCircuitBreaker(1, time.Hour, zerolog.Nop())
+
+	client.TriggerEndpoint(context.Background(), "/v1/internal/alerts")
+	if cs, _ := client.CircuitState(); cs.State != CircuitClosed {
+		t.Fatalf("expected a 4xx to leave the breaker closed, got %+v", cs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.TriggerEndpoint(ctx, "/v1/internal/alerts")
+	if cs, _ := client.CircuitState(); cs.State != CircuitClosed || cs.ConsecutiveFailures != 0 {
+		t.Fatalf("expected cancellation not to count, got %+v", cs)
+	}
+}
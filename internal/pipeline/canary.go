@@ -0,0 +1,69 @@
+package pipeline
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+)
+
+// CanaryHeader is set to "true" on every request of a run routed to the
+// canary backend.
+const CanaryHeader = "X-Canary"
+
+// canaryRouter sends a fixed share of runs to a second backend, for
+// migrations between blue/green deployments.
+type canaryRouter struct {
+	baseURL string
+	percent float64 // 0–100
+
+	mu  sync.Mutex // guards rng, which is not safe for concurrent use
+	rng *rand.Rand
+}
+
+// newCanaryRouter returns a router for percent (0–100) of runs, drawing from
+// src. Returns nil when no canary is configured.
+func newCanaryRouter(baseURL string, percent float64, src rand.Source) *canaryRouter {
+	if baseURL == "" || percent <= 0 {
+		return nil
+	}
+	return &canaryRouter{baseURL: baseURL, percent: percent, rng: rand.New(src)}
+}
+
+// pick reports whether the next run goes to the canary.
+func (r *canaryRouter) pick() bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Float64()*100 < r.percent
+}
+
+type canaryKey struct{}
+
+// withCanary marks every request made with ctx as part of a canary run.
+func withCanary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, canaryKey{}, true)
+}
+
+func isCanary(ctx context.Context) bool {
+	v, _ := ctx.Value(canaryKey{}).(bool)
+	return v
+}
+
+// runEndpointURL is endpointURL against the backend the run in ctx was
+// routed to.
+func (c *Client) runEndpointURL(ctx context.Context, endpoint string) string {
+	if isCanary(ctx) && c.canary != nil {
+		return joinEndpointURL(c.canary.baseURL, c.basePath, endpoint)
+	}
+	return c.endpointURL(endpoint)
+}
+
+// markCanary sets CanaryHeader on req when ctx belongs to a canary run.
+func markCanary(ctx context.Context, req *http.Request) {
+	if isCanary(ctx) {
+		req.Header.Set(CanaryHeader, "true")
+	}
+}
@@ -0,0 +1,80 @@
+package pipeline
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"testing"
+)
+
+func TestCanaryRouterApproximatesPercent(t *testing.T) {
+	for _, percent := range []float64{5, 20, 50} {
+		r := newCanaryRouter("http://canary.test", percent, rand.NewPCG(1, 2))
+		const runs = 20000
+		canary := 0
+		for range runs {
+			if r.pick() {
+				canary++
+			}
+		}
+		if got := float64(canary) / runs * 100; math.Abs(got-percent) > 1 {
+			t.Fatalf("CANARY_PERCENT=%v: expected about %v%% canary runs, got %.2f%%", percent, percent, got)
+		}
+	}
+
+	if r := newCanaryRouter("http://canary.test", 0, rand.NewPCG(1, 2)); r != nil || r.pick() {
+		t.Fatalf("expected no router at 0%%")
+	}
+	always := newCanaryRouter("http://canary.test", 100, rand.NewPCG(1, 2))
+	for range 100 {
+		if !always.pick() {
+			t.Fatalf("expected every run routed to the canary at 100%%")
+		}
+	}
+}
+
+func TestTriggerAllRoutesCanaryRunsWithHeader(t *testing.T) {
+	type seen struct{ host, canary string }
+	var reqs []seen
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		reqs = append(reqs, seen{req.URL.Host, req.Header.Get(CanaryHeader)})
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"completed"}}`), nil
+	})
+
+	client := newTestClient("http://primary.test", transport)
+	client.canary = newCanaryRouter("http://canary.test", 100, rand.NewPCG(1, 2))
+	if result := client.TriggerAll(context.Background(), "/v1/internal/pipelines/post-game"); !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+	if len(reqs) < 2 {
+		t.Fatalf("expected a start and a poll, got %v", reqs)
+	}
+	for _, r := range reqs {
+		if r.host != "canary.test" || r.canary != "true" {
+			t.Fatalf("expected every request of a canary run on the canary with %s, got %v", CanaryHeader, reqs)
+		}
+	}
+	if p, _ := client.Progress(); !p.Canary {
+		t.Fatalf("expected progress to record the canary run")
+	}
+	reqs = nil
+	if err := client.CancelJob(context.Background(), "job-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 1 || reqs[0].host != "canary.test" {
+		t.Fatalf("expected the canary job cancelled on the canary, got %v", reqs)
+	}
+
+	reqs = nil
+	client.canary = newCanaryRouter("http://canary.test", 0.001, rand.NewPCG(1, 2))
+	client.TriggerAll(context.Background(), "/v1/internal/pipelines/post-game")
+	for _, r := range reqs {
+		if r.host != "primary.test" || r.canary != "" {
+			t.Fatalf("expected a primary run without %s, got %v", CanaryHeader, reqs)
+		}
+	}
+}
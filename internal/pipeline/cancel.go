@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+)
+
+// ErrJobCancelled is TriggerResult.Error for a job that reported the
+// "cancelled" status, e.g. after CancelJob.
+var ErrJobCancelled = errors.New("pipeline job was cancelled")
+
+// CancelJob asks the backend to abort a running job via
+// DELETE /v1/internal/pipelines/jobs/{job_id}, with the client's usual auth
+// and retries. A 404 or 409 means the job already finished (or never
+// existed) and is not an error: there is nothing left to cancel.
+func (c *Client) CancelJob(ctx context.Context, jobID string) error {
+	if err := c.validateJobID(jobID); err != nil {
+		return err
+	}
+	// A job started on the canary backend is cancelled there too.
+	if p, ok := c.Progress(); ok && p.Canary && p.JobID == jobID {
+		ctx = withCanary(ctx)
+	}
+	url := c.runEndpointURL(ctx, "/v1/internal/pipelines/jobs/"+neturl.PathEscape(jobID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setAuth(req)
+	markCanary(ctx, req)
+
+	result := c.do(ctx, req, c.retryCfg, c.log)
+	if result.FinalError != nil {
+		return fmt.Errorf("failed to cancel job: %w", result.FinalError)
+	}
+	if result.Response == nil {
+		return fmt.Errorf("no response received")
+	}
+	defer result.Response.Body.Close()
+
+	switch status := result.Response.StatusCode; {
+	case status == http.StatusNotFound || status == http.StatusConflict:
+		c.log.Info().
+			Str("job_id", jobID).
+			Int("status_code", status).
+			Msg("job already finished, nothing to cancel")
+		return nil
+	case status < 200 || status >= 300:
+		body, _ := io.ReadAll(result.Response.Body)
+		return fmt.Errorf("cancel returned status %d: %s", status, truncateForLog(string(body), endpointErrorBodyMaxLen))
+	}
+
+	c.log.Info().
+		Str("job_id", jobID).
+		Int("attempts", result.Attempts).
+		Msg("pipeline job cancel requested")
+	return nil
+}
@@ -0,0 +1,77 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestCancelJobSendsDelete(t *testing.T) {
+	var method, path, auth string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		method, path, auth = req.Method, req.URL.Path, req.Header.Get("Authorization")
+		return jsonResponse(http.StatusAccepted, `{"status":"ok"}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	if err := client.CancelJob(context.Background(), "job-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if method != http.MethodDelete || path != "/v1/internal/pipelines/jobs/job-1" {
+		t.Fatalf("expected DELETE /v1/internal/pipelines/jobs/job-1, got %s %s", method, path)
+	}
+	if auth != "Bearer test-token" {
+		t.Fatalf("expected bearer auth, got %q", auth)
+	}
+}
+
+func TestCancelJobTreatsFinishedJobAsSuccess(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusConflict} {
+		transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(status, `{"status":"error"}`), nil
+		})
+		client := newTestClient("http://example.test", transport)
+		if err := client.CancelJob(context.Background(), "job-1"); err != nil {
+			t.Fatalf("status %d: expected no error, got %v", status, err)
+		}
+	}
+}
+
+func TestCancelJobErrors(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusForbidden, `{"status":"error"}`), nil
+	})
+	client := newTestClient("http://example.test", transport)
+
+	if err := client.CancelJob(context.Background(), "job-1"); err == nil {
+		t.Fatalf("expected error for status 403")
+	}
+	if err := client.CancelJob(context.Background(), "../all"); !errors.Is(err, ErrInvalidJobID) {
+		t.Fatalf("expected ErrInvalidJobID, got %v", err)
+	}
+}
+
+func TestTriggerAllStopsOnCancelledStatus(t *testing.T) {
+	polls := 0
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		polls++
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"cancelled","pipelines_total":5,"pipelines_completed":2}}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	result := client.TriggerAll(context.Background(), "/test")
+
+	if result.Success {
+		t.Fatalf("expected a cancelled job not to succeed")
+	}
+	if !errors.Is(result.Error, ErrJobCancelled) {
+		t.Fatalf("expected ErrJobCancelled, got %v", result.Error)
+	}
+	if polls != 1 {
+		t.Fatalf("expected polling to stop at the cancelled status, polled %d times", polls)
+	}
+}
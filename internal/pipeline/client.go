@@ -3,25 +3,88 @@ package pipeline
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"cron-runner/internal/config"
+	"cron-runner/internal/notify"
+	"cron-runner/internal/redact"
 	"cron-runner/internal/retry"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Client handles communication with the backend pipeline API.
 type Client struct {
 	httpClient *http.Client
 	baseURL    string
-	authToken  string
+	basePath   string // API_BASE_PATH, inserted between baseURL and every endpoint
 	retryCfg   retry.Config
 	pollCfg    PollConfig
+	jobIDRe    *regexp.Regexp
 	log        zerolog.Logger
+
+	tokenMu   sync.RWMutex
+	authToken string
+	tokenFile string // optional; re-read on an auth failure during the first start
+
+	startAttempted atomic.Bool // set once the first startJob has been sent
+	jobRunning     atomic.Bool // held for the duration of a TriggerAll
+
+	progress progressTracker // current or last TriggerAll, for Progress
+
+	// treat409AsRunning attaches to the job_id in a 409 start response
+	// instead of failing, for backends that reject duplicate starts.
+	treat409AsRunning bool
+
+	// idempotencyHeader, when set, carries a random key on every job start,
+	// the same one across its retries, so the backend can drop duplicates.
+	idempotencyHeader string
+
+	httpTimingDebug bool // log DNS/connect/TLS/TTFB timings of each start request
+
+	resultLogs otellog.Logger // nil = disabled; emits each run's result as an OTel log record
+
+	// statusPolicy decides how each per-pipeline status counts toward the run.
+	statusPolicy StatusPolicy
+
+	logSummary bool // log a one-line Summary after every TriggerAll
+
+	dryRun bool // log what would be sent instead of calling the backend
+
+	deny *redact.Denylist // REDACT_KEYS; headers masked in dry-run logs
+
+	startupRetries int           // connection retries for startup checks
+	startupBackoff time.Duration // first wait between them; doubles
+
+	notifier notify.Notifier   // optional; notified when a TriggerAll or TriggerEndpoint fails
+	metadata map[string]string // RUN_METADATA, attached to failure notifications
+
+	breaker *circuitBreaker // nil = disabled; guards every retry.Do call
+
+	canary *canaryRouter // nil = disabled; routes a share of runs to another backend
+
+	// waitForURL, when set, must answer 2xx before each trigger is sent;
+	// it is polled every waitForInterval for up to waitForTimeout, through
+	// waitForClient rather than the backend transport.
+	waitForURL      string
+	waitForTimeout  time.Duration
+	waitForInterval time.Duration
+	waitForClient   *http.Client
 }
 
 // PollConfig holds settings for job status polling.
@@ -29,33 +92,129 @@ type PollConfig struct {
 	InitialInterval time.Duration
 	MaxInterval     time.Duration
 	MaxWaitTime     time.Duration
+	ProgressTimeout time.Duration // 0 = disabled; fail if pipelines_completed stops advancing this long
+	Mode            string        // PollModeShort (default) or PollModeLongPoll
+	LongPollTimeout time.Duration // per-request timeout for a single long poll
+	RequestTimeout  time.Duration // per-request timeout for a short status poll; 0 = the client's RequestTimeout
+	LogInterval     time.Duration // 0 = log every poll; otherwise at most one status log per interval
+	BackoffFactor   float64       // interval growth after each short poll; 0 = defaultPollBackoffFactor
+	Jitter          float64       // fraction (0–1) each short-poll wait is randomly shortened by
 }
 
-// NewClient creates a new pipeline client.
-func NewClient(cfg *config.Config, log zerolog.Logger) *Client {
-	return &Client{
-		httpClient: &http.Client{Timeout: cfg.RequestTimeout},
-		baseURL:    cfg.BackendURL,
-		authToken:  cfg.PipelineAuth,
+// Poll modes.
+const (
+	PollModeShort    = "poll"
+	PollModeLongPoll = "longpoll"
+)
+
+// NewClient creates a new pipeline client. It fails if the backend transport
+// settings (proxy, mTLS and CA files, SSH tunnel) cannot be applied, rather
+// than connecting without them.
+func NewClient(cfg *config.Config, log zerolog.Logger) (*Client, error) {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("backend transport: %w", err)
+	}
+	c := &Client{
+		httpClient: &http.Client{
+			Timeout:   cfg.RequestTimeout,
+			Transport: transport,
+		},
+		baseURL:  cfg.BackendURL,
+		basePath: cfg.APIBasePath,
 		retryCfg: retry.Config{
 			MaxRetries:     cfg.MaxRetries,
 			InitialBackoff: cfg.InitialBackoff,
 			MaxBackoff:     cfg.MaxBackoff,
 			BackoffFactor:  cfg.BackoffFactor,
+			Jitter:         cfg.Jitter,
+			MaxElapsedTime: cfg.RetryMaxElapsed,
+			IdempotentOnly: cfg.RetryIdempotentOnly,
+
+			IdempotencyHeader: cfg.IdempotencyKeyHeader,
+
+			RetryableStatusCodes: cfg.RetryableStatusCodes,
+			BackoffStrategy:      retry.Strategy(cfg.BackoffStrategy),
 		},
 		pollCfg: PollConfig{
 			InitialInterval: cfg.PollInitialInterval,
 			MaxInterval:     cfg.PollMaxInterval,
 			MaxWaitTime:     cfg.PollMaxWaitTime,
+			ProgressTimeout: cfg.PollProgressTimeout,
+			Mode:            cfg.PollMode,
+			LongPollTimeout: cfg.PollLongPollTimeout,
+			RequestTimeout:  cfg.PollRequestTimeout,
+			LogInterval:     cfg.PollLogInterval,
+			BackoffFactor:   cfg.PollBackoffFactor,
+			Jitter:          cfg.PollJitter,
 		},
-		log: log.With().Str("component", "pipeline-client").Logger(),
+		jobIDRe:   regexp.MustCompile(jobIDPatternOrDefault(cfg.JobIDPattern)),
+		log:       log.With().Str("component", "pipeline-client").Logger(),
+		authToken: cfg.PipelineAuth,
+		tokenFile: cfg.PipelineAuthFile,
+
+		treat409AsRunning: cfg.Treat409AsRunning,
+		statusPolicy:      StatusPolicy(cfg.StatusPolicy),
+		logSummary:        cfg.LogRunSummary,
+		dryRun:            cfg.DryRun,
+		deny:              redact.New(cfg.RedactKeys),
+		metadata:          cfg.RunMetadata,
+		startupRetries:    cfg.StartupCheckRetries,
+		startupBackoff:    cfg.StartupCheckBackoff,
+
+		httpTimingDebug: cfg.HTTPTimingDebug,
+
+		waitForURL:      cfg.WaitForURL,
+		waitForTimeout:  cfg.WaitForTimeout,
+		waitForInterval: defaultWaitForInterval,
+		waitForClient:   &http.Client{Timeout: cfg.RequestTimeout},
+	}
+	c.canary = newCanaryRouter(cfg.CanaryBackendURL, cfg.CanaryPercent, rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	if cfg.OtelResultLogs {
+		c.resultLogs = global.Logger("cron-runner/pipeline")
+	}
+	if cfg.SendIdempotencyKey {
+		c.idempotencyHeader = cfg.IdempotencyKeyHeader
+	}
+	if cfg.TLSInsecureSkipVerify {
+		c.log.Warn().Msg("TLS_INSECURE_SKIP_VERIFY is set: backend TLS certificates are NOT verified, never use this outside development")
 	}
+	if cfg.SSHTunnelHost != "" && cfg.SSHTunnelKnownHosts == "" && cfg.SSHTunnelInsecureHostKey {
+		c.log.Warn().Msg("SSH_TUNNEL_INSECURE_HOST_KEY is set: the bastion's host key is NOT verified, never use this outside development")
+	}
+	if cfg.SessionLoginPath != "" {
+		c.httpClient.Transport = newSessionTransport(c.httpClient.Transport,
+			c.endpointURL(cfg.SessionLoginPath), cfg.SessionUsername, cfg.SessionPassword, c.log)
+	}
+	c.breaker = newCircuitBreaker(cfg.CircuitFailureThreshold, cfg.CircuitCooldown, c.log)
+	switch {
+	case len(cfg.AlertRoutes) > 0:
+		routes := make([]notify.Route, len(cfg.AlertRoutes))
+		for i, r := range cfg.AlertRoutes {
+			routes[i] = notify.Route{Pattern: r.Pattern, URL: r.URL}
+		}
+		c.notifier = notify.NewRouter(routes, cfg.FailureWebhookURL, log)
+	case cfg.FailureWebhookURL != "":
+		c.notifier = notify.New(cfg.FailureWebhookURL, log)
+	}
+	return c, nil
+}
+
+// Transport returns the round tripper backend requests are sent through,
+// including session-cookie auth when configured, so other backend callers
+// authenticate the same way.
+func (c *Client) Transport() http.RoundTripper {
+	return c.httpClient.Transport
 }
 
 // TriggerResult contains the outcome of a pipeline trigger.
 type TriggerResult struct {
 	Success      bool
+	Degraded     bool // succeeded, but some pipelines ended in a "warn" status under the policy
+	Skipped      bool // not started because another TriggerAll was still in progress
+	DryRun       bool // DRY_RUN: nothing was sent; Success is synthetic
 	JobID        string
+	RunID        string // correlates this run's log lines (run_id); TriggerAll only
 	StatusCode   int
 	ResponseBody string
 	Attempts     int
@@ -71,7 +230,7 @@ type JobStatus struct {
 	CreatedAt          string                    `json:"created_at"`
 	StartedAt          string                    `json:"started_at,omitempty"`
 	CompletedAt        string                    `json:"completed_at,omitempty"`
-	DurationSeconds    float64                   `json:"duration_seconds,omitempty"`
+	DurationSeconds    FlexFloat64               `json:"duration_seconds,omitempty"`
 	PipelinesTotal     int                       `json:"pipelines_total"`
 	PipelinesCompleted int                       `json:"pipelines_completed"`
 	PipelinesFailed    int                       `json:"pipelines_failed"`
@@ -82,12 +241,65 @@ type JobStatus struct {
 
 // PipelineResult represents the result of a single pipeline.
 type PipelineResult struct {
-	PipelineName     string  `json:"pipeline_name"`
-	Status           string  `json:"status"`
-	Message          string  `json:"message"`
-	DurationSeconds  float64 `json:"duration_seconds,omitempty"`
-	RecordsProcessed int     `json:"records_processed,omitempty"`
-	Error            string  `json:"error,omitempty"`
+	PipelineName     string      `json:"pipeline_name"`
+	Status           string      `json:"status"`
+	Message          string      `json:"message"`
+	DurationSeconds  FlexFloat64 `json:"duration_seconds,omitempty"`
+	RecordsProcessed int         `json:"records_processed,omitempty"`
+	Error            string      `json:"error,omitempty"`
+}
+
+// DefaultJobIDPattern accepts any non-empty job ID without path separators.
+// IDs are additionally URL-escaped when building the poll URL.
+const DefaultJobIDPattern = `^[^/\\]+$`
+
+func jobIDPatternOrDefault(pattern string) string {
+	if pattern == "" {
+		return DefaultJobIDPattern
+	}
+	return pattern
+}
+
+// ErrInvalidJobID is wrapped by errors for job IDs rejected by validateJobID.
+var ErrInvalidJobID = errors.New("invalid job ID")
+
+// validateJobID rejects job IDs that would produce a broken or redirected
+// poll URL, such as IDs containing slashes or dot segments.
+func (c *Client) validateJobID(jobID string) error {
+	if jobID == "." || jobID == ".." || !c.jobIDRe.MatchString(jobID) {
+		return fmt.Errorf("%w %q: must match %s", ErrInvalidJobID, jobID, c.jobIDRe.String())
+	}
+	return nil
+}
+
+// FlexFloat64 is a float64 that also unmarshals from a JSON string
+// (e.g. "12.5"), since some backend versions encode durations that way.
+type FlexFloat64 float64
+
+func (f *FlexFloat64) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if s == "" {
+			*f = 0
+			return nil
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid numeric string %q: %w", s, err)
+		}
+		*f = FlexFloat64(v)
+		return nil
+	}
+
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = FlexFloat64(v)
+	return nil
 }
 
 // jobCreatedResponse is the response from POST /pipelines/all
@@ -109,6 +321,21 @@ type jobStatusResponse struct {
 	Data    JobStatus `json:"data"`
 }
 
+// endpointURL joins the backend base URL, the API base path and endpoint,
+// tolerating leading/trailing slashes on any of them.
+func (c *Client) endpointURL(endpoint string) string {
+	return joinEndpointURL(c.baseURL, c.basePath, endpoint)
+}
+
+func joinEndpointURL(baseURL, basePath, endpoint string) string {
+	u, err := neturl.JoinPath(baseURL, basePath, endpoint)
+	if err != nil {
+		// Leave the malformed base for http.NewRequest to report.
+		return baseURL + basePath + endpoint
+	}
+	return u
+}
+
 const endpointErrorBodyMaxLen = 512
 
 func truncateForLog(body string, maxLen int) string {
@@ -119,10 +346,27 @@ func truncateForLog(body string, maxLen int) string {
 }
 
 // TriggerEndpoint POSTs to a custom endpoint path and returns immediately.
-// Use this for fire-and-forget triggers like alert mode.
+// Use this for fire-and-forget triggers like alert mode, and for scheduled
+// jobs. A failed trigger is sent to the failure notifier, as for TriggerAll.
 func (c *Client) TriggerEndpoint(ctx context.Context, endpoint string) TriggerResult {
+	result := c.triggerEndpoint(ctx, endpoint)
+	if c.notifier != nil && !result.Success {
+		c.notifier.Notify(c.failureFor(endpoint, result))
+	}
+	return result
+}
+
+func (c *Client) triggerEndpoint(ctx context.Context, endpoint string) TriggerResult {
+	if c.dryRun {
+		return c.dryRunResult(endpoint, c.retryCfg, false, c.log)
+	}
+
 	startTime := time.Now()
-	url := c.baseURL + endpoint
+	url := c.endpointURL(endpoint)
+
+	if err := c.waitForDependency(ctx, c.log); err != nil {
+		return TriggerResult{Duration: time.Since(startTime), Error: err}
+	}
 
 	c.log.Info().
 		Str("url", url).
@@ -137,9 +381,9 @@ func (c *Client) TriggerEndpoint(ctx context.Context, endpoint string) TriggerRe
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.authToken)
+	c.setAuth(req)
 
-	result := retry.Do(ctx, c.httpClient, req, c.retryCfg, c.log)
+	result := c.do(ctx, req, c.retryCfg, c.log)
 
 	triggerResult := TriggerResult{
 		Attempts: result.Attempts,
@@ -153,6 +397,7 @@ func (c *Client) TriggerEndpoint(ctx context.Context, endpoint string) TriggerRe
 			Str("endpoint", endpoint).
 			Int("attempts", result.Attempts).
 			Dur("duration", triggerResult.Duration).
+			Float64("duration_seconds", triggerResult.Duration.Seconds()).
 			Msg("failed to trigger endpoint")
 		return triggerResult
 	}
@@ -194,6 +439,7 @@ func (c *Client) TriggerEndpoint(ctx context.Context, endpoint string) TriggerRe
 			Int("status_code", triggerResult.StatusCode).
 			Str("response_body", bodySnippet).
 			Dur("duration", triggerResult.Duration).
+			Float64("duration_seconds", triggerResult.Duration.Seconds()).
 			Msg("endpoint returned non-success status")
 		return triggerResult
 	}
@@ -204,6 +450,7 @@ func (c *Client) TriggerEndpoint(ctx context.Context, endpoint string) TriggerRe
 		Int("attempts", result.Attempts).
 		Int("status_code", triggerResult.StatusCode).
 		Dur("duration", triggerResult.Duration).
+		Float64("duration_seconds", triggerResult.Duration.Seconds()).
 		Msg("endpoint triggered successfully")
 
 	return triggerResult
@@ -213,7 +460,7 @@ func (c *Client) TriggerEndpoint(ctx context.Context, endpoint string) TriggerRe
 // Use this for read-only data fetches like the schedule endpoint.
 func (c *Client) FetchEndpoint(ctx context.Context, endpoint string) TriggerResult {
 	startTime := time.Now()
-	url := c.baseURL + endpoint
+	url := c.endpointURL(endpoint)
 
 	c.log.Info().
 		Str("url", url).
@@ -227,9 +474,9 @@ func (c *Client) FetchEndpoint(ctx context.Context, endpoint string) TriggerResu
 		}
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.authToken)
+	c.setAuth(req)
 
-	result := retry.Do(ctx, c.httpClient, req, c.retryCfg, c.log)
+	result := c.do(ctx, req, c.retryCfg, c.log)
 
 	triggerResult := TriggerResult{
 		Attempts: result.Attempts,
@@ -243,6 +490,7 @@ func (c *Client) FetchEndpoint(ctx context.Context, endpoint string) TriggerResu
 			Str("endpoint", endpoint).
 			Int("attempts", result.Attempts).
 			Dur("duration", triggerResult.Duration).
+			Float64("duration_seconds", triggerResult.Duration.Seconds()).
 			Msg("failed to fetch endpoint")
 		return triggerResult
 	}
@@ -287,17 +535,87 @@ func (c *Client) FetchEndpoint(ctx context.Context, endpoint string) TriggerResu
 		Int("attempts", result.Attempts).
 		Int("status_code", triggerResult.StatusCode).
 		Dur("duration", triggerResult.Duration).
+		Float64("duration_seconds", triggerResult.Duration.Seconds()).
 		Msg("endpoint fetched successfully")
 
 	return triggerResult
 }
 
+// ErrAlreadyRunning is returned (with Skipped set) by TriggerAll when another
+// pipeline job started by this client has not finished yet.
+var ErrAlreadyRunning = errors.New("a pipeline job is already running")
+
 // TriggerAll starts a pipeline job at the given endpoint and polls until completion.
+// Only one TriggerAll runs at a time per client; overlapping calls return
+// immediately with Skipped set rather than starting a second backend job.
 func (c *Client) TriggerAll(ctx context.Context, endpoint string) TriggerResult {
+	return c.triggerAll(ctx, endpoint, c.retryCfg)
+}
+
+// triggerAll is TriggerAll with rc used to retry the job start.
+func (c *Client) triggerAll(ctx context.Context, endpoint string, rc retry.Config) TriggerResult {
+	if !c.jobRunning.CompareAndSwap(false, true) {
+		c.log.Warn().
+			Str("endpoint", endpoint).
+			Msg("pipeline job already running, skipping trigger")
+		return TriggerResult{Skipped: true, Error: ErrAlreadyRunning}
+	}
+	return c.runLocked(ctx, endpoint, rc)
+}
+
+// runLocked runs a job with the run lock already taken, and releases it when
+// the run finishes.
+func (c *Client) runLocked(ctx context.Context, endpoint string, rc retry.Config) (result TriggerResult) {
+	defer c.jobRunning.Store(false)
+
+	runID := runIDFrom(ctx)
+	log := c.log.With().Str("run_id", runID).Logger()
+
+	ctx, span := tracer.Start(ctx, "pipeline.trigger_all", trace.WithAttributes(
+		attribute.String("endpoint", endpoint),
+		attribute.String("run_id", runID),
+	))
+	defer func() { endRunSpan(span, result) }()
+
+	c.progress.start(runID, endpoint)
+	defer func() {
+		result.RunID = runID
+		c.progress.finish(result)
+		c.emitResultRecord(ctx, endpoint, result)
+		if c.logSummary {
+			log.Info().Str("summary", result.Summary()).Msg("run summary")
+		}
+		if c.notifier != nil && !result.Success {
+			c.notifier.Notify(c.failureFor(endpoint, result))
+		}
+	}()
+
+	if c.dryRun {
+		return c.dryRunResult(endpoint, rc, true, log)
+	}
+
+	if c.canary != nil {
+		variant, backend := "primary", c.baseURL
+		if c.canary.pick() {
+			ctx = withCanary(ctx)
+			c.progress.setCanary()
+			variant, backend = "canary", c.canary.baseURL
+		}
+		span.SetAttributes(attribute.String("backend.variant", variant))
+		log.Info().
+			Str("variant", variant).
+			Str("backend", backend).
+			Msg("backend variant selected")
+	}
+
 	startTime := time.Now()
 
+	if err := c.waitForDependency(ctx, log); err != nil {
+		return TriggerResult{Duration: time.Since(startTime), Error: err}
+	}
+
 	// Step 1: Start the job
-	jobID, attempts, err := c.startJob(ctx, endpoint)
+	jobID, attempts, err := c.startJob(ctx, endpoint, rc, log)
 	if err != nil {
 		return TriggerResult{
 			Attempts: attempts,
@@ -306,15 +624,16 @@ func (c *Client) TriggerAll(ctx context.Context, endpoint string) TriggerResult
 		}
 	}
 
-	c.log.Info().
+	c.progress.setJobID(jobID)
+	log.Info().
 		Str("job_id", jobID).
 		Int("attempts", attempts).
 		Msg("pipeline job started, polling for completion")
 
 	// Step 2: Poll for completion
-	jobStatus, err := c.pollJobCompletion(ctx, jobID)
+	jobStatus, err := c.pollJobCompletion(ctx, jobID, log)
 
-	result := TriggerResult{
+	result = TriggerResult{
 		JobID:      jobID,
 		Attempts:   attempts,
 		Duration:   time.Since(startTime),
@@ -323,30 +642,55 @@ func (c *Client) TriggerAll(ctx context.Context, endpoint string) TriggerResult
 
 	if err != nil {
 		result.Error = err
-		c.log.Error().
+		log.Error().
 			Err(err).
 			Str("job_id", jobID).
 			Dur("duration", result.Duration).
+			Float64("duration_seconds", result.Duration.Seconds()).
 			Msg("pipeline job polling failed")
 		return result
 	}
 
 	if jobStatus != nil {
-		result.Success = jobStatus.Status == "completed" && jobStatus.PipelinesFailed == 0
+		failed, degraded, ok := c.statusPolicy.evaluate(jobStatus)
+		result.Success = jobStatus.Status == "completed" && ok
+		result.Degraded = result.Success && len(degraded) > 0
 
-		if result.Success {
-			c.log.Info().
+		if jobStatus.Status == "cancelled" {
+			result.Error = ErrJobCancelled
+			log.Warn().
+				Str("job_id", jobID).
+				Int("pipelines_completed", jobStatus.PipelinesCompleted).
+				Int("pipelines_total", jobStatus.PipelinesTotal).
+				Dur("total_duration", result.Duration).
+				Msg("pipeline job cancelled")
+		} else if result.Degraded {
+			log.Warn().
 				Str("job_id", jobID).
 				Int("pipelines_completed", jobStatus.PipelinesCompleted).
-				Float64("job_duration_seconds", jobStatus.DurationSeconds).
+				Strs("degraded_pipelines", degraded).
+				Float64("job_duration_seconds", float64(jobStatus.DurationSeconds)).
+				Func(SlowestFields(jobStatus)).
 				Dur("total_duration", result.Duration).
+				Float64("duration_seconds", result.Duration.Seconds()).
+				Msg("pipeline job completed with degraded pipelines")
+		} else if result.Success {
+			log.Info().
+				Str("job_id", jobID).
+				Int("pipelines_completed", jobStatus.PipelinesCompleted).
+				Float64("job_duration_seconds", float64(jobStatus.DurationSeconds)).
+				Func(SlowestFields(jobStatus)).
+				Dur("total_duration", result.Duration).
+				Float64("duration_seconds", result.Duration.Seconds()).
 				Msg("all pipelines completed successfully")
 		} else {
-			c.log.Error().
+			log.Error().
 				Str("job_id", jobID).
 				Str("job_status", jobStatus.Status).
 				Int("pipelines_failed", jobStatus.PipelinesFailed).
 				Int("pipelines_completed", jobStatus.PipelinesCompleted).
+				Strs("failed_pipelines", failed).
+				Func(SlowestFields(jobStatus)).
 				Str("error", jobStatus.Error).
 				Msg("pipeline job failed")
 		}
@@ -355,143 +699,537 @@ func (c *Client) TriggerAll(ctx context.Context, endpoint string) TriggerResult
 	return result
 }
 
-// startJob initiates a new pipeline job and returns the job ID.
-func (c *Client) startJob(ctx context.Context, endpoint string) (string, int, error) {
-	url := c.baseURL + endpoint
+// SlowestFields adds the slowest pipeline of a finished job to a log line
+// (slowest_pipeline, slowest_pipeline_seconds), when any pipeline reported
+// a duration. Use it with zerolog's Event.Func.
+func SlowestFields(js *JobStatus) func(*zerolog.Event) {
+	return func(e *zerolog.Event) {
+		if name, secs, ok := js.Slowest(); ok {
+			e.Str("slowest_pipeline", name).Float64("slowest_pipeline_seconds", secs)
+		}
+	}
+}
+
+// pipelineNameRe is the allowlist for TriggerOne names, so a name can never
+// change the path of the request it is inserted into.
+var pipelineNameRe = regexp.MustCompile(`^[a-z0-9_-]+$`)
 
-	c.log.Info().
+// ValidPipelineName reports whether name may be passed to TriggerOne.
+func ValidPipelineName(name string) bool {
+	return pipelineNameRe.MatchString(name)
+}
+
+// TriggerOne starts a single named pipeline via /v1/internal/pipelines/{name}
+// and polls the job until completion, like TriggerAll.
+func (c *Client) TriggerOne(ctx context.Context, name string) TriggerResult {
+	return c.TriggerOneWithRetry(ctx, name, c.retryCfg)
+}
+
+// TriggerOneWithRetry is TriggerOne with rc, instead of the client's retry
+// config, used to start the job. Use RetryConfig as the base for overrides.
+func (c *Client) TriggerOneWithRetry(ctx context.Context, name string, rc retry.Config) TriggerResult {
+	if !ValidPipelineName(name) {
+		return TriggerResult{Error: fmt.Errorf("invalid pipeline name %q: must match %s", name, pipelineNameRe)}
+	}
+	return c.triggerAll(ctx, "/v1/internal/pipelines/"+name, rc)
+}
+
+// RetryConfig returns the client's default retry configuration.
+func (c *Client) RetryConfig() retry.Config {
+	return c.retryCfg
+}
+
+// Busy reports whether a TriggerAll or TriggerOne is currently in progress.
+func (c *Client) Busy() bool {
+	return c.jobRunning.Load()
+}
+
+// Reservation is the run lock, taken ahead of a run by Reserve. Exactly one
+// of Run or Release must be called on it.
+type Reservation struct {
+	c    *Client
+	done atomic.Bool
+}
+
+// Reserve takes the run lock without starting a run, so a caller can reject
+// an overlapping request before it replies. It returns false while another
+// run holds the lock.
+func (c *Client) Reserve() (*Reservation, bool) {
+	if !c.jobRunning.CompareAndSwap(false, true) {
+		return nil, false
+	}
+	return &Reservation{c: c}, true
+}
+
+// TriggerOne is Client.TriggerOneWithRetry under the reserved lock, which it
+// releases when the run finishes.
+func (r *Reservation) TriggerOne(ctx context.Context, name string, rc retry.Config) TriggerResult {
+	if !r.done.CompareAndSwap(false, true) {
+		return TriggerResult{Skipped: true, Error: ErrAlreadyRunning}
+	}
+	if !ValidPipelineName(name) {
+		r.c.jobRunning.Store(false)
+		return TriggerResult{Error: fmt.Errorf("invalid pipeline name %q: must match %s", name, pipelineNameRe)}
+	}
+	return r.c.runLocked(ctx, "/v1/internal/pipelines/"+name, rc)
+}
+
+// Release drops the lock without running. It is a no-op once the
+// reservation was used or released.
+func (r *Reservation) Release() {
+	if r.done.CompareAndSwap(false, true) {
+		r.c.jobRunning.Store(false)
+	}
+}
+
+// failureFor builds the failure webhook payload for a finished run.
+func (c *Client) failureFor(endpoint string, result TriggerResult) notify.Failure {
+	f := notify.Failure{
+		JobID:      result.JobID,
+		Endpoint:   endpoint,
+		Attempts:   result.Attempts,
+		DurationMs: result.Duration.Milliseconds(),
+		StatusCode: result.StatusCode,
+		Metadata:   c.metadata,
+	}
+	if result.Error != nil {
+		f.Error = result.Error.Error()
+	}
+	if result.JobDetails != nil {
+		f.FailedPipelines, _, _ = c.statusPolicy.evaluate(result.JobDetails)
+		if f.Error == "" {
+			f.Error = result.JobDetails.Error
+		}
+	}
+	return f
+}
+
+// WaitNotifications waits, until ctx expires, for pending failure
+// notifications to be sent. Call before exiting.
+func (c *Client) WaitNotifications(ctx context.Context) {
+	if c.notifier != nil {
+		c.notifier.Wait(ctx)
+	}
+}
+
+// startJob initiates a new pipeline job, retrying per rc, and returns the job ID.
+func (c *Client) startJob(ctx context.Context, endpoint string, rc retry.Config, log zerolog.Logger) (string, int, error) {
+	ctx, span := tracer.Start(ctx, "pipeline.start_job", trace.WithAttributes(attribute.String("endpoint", endpoint)))
+	jobID, attempts, err := c.sendStart(ctx, endpoint, rc, log)
+	span.SetAttributes(attribute.String("job.id", jobID), attribute.Int("attempts", attempts))
+	endSpan(span, err)
+	return jobID, attempts, err
+}
+
+// sendStart sends the job start request and parses the job ID from the
+// response.
+func (c *Client) sendStart(ctx context.Context, endpoint string, rc retry.Config, log zerolog.Logger) (string, int, error) {
+	url := c.runEndpointURL(ctx, endpoint)
+
+	log.Info().
 		Str("url", url).
 		Msg("starting pipeline job")
 
+	var timing *requestTiming
+	if c.httpTimingDebug {
+		ctx, timing = withRequestTiming(ctx)
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
 	if err != nil {
 		return "", 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.authToken)
+	c.setAuth(req)
+	markCanary(ctx, req)
+	if c.idempotencyHeader != "" {
+		// Set once: every retry below resends this same request.
+		key := uuid.NewString()
+		req.Header.Set(c.idempotencyHeader, key)
+		log.Debug().Str("idempotency_key", key).Msg("start request idempotency key")
+	}
 
-	result := retry.Do(ctx, c.httpClient, req, c.retryCfg, c.log)
+	// The body is read after retry.Do returns, so a keep-alive connection
+	// dropped mid-body surfaces here rather than inside the retry loop.
+	// Treat a truncated body as retryable within the same attempt budget:
+	// budget holds the retries left once the attempts so far are spent.
+	// Under RETRY_IDEMPOTENT_ONLY a start without an idempotency key is
+	// never resent, since the backend may already have created the job.
+	var (
+		body     []byte
+		status   int
+		attempts int
+		backoff  time.Duration
+	)
+	budget := rc
+	for {
+		result := c.doStart(ctx, req, budget, log)
+		attempts += result.Attempts
+		if timing != nil {
+			timing.log(log, "start request timing")
+		}
 
-	if result.FinalError != nil {
-		return "", result.Attempts, fmt.Errorf("failed to start job: %w", result.FinalError)
-	}
+		if result.FinalError != nil {
+			return "", attempts, fmt.Errorf("failed to start job: %w", result.FinalError)
+		}
+		if result.Response == nil {
+			return "", attempts, fmt.Errorf("no response received")
+		}
+
+		status = result.Response.StatusCode
+		body, err = io.ReadAll(result.Response.Body)
+		result.Response.Body.Close()
+		if err == nil {
+			break
+		}
+		budget.MaxRetries -= result.Attempts
+		if !isTruncatedBody(err) || budget.MaxRetries < 0 || !rc.CanRepeat(req) {
+			return "", attempts, fmt.Errorf("failed to read response: %w", err)
+		}
 
-	if result.Response == nil {
-		return "", result.Attempts, fmt.Errorf("no response received")
+		backoff = retry.CalculateBackoff(rc, attempts-1, backoff, nil)
+		log.Warn().
+			Err(err).
+			Int("attempt", attempts).
+			Dur("backoff", backoff).
+			Msg("start response truncated, retrying")
+
+		select {
+		case <-ctx.Done():
+			return "", attempts, ctx.Err()
+		case <-time.After(backoff):
+		}
 	}
-	defer result.Response.Body.Close()
 
-	body, err := io.ReadAll(result.Response.Body)
-	if err != nil {
-		return "", result.Attempts, fmt.Errorf("failed to read response: %w", err)
+	if status == http.StatusConflict && c.treat409AsRunning {
+		jobID, err := c.existingJobID(body)
+		if err != nil {
+			return "", attempts, err
+		}
+		log.Info().
+			Str("job_id", jobID).
+			Msg("job already running, attaching to existing job")
+		return jobID, attempts, nil
 	}
 
-	if result.Response.StatusCode < 200 || result.Response.StatusCode >= 300 {
-		return "", result.Attempts, fmt.Errorf("unexpected status %d: %s", result.Response.StatusCode, string(body))
+	if status < 200 || status >= 300 {
+		return "", attempts, fmt.Errorf("unexpected status %d: %s", status, string(body))
 	}
 
 	var resp jobCreatedResponse
 	if err := json.Unmarshal(body, &resp); err != nil {
-		return "", result.Attempts, fmt.Errorf("failed to parse response: %w", err)
+		return "", attempts, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if resp.Data.JobID == "" {
-		return "", result.Attempts, fmt.Errorf("no job ID in response")
+		return "", attempts, fmt.Errorf("no job ID in response")
+	}
+	if err := c.validateJobID(resp.Data.JobID); err != nil {
+		return "", attempts, err
+	}
+
+	return resp.Data.JobID, attempts, nil
+}
+
+// existingJobID extracts the in-progress job's ID from a 409 start response.
+func (c *Client) existingJobID(body []byte) (string, error) {
+	var resp jobCreatedResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse 409 response: %w", err)
+	}
+	if resp.Data.JobID == "" {
+		return "", fmt.Errorf("job already running but 409 response has no job ID: %s", string(body))
+	}
+	if err := c.validateJobID(resp.Data.JobID); err != nil {
+		return "", err
+	}
+	return resp.Data.JobID, nil
+}
+
+// doStart sends the start request through the retry loop.
+func (c *Client) doStart(ctx context.Context, req *http.Request, rc retry.Config, log zerolog.Logger) retry.Result {
+	result := c.do(ctx, req, rc, log)
+
+	// Startup grace: right after a token rotation the sidecar may not have
+	// finished writing the token file, so the very first start can be rejected.
+	// Re-read the token once and retry immediately before giving up.
+	firstStart := !c.startAttempted.Swap(true)
+	if firstStart && c.tokenFile != "" && result.FinalError == nil &&
+		result.Response != nil && isAuthFailure(result.Response.StatusCode) {
+		result.Response.Body.Close()
+		log.Warn().
+			Int("status_code", result.Response.StatusCode).
+			Msg("first start rejected, re-reading token file")
+
+		if err := c.reloadToken(); err != nil {
+			return retry.Result{Attempts: result.Attempts, TotalTime: result.TotalTime, FinalError: err}
+		}
+		c.setAuth(req)
+
+		priorAttempts := result.Attempts
+		result = c.do(ctx, req, rc, log)
+		result.Attempts += priorAttempts
 	}
 
-	return resp.Data.JobID, result.Attempts, nil
+	return result
+}
+
+// isTruncatedBody reports whether a body read failed because the connection
+// closed before the full body arrived.
+func isTruncatedBody(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
 }
 
 // pollJobCompletion polls the job status endpoint until the job completes or times out.
-func (c *Client) pollJobCompletion(ctx context.Context, jobID string) (*JobStatus, error) {
-	url := c.baseURL + "/v1/internal/pipelines/jobs/" + jobID
+func (c *Client) pollJobCompletion(ctx context.Context, jobID string, log zerolog.Logger) (*JobStatus, error) {
+	mode := c.pollCfg.Mode
+	if mode == "" {
+		mode = PollModeShort
+	}
+	ctx, span := tracer.Start(ctx, "pipeline.poll", trace.WithAttributes(
+		attribute.String("job.id", jobID),
+		attribute.String("poll.mode", mode),
+	))
+
+	var status *JobStatus
+	var err error
+	if mode == PollModeLongPoll {
+		status, err = c.longPollJobCompletion(ctx, jobID, log)
+	} else {
+		status, err = c.shortPollJobCompletion(ctx, jobID, log)
+	}
+	if status != nil {
+		span.SetAttributes(attribute.String("job.status", status.Status))
+	}
+	endSpan(span, err)
+	return status, err
+}
+
+// shortPollJobCompletion polls the job status endpoint at growing intervals.
+func (c *Client) shortPollJobCompletion(ctx context.Context, jobID string, log zerolog.Logger) (*JobStatus, error) {
+	url := c.runEndpointURL(ctx, "/v1/internal/pipelines/jobs/"+neturl.PathEscape(jobID))
 
 	interval := c.pollCfg.InitialInterval
 	deadline := time.Now().Add(c.pollCfg.MaxWaitTime)
+	lastPipeline := ""
+	lastCompleted := -1
+	lastProgress := time.Now()
+	var lastStatusLog time.Time
+
+	// Status polls get their own, usually shorter, timeout so a hung poll
+	// fails fast; the shared client's RequestTimeout is sized for job starts.
+	hc := c.httpClient
+	if c.pollCfg.RequestTimeout > 0 {
+		hc = &http.Client{Transport: c.httpClient.Transport, Jar: c.httpClient.Jar}
+	}
 
 	for {
+		// Check context cancellation
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		// Check if we've exceeded the deadline
 		if time.Now().After(deadline) {
 			return nil, fmt.Errorf("polling timeout after %v", c.pollCfg.MaxWaitTime)
 		}
 
-		// Check context cancellation
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-		}
-
 		// Fetch job status
-		status, err := c.fetchJobStatus(ctx, url)
+		status, retryAfter, err := c.fetchJobStatusWithTimeout(ctx, hc, url)
+		if c.isPermanentPollError(err) {
+			return nil, fmt.Errorf("failed to fetch job status: %w", err)
+		}
 		if err != nil {
-			c.log.Warn().
+			log.Warn().
 				Err(err).
 				Str("job_id", jobID).
 				Msg("failed to fetch job status, will retry")
 		} else {
-			c.log.Debug().
-				Str("job_id", jobID).
-				Str("status", status.Status).
-				Int("completed", status.PipelinesCompleted).
-				Int("total", status.PipelinesTotal).
-				Str("current", status.CurrentPipeline).
-				Msg("job status update")
+			c.progress.update(status)
+			remaining := time.Until(deadline)
+			if c.pollCfg.LogInterval <= 0 || time.Since(lastStatusLog) >= c.pollCfg.LogInterval {
+				lastStatusLog = time.Now()
+				log.Debug().
+					Str("job_id", jobID).
+					Str("status", status.Status).
+					Int("completed", status.PipelinesCompleted).
+					Int("total", status.PipelinesTotal).
+					Str("current", status.CurrentPipeline).
+					Time("deadline", deadline).
+					Dur("remaining", remaining).
+					Msg("job status update")
+			}
+
+			if status.CurrentPipeline != lastPipeline {
+				if status.CurrentPipeline != "" {
+					log.Info().
+						Str("job_id", jobID).
+						Str("pipeline", status.CurrentPipeline).
+						Str("previous", lastPipeline).
+						Msg("pipeline started")
+				}
+				lastPipeline = status.CurrentPipeline
+			}
 
 			// Check if job is done
-			if status.Status == "completed" || status.Status == "failed" {
+			if isTerminalStatus(status.Status) {
 				return status, nil
 			}
+
+			if status.PipelinesCompleted > lastCompleted {
+				lastCompleted = status.PipelinesCompleted
+				lastProgress = time.Now()
+			} else if c.pollCfg.ProgressTimeout > 0 && time.Since(lastProgress) > c.pollCfg.ProgressTimeout {
+				return nil, fmt.Errorf("no progress: pipelines_completed stuck at %d/%d for %v",
+					status.PipelinesCompleted, status.PipelinesTotal, time.Since(lastProgress).Round(time.Millisecond))
+			}
+
+			if remaining < interval {
+				log.Warn().
+					Str("job_id", jobID).
+					Time("deadline", deadline).
+					Dur("remaining", remaining).
+					Msg("poll deadline approaching")
+			}
 		}
 
-		// Wait before next poll
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-time.After(interval):
+		// Wait before next poll, preferring the backend's Retry-After hint.
+		// Never sleep past the deadline; this is the only place the loop waits.
+		wait := c.pollCfg.jittered(interval)
+		if retryAfter > 0 {
+			wait = min(retryAfter, c.pollCfg.MaxInterval)
+		}
+		wait = min(wait, time.Until(deadline))
+		if err := sleepCtx(ctx, wait); err != nil {
+			return nil, err
 		}
 
 		// Increase interval with backoff (cap at max)
-		interval = time.Duration(float64(interval) * 1.5)
-		if interval > c.pollCfg.MaxInterval {
-			interval = c.pollCfg.MaxInterval
+		interval = c.pollCfg.next(interval)
+	}
+}
+
+// defaultPollBackoffFactor is how much the short-poll interval grows after
+// each poll when POLL_BACKOFF_FACTOR is unset.
+const defaultPollBackoffFactor = 1.5
+
+// next returns the poll interval after interval: grown by BackoffFactor and
+// capped at MaxInterval.
+func (p PollConfig) next(interval time.Duration) time.Duration {
+	factor := p.BackoffFactor
+	if factor < 1 {
+		factor = defaultPollBackoffFactor
+	}
+	return min(time.Duration(float64(interval)*factor), p.MaxInterval)
+}
+
+// jittered shortens interval by a random fraction of up to Jitter, so
+// runners polling the same job drift apart. It never goes below
+// InitialInterval, and the interval growth itself stays deterministic.
+func (p PollConfig) jittered(interval time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return interval
+	}
+	d := interval - time.Duration(float64(interval)*min(p.Jitter, 1)*rand.Float64())
+	return max(d, min(p.InitialInterval, interval))
+}
+
+// fetchJobStatusWithTimeout is fetchJobStatus bounded by PollConfig's
+// RequestTimeout. The request context is derived from ctx, so cancelling
+// the run still aborts the request.
+func (c *Client) fetchJobStatusWithTimeout(ctx context.Context, hc *http.Client, url string) (*JobStatus, time.Duration, error) {
+	if c.pollCfg.RequestTimeout <= 0 {
+		return c.fetchJobStatus(ctx, hc, url)
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, c.pollCfg.RequestTimeout)
+	defer cancel()
+	return c.fetchJobStatus(reqCtx, hc, url)
+}
+
+// isTerminalStatus reports whether a job status means the job is done.
+func isTerminalStatus(status string) bool {
+	return status == "completed" || status == "failed" || status == "cancelled"
+}
+
+// statusError is an unexpected non-2xx job status response.
+type statusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+// isPermanentPollError reports whether a job status fetch error won't heal
+// by polling again: a status the retry config doesn't consider retryable
+// (by default any 4xx other than 404 and 429). Network errors are retried.
+func (c *Client) isPermanentPollError(err error) bool {
+	var se *statusError
+	if !errors.As(err, &se) {
+		return false
+	}
+	return !c.retryCfg.Retryable(&http.Response{StatusCode: se.StatusCode}, nil)
+}
+
+// sleepCtx waits for d, returning ctx's error early if it is cancelled.
+// Cancellation is checked before and after the wait, so a cancelled context
+// never sleeps and a cancellation racing the timer is still reported.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+		case <-t.C:
 		}
 	}
+	return ctx.Err()
 }
 
-// fetchJobStatus fetches the current status of a job.
-func (c *Client) fetchJobStatus(ctx context.Context, url string) (*JobStatus, error) {
+// fetchJobStatus fetches the current status of a job using hc. retryAfter is
+// the delay suggested by the response's Retry-After header, or 0 if absent.
+func (c *Client) fetchJobStatus(ctx context.Context, hc *http.Client, url string) (status *JobStatus, retryAfter time.Duration, err error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.authToken)
+	c.setAuth(req)
+	markCanary(ctx, req)
+	injectTraceContext(ctx, req)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := hc.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, 0, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if d, ok := retry.ParseRetryAfter(resp.Header.Get("Retry-After")); ok && d > 0 {
+		retryAfter = d
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, retryAfter, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode == 404 {
-		return nil, fmt.Errorf("job not found")
+		return nil, retryAfter, fmt.Errorf("job not found")
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		return nil, retryAfter, &statusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var statusResp jobStatusResponse
 	if err := json.Unmarshal(body, &statusResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, retryAfter, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	return &statusResp.Data, nil
+	return &statusResp.Data, retryAfter, nil
 }
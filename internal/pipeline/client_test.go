@@ -1,14 +1,22 @@
 package pipeline
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"cron-runner/internal/config"
+	"cron-runner/internal/notify"
 
 	"github.com/rs/zerolog"
 )
@@ -35,7 +43,10 @@ func newTestClient(baseURL string, transport http.RoundTripper) *Client {
 		LogJSON:             true,
 	}
 
-	client := NewClient(cfg, zerolog.New(io.Discard))
+	client, err := NewClient(cfg, zerolog.New(io.Discard))
+	if err != nil {
+		panic(err)
+	}
 	client.httpClient.Transport = transport
 	return client
 }
@@ -117,3 +128,1009 @@ func TestTriggerEndpointFailure404(t *testing.T) {
 		t.Fatalf("expected response body %q, got %q", "missing", result.ResponseBody)
 	}
 }
+
+func TestTriggerEndpointLogsDurationSeconds(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		time.Sleep(5 * time.Millisecond)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("ok")),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	var buf bytes.Buffer
+	client := newTestClient("http://example.test", transport)
+	client.log = zerolog.New(&buf)
+
+	result := client.TriggerEndpoint(context.Background(), "/test")
+	if !result.Success {
+		t.Fatalf("expected success true, got false with error: %v", result.Error)
+	}
+
+	var found bool
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		if entry["message"] != "endpoint triggered successfully" {
+			continue
+		}
+		found = true
+		secs, ok := entry["duration_seconds"].(float64)
+		if !ok {
+			t.Fatalf("expected numeric duration_seconds, got %v", entry["duration_seconds"])
+		}
+		if secs != result.Duration.Seconds() {
+			t.Fatalf("expected duration_seconds %v, got %v", result.Duration.Seconds(), secs)
+		}
+	}
+	if !found {
+		t.Fatalf("expected completion log line")
+	}
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Header:     make(http.Header),
+	}
+}
+
+func TestTriggerAllRereadsTokenOnFirstAuthFailure(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("stale"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var starts int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Authorization") != "Bearer fresh" {
+			// Simulate the sidecar finishing its write after the first request.
+			os.WriteFile(tokenFile, []byte("fresh\n"), 0o600)
+			return jsonResponse(http.StatusUnauthorized, "unauthorized"), nil
+		}
+		if req.Method == http.MethodPost {
+			starts++
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"completed"}}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.authToken = "stale"
+	client.tokenFile = tokenFile
+
+	result := client.TriggerAll(context.Background(), "/test")
+	if !result.Success {
+		t.Fatalf("expected success true, got false with error: %v", result.Error)
+	}
+	if starts != 1 {
+		t.Fatalf("expected 1 accepted start, got %d", starts)
+	}
+	if result.Attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", result.Attempts)
+	}
+}
+
+func TestTriggerAllRetriesTruncatedStartBody(t *testing.T) {
+	var starts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			starts++
+			if starts == 1 {
+				// Promise a longer body than we send, then drop the connection.
+				conn, buf, err := w.(http.Hijacker).Hijack()
+				if err != nil {
+					t.Errorf("hijack failed: %v", err)
+					return
+				}
+				buf.WriteString("HTTP/1.1 202 Accepted\r\nContent-Length: 100\r\n\r\n{\"data\":")
+				buf.Flush()
+				conn.Close()
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed"}}`)
+	}))
+	defer srv.Close()
+
+	client := newTestClient(srv.URL, http.DefaultTransport)
+	client.retryCfg.MaxRetries = 1
+
+	result := client.TriggerAll(context.Background(), "/test")
+	if !result.Success {
+		t.Fatalf("expected success true, got false with error: %v", result.Error)
+	}
+	if starts != 2 {
+		t.Fatalf("expected 2 start requests, got %d", starts)
+	}
+	if result.JobID != "job-1" {
+		t.Fatalf("expected job ID %q, got %q", "job-1", result.JobID)
+	}
+}
+
+// truncatedBody fails its read like a connection dropped mid-body.
+type truncatedBody struct{}
+
+func (truncatedBody) Read([]byte) (int, error) { return 0, io.ErrUnexpectedEOF }
+func (truncatedBody) Close() error             { return nil }
+
+func TestTruncatedStartBodySharesRetryBudget(t *testing.T) {
+	var starts int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		starts++
+		if starts <= 2 {
+			return jsonResponse(http.StatusServiceUnavailable, `{}`), nil
+		}
+		return &http.Response{StatusCode: http.StatusAccepted, Header: make(http.Header), Body: truncatedBody{}}, nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.retryCfg.MaxRetries = 2
+
+	result := client.TriggerAll(context.Background(), "/test")
+	if result.Success || !strings.Contains(result.Error.Error(), "failed to read response") {
+		t.Fatalf("expected the truncated body to fail the start, got %+v", result)
+	}
+	if starts != 3 || result.Attempts != 3 {
+		t.Fatalf("expected 3 start attempts in total (MAX_RETRIES=2), got %d requests, %d attempts", starts, result.Attempts)
+	}
+}
+
+func TestTruncatedStartBodyNotResentWhenNotIdempotent(t *testing.T) {
+	var starts int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		starts++
+		return &http.Response{StatusCode: http.StatusAccepted, Header: make(http.Header), Body: truncatedBody{}}, nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.retryCfg.MaxRetries = 2
+	client.retryCfg.IdempotentOnly = true
+	client.idempotencyHeader = "" // SEND_IDEMPOTENCY_KEY=false
+
+	result := client.TriggerAll(context.Background(), "/test")
+	if result.Success || !strings.Contains(result.Error.Error(), "failed to read response") {
+		t.Fatalf("expected the truncated body to fail the start, got %+v", result)
+	}
+	if starts != 1 {
+		t.Fatalf("expected the start POST to be sent once, got %d", starts)
+	}
+}
+
+func TestPollLogsDecreasingRemaining(t *testing.T) {
+	var polls int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		polls++
+		status := "running"
+		if polls == 3 {
+			status = "completed"
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"`+status+`"}}`), nil
+	})
+
+	var buf bytes.Buffer
+	client := newTestClient("http://example.test", transport)
+	client.log = zerolog.New(&buf).Level(zerolog.DebugLevel)
+
+	if result := client.TriggerAll(context.Background(), "/test"); !result.Success {
+		t.Fatalf("expected success true, got false with error: %v", result.Error)
+	}
+
+	var remaining []float64
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		if entry["message"] != "job status update" {
+			continue
+		}
+		if _, ok := entry["deadline"].(string); !ok {
+			t.Fatalf("expected deadline field, got %v", entry["deadline"])
+		}
+		r, ok := entry["remaining"].(float64)
+		if !ok {
+			t.Fatalf("expected numeric remaining field, got %v", entry["remaining"])
+		}
+		remaining = append(remaining, r)
+	}
+
+	if len(remaining) != 3 {
+		t.Fatalf("expected 3 status logs, got %d", len(remaining))
+	}
+	for i := 1; i < len(remaining); i++ {
+		if remaining[i] >= remaining[i-1] {
+			t.Fatalf("expected remaining to decrease, got %v", remaining)
+		}
+	}
+}
+
+func TestPollLogsCurrentPipelineTransitions(t *testing.T) {
+	sequence := []string{"", "foo", "foo", "bar", "bar", "bar", "baz"}
+	var polls int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		current := sequence[polls]
+		polls++
+		status := "running"
+		if polls == len(sequence) {
+			status = "completed"
+		}
+		return jsonResponse(http.StatusOK,
+			`{"data":{"job_id":"job-1","status":"`+status+`","current_pipeline":"`+current+`"}}`), nil
+	})
+
+	var buf bytes.Buffer
+	client := newTestClient("http://example.test", transport)
+	client.log = zerolog.New(&buf)
+
+	if result := client.TriggerAll(context.Background(), "/test"); !result.Success {
+		t.Fatalf("expected success true, got false with error: %v", result.Error)
+	}
+
+	var started []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		if p, ok := entry["pipeline"].(string); ok {
+			started = append(started, p)
+		}
+	}
+
+	want := []string{"foo", "bar", "baz"}
+	if strings.Join(started, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected transitions %v, got %v", want, started)
+	}
+}
+
+func TestJobStatusDurationSecondsNumericAndString(t *testing.T) {
+	cases := map[string]string{
+		"numeric": `{"job_id":"job-1","duration_seconds":12.5,"results":{"a":{"duration_seconds":1.25}}}`,
+		"string":  `{"job_id":"job-1","duration_seconds":"12.5","results":{"a":{"duration_seconds":"1.25"}}}`,
+	}
+
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			var status JobStatus
+			if err := json.Unmarshal([]byte(body), &status); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if status.DurationSeconds != 12.5 {
+				t.Fatalf("expected duration 12.5, got %v", status.DurationSeconds)
+			}
+			if status.Results["a"].DurationSeconds != 1.25 {
+				t.Fatalf("expected pipeline duration 1.25, got %v", status.Results["a"].DurationSeconds)
+			}
+		})
+	}
+}
+
+func TestJobStatusDurationSecondsInvalidString(t *testing.T) {
+	var status JobStatus
+	if err := json.Unmarshal([]byte(`{"duration_seconds":"soon"}`), &status); err == nil {
+		t.Fatalf("expected error for non-numeric duration string")
+	}
+}
+
+func TestTriggerAllJobIDValidation(t *testing.T) {
+	cases := []struct {
+		name     string
+		jobID    string
+		wantPath string // escaped poll path; empty means the run must fail before polling
+	}{
+		{name: "valid", jobID: "job-1", wantPath: "/v1/internal/pipelines/jobs/job-1"},
+		{name: "slash", jobID: "job/../admin"},
+		{name: "dot segment", jobID: ".."},
+		{name: "escaped", jobID: "job 1?x=#", wantPath: "/v1/internal/pipelines/jobs/job%201%3Fx=%23"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var polledPath string
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if req.Method == http.MethodPost {
+					body, _ := json.Marshal(map[string]any{"data": map[string]string{"job_id": tc.jobID}})
+					return jsonResponse(http.StatusAccepted, string(body)), nil
+				}
+				polledPath = req.URL.EscapedPath()
+				return jsonResponse(http.StatusOK, `{"data":{"status":"completed"}}`), nil
+			})
+
+			client := newTestClient("http://example.test", transport)
+			result := client.TriggerAll(context.Background(), "/test")
+
+			if tc.wantPath == "" {
+				if result.Success || result.Error == nil {
+					t.Fatalf("expected invalid job ID to fail the run")
+				}
+				if !strings.Contains(result.Error.Error(), "invalid job ID") {
+					t.Fatalf("expected invalid job ID error, got: %v", result.Error)
+				}
+				if polledPath != "" {
+					t.Fatalf("expected no poll request, got %q", polledPath)
+				}
+				return
+			}
+			if !result.Success {
+				t.Fatalf("expected success true, got false with error: %v", result.Error)
+			}
+			if polledPath != tc.wantPath {
+				t.Fatalf("expected poll path %q, got %q", tc.wantPath, polledPath)
+			}
+		})
+	}
+}
+
+func TestTriggerAllTreats409AsRunning(t *testing.T) {
+	var polledPath string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusConflict,
+				`{"status":"error","message":"job already running","data":{"job_id":"existing-7"}}`), nil
+		}
+		polledPath = req.URL.Path
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"existing-7","status":"completed"}}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.treat409AsRunning = true
+
+	result := client.TriggerAll(context.Background(), "/test")
+	if !result.Success {
+		t.Fatalf("expected success true, got false with error: %v", result.Error)
+	}
+	if result.JobID != "existing-7" {
+		t.Fatalf("expected job ID %q, got %q", "existing-7", result.JobID)
+	}
+	if polledPath != "/v1/internal/pipelines/jobs/existing-7" {
+		t.Fatalf("expected to poll the existing job, got %q", polledPath)
+	}
+}
+
+func TestTriggerAll409FailsWhenOptionDisabled(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusConflict, `{"data":{"job_id":"existing-7"}}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	result := client.TriggerAll(context.Background(), "/test")
+	if result.Success {
+		t.Fatalf("expected success false, got true")
+	}
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "409") {
+		t.Fatalf("expected 409 error, got: %v", result.Error)
+	}
+}
+
+func TestEndpointURLJoining(t *testing.T) {
+	cases := []struct {
+		baseURL  string
+		basePath string
+		want     string
+	}{
+		{"https://api.example.test", "", "https://api.example.test/v1/internal/pipelines/all"},
+		{"https://api.example.test/", "", "https://api.example.test/v1/internal/pipelines/all"},
+		{"https://api.example.test/data", "", "https://api.example.test/data/v1/internal/pipelines/all"},
+		{"https://api.example.test/data/", "/api/", "https://api.example.test/data/api/v1/internal/pipelines/all"},
+		{"https://api.example.test", "api", "https://api.example.test/api/v1/internal/pipelines/all"},
+	}
+
+	for _, tc := range cases {
+		client := &Client{baseURL: tc.baseURL, basePath: tc.basePath}
+		if got := client.endpointURL("/v1/internal/pipelines/all"); got != tc.want {
+			t.Errorf("endpointURL(%q, %q) = %q, want %q", tc.baseURL, tc.basePath, got, tc.want)
+		}
+	}
+}
+
+func TestPollFailsWhenProgressStalls(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		return jsonResponse(http.StatusOK,
+			`{"data":{"job_id":"job-1","status":"running","pipelines_total":3,"pipelines_completed":1}}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.pollCfg.ProgressTimeout = 20 * time.Millisecond
+
+	result := client.TriggerAll(context.Background(), "/test")
+	if result.Success {
+		t.Fatalf("expected success false, got true")
+	}
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "no progress") {
+		t.Fatalf("expected no progress error, got: %v", result.Error)
+	}
+	if result.Duration >= client.pollCfg.MaxWaitTime {
+		t.Fatalf("expected to fail before the overall wait time, took %v", result.Duration)
+	}
+}
+
+func TestTriggerAllLongPoll(t *testing.T) {
+	var resultCalls, statusCalls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+		case strings.HasSuffix(r.URL.Path, "/result"):
+			resultCalls++
+			if resultCalls == 1 {
+				// Hold past the per-request timeout so the client re-polls.
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+			io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed"}}`)
+		default:
+			statusCalls++
+			io.WriteString(w, `{"data":{"job_id":"job-1","status":"running"}}`)
+		}
+	}))
+	defer srv.Close()
+
+	client := newTestClient(srv.URL, http.DefaultTransport)
+	client.pollCfg.Mode = PollModeLongPoll
+	client.pollCfg.LongPollTimeout = 100 * time.Millisecond
+
+	result := client.TriggerAll(context.Background(), "/test")
+	if !result.Success {
+		t.Fatalf("expected success true, got false with error: %v", result.Error)
+	}
+	if resultCalls != 2 {
+		t.Fatalf("expected 2 long poll requests, got %d", resultCalls)
+	}
+	if statusCalls != 0 {
+		t.Fatalf("expected no short polls, got %d", statusCalls)
+	}
+}
+
+func TestTriggerAllAppliesPipelineStatusPolicy(t *testing.T) {
+	body := func(statuses map[string]string) string {
+		results := make(map[string]PipelineResult)
+		for name, status := range statuses {
+			results[name] = PipelineResult{PipelineName: name, Status: status}
+		}
+		b, _ := json.Marshal(map[string]any{"data": JobStatus{JobID: "job-1", Status: "completed", Results: results}})
+		return string(b)
+	}
+	policy := StatusPolicy{"skipped": OutcomePass, "warning": OutcomeWarn, "partial": OutcomeFail}
+
+	tests := []struct {
+		name         string
+		statuses     map[string]string
+		wantSuccess  bool
+		wantDegraded bool
+	}{
+		{"all completed", map[string]string{"a": "completed", "b": "completed"}, true, false},
+		{"skipped passes", map[string]string{"a": "completed", "b": "skipped"}, true, false},
+		{"warning degrades", map[string]string{"a": "skipped", "b": "warning"}, true, true},
+		{"partial fails", map[string]string{"a": "warning", "b": "partial"}, false, false},
+		{"failed fails by default", map[string]string{"a": "completed", "b": "failed"}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if req.Method == http.MethodPost {
+					return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+				}
+				return jsonResponse(http.StatusOK, body(tt.statuses)), nil
+			})
+
+			client := newTestClient("http://example.test", transport)
+			client.statusPolicy = policy
+
+			result := client.TriggerAll(context.Background(), "/test")
+			if result.Success != tt.wantSuccess {
+				t.Fatalf("expected success %v, got %v", tt.wantSuccess, result.Success)
+			}
+			if result.Degraded != tt.wantDegraded {
+				t.Fatalf("expected degraded %v, got %v", tt.wantDegraded, result.Degraded)
+			}
+		})
+	}
+}
+
+func TestTriggerAllSkipsWhileAnotherIsRunning(t *testing.T) {
+	release := make(chan struct{})
+	polling := make(chan struct{}, 1)
+	var starts int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			starts++
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		select {
+		case polling <- struct{}{}:
+		default:
+		}
+		<-release
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"failed"}}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+
+	done := make(chan TriggerResult)
+	go func() { done <- client.TriggerAll(context.Background(), "/test") }()
+	<-polling
+
+	second := client.TriggerAll(context.Background(), "/test")
+	if !second.Skipped || !errors.Is(second.Error, ErrAlreadyRunning) {
+		t.Fatalf("expected overlapping trigger to be skipped, got %+v", second)
+	}
+
+	close(release)
+	if first := <-done; first.Success || first.Skipped {
+		t.Fatalf("expected first run to complete as failed, got %+v", first)
+	}
+
+	// The lock is released even though the first run failed.
+	third := client.TriggerAll(context.Background(), "/test")
+	if third.Skipped {
+		t.Fatalf("expected trigger after the first finished to run")
+	}
+	if starts != 2 {
+		t.Fatalf("expected 2 job starts, got %d", starts)
+	}
+}
+
+func TestReserveHoldsRunLockUntilUsedOrReleased(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"completed"}}`), nil
+	})
+	client := newTestClient("http://example.test", transport)
+
+	run, ok := client.Reserve()
+	if !ok {
+		t.Fatalf("expected the first reservation to succeed")
+	}
+	if _, ok := client.Reserve(); ok {
+		t.Fatalf("expected a second reservation to fail while the first is held")
+	}
+	if result := client.TriggerAll(context.Background(), "/test"); !result.Skipped {
+		t.Fatalf("expected TriggerAll to be skipped while reserved, got %+v", result)
+	}
+
+	if result := run.TriggerOne(context.Background(), "pre-game", client.RetryConfig()); !result.Success {
+		t.Fatalf("expected the reserved run to succeed, got %+v", result)
+	}
+	if client.Busy() {
+		t.Fatalf("expected the lock released after the reserved run")
+	}
+
+	run, _ = client.Reserve()
+	run.Release()
+	run.Release()
+	if client.Busy() {
+		t.Fatalf("expected the lock released by Release")
+	}
+}
+
+func TestPollStatusLogThrottledByInterval(t *testing.T) {
+	var polls int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		polls++
+		status := "running"
+		if polls == 5 {
+			status = "completed"
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"`+status+`"}}`), nil
+	})
+
+	var buf bytes.Buffer
+	client := newTestClient("http://example.test", transport)
+	client.log = zerolog.New(&buf).Level(zerolog.DebugLevel)
+	client.pollCfg.LogInterval = time.Hour
+
+	if result := client.TriggerAll(context.Background(), "/test"); !result.Success {
+		t.Fatalf("expected success true, got false with error: %v", result.Error)
+	}
+
+	if n := strings.Count(buf.String(), `"message":"job status update"`); n != 1 {
+		t.Fatalf("expected 1 status log over %d rapid polls, got %d", polls, n)
+	}
+}
+
+func TestPollIntervalGrowthIsConfigurableAndCapped(t *testing.T) {
+	p := PollConfig{InitialInterval: time.Second, MaxInterval: 10 * time.Second}
+	if got := p.next(2 * time.Second); got != 3*time.Second {
+		t.Fatalf("expected default 1.5x growth to 3s, got %v", got)
+	}
+	p.BackoffFactor = 3
+	if got := p.next(2 * time.Second); got != 6*time.Second {
+		t.Fatalf("expected 3x growth to 6s, got %v", got)
+	}
+	if got := p.next(5 * time.Second); got != 10*time.Second {
+		t.Fatalf("expected growth capped at MaxInterval, got %v", got)
+	}
+	p.BackoffFactor = 1
+	if got := p.next(2 * time.Second); got != 2*time.Second {
+		t.Fatalf("expected a factor of 1 to keep the interval fixed, got %v", got)
+	}
+}
+
+func TestPollJitterStaysWithinBounds(t *testing.T) {
+	p := PollConfig{InitialInterval: 4 * time.Second, MaxInterval: 30 * time.Second}
+	if got := p.jittered(10 * time.Second); got != 10*time.Second {
+		t.Fatalf("expected no jitter by default, got %v", got)
+	}
+
+	p.Jitter = 0.5
+	varied := false
+	for i := 0; i < 200; i++ {
+		got := p.jittered(10 * time.Second)
+		if got < 5*time.Second || got > 10*time.Second {
+			t.Fatalf("jittered 10s out of [5s, 10s]: %v", got)
+		}
+		varied = varied || got != 10*time.Second
+		if got := p.jittered(5 * time.Second); got < p.InitialInterval {
+			t.Fatalf("jitter pushed %v below the initial interval %v", got, p.InitialInterval)
+		}
+	}
+	if !varied {
+		t.Fatal("expected jitter to vary the wait")
+	}
+}
+
+func TestPollHonorsRetryAfterClampedToMaxInterval(t *testing.T) {
+	var pollTimes []time.Time
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		pollTimes = append(pollTimes, time.Now())
+		if len(pollTimes) == 1 {
+			resp := jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"running"}}`)
+			resp.Header.Set("Retry-After", "5")
+			return resp, nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"completed"}}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.pollCfg.MaxInterval = 50 * time.Millisecond
+
+	if result := client.TriggerAll(context.Background(), "/test"); !result.Success {
+		t.Fatalf("expected success true, got false with error: %v", result.Error)
+	}
+	if len(pollTimes) != 2 {
+		t.Fatalf("expected 2 polls, got %d", len(pollTimes))
+	}
+	gap := pollTimes[1].Sub(pollTimes[0])
+	if gap < 50*time.Millisecond {
+		t.Fatalf("expected Retry-After to delay the next poll to the max interval, waited %v", gap)
+	}
+	if gap > time.Second {
+		t.Fatalf("expected Retry-After to be clamped to the max interval, waited %v", gap)
+	}
+}
+
+func TestPollReturnsPromptlyWhenCancelledDuringSleep(t *testing.T) {
+	polled := make(chan struct{}, 1)
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		select {
+		case polled <- struct{}{}:
+		default:
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"running"}}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.pollCfg.InitialInterval = time.Hour
+	client.pollCfg.MaxInterval = time.Hour
+	client.pollCfg.MaxWaitTime = 2 * time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-polled
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	result := client.TriggerAll(ctx, "/test")
+	if !errors.Is(result.Error, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", result.Error)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected prompt return after cancellation, took %v", elapsed)
+	}
+}
+
+func TestSleepCtxCancelledBeforeSleep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	if err := sleepCtx(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("expected no sleep on a cancelled context, took %v", elapsed)
+	}
+}
+
+func TestPollRetriesStatusFetchOn503(t *testing.T) {
+	var polls int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		polls++
+		if polls == 1 {
+			return jsonResponse(http.StatusServiceUnavailable, `maintenance`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"completed"}}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+
+	result := client.TriggerAll(context.Background(), "/test")
+	if !result.Success {
+		t.Fatalf("expected success after a 503 poll, got error: %v", result.Error)
+	}
+	if polls != 2 {
+		t.Fatalf("expected 2 polls, got %d", polls)
+	}
+}
+
+func TestPollFailsImmediatelyOn403(t *testing.T) {
+	var polls int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		polls++
+		return jsonResponse(http.StatusForbidden, `forbidden`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+
+	result := client.TriggerAll(context.Background(), "/test")
+	if result.Success {
+		t.Fatalf("expected success false, got true")
+	}
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "unexpected status 403") {
+		t.Fatalf("expected 403 error, got: %v", result.Error)
+	}
+	if polls != 1 {
+		t.Fatalf("expected no further polls after a 403, got %d", polls)
+	}
+}
+
+func TestTriggerAllNotifiesWebhookOnFailure(t *testing.T) {
+	payloads := make(chan map[string]any, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]any
+		json.NewDecoder(r.Body).Decode(&p)
+		payloads <- p
+	}))
+	defer hook.Close()
+
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == strings.TrimPrefix(hook.URL, "http://") {
+			return http.DefaultTransport.RoundTrip(req)
+		}
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"failed","pipelines_failed":1,
+			"results":{"games":{"status":"failed"},"players":{"status":"completed"}}}}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.notifier = notify.New(hook.URL, zerolog.Nop())
+
+	if result := client.TriggerAll(context.Background(), "/test"); result.Success {
+		t.Fatalf("expected success false, got true")
+	}
+	client.WaitNotifications(context.Background())
+
+	select {
+	case p := <-payloads:
+		if p["job_id"] != "job-1" {
+			t.Fatalf("expected job_id job-1, got %v", p["job_id"])
+		}
+		failed, _ := p["failed_pipelines"].([]any)
+		if len(failed) != 1 || failed[0] != "games" {
+			t.Fatalf("expected failed_pipelines [games], got %v", p["failed_pipelines"])
+		}
+	default:
+		t.Fatalf("expected a webhook notification")
+	}
+}
+
+func TestTriggerAllNotifiesOnlyAfterRetriesExhausted(t *testing.T) {
+	var notifications atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notifications.Add(1)
+	}))
+	defer hook.Close()
+
+	for _, tt := range []struct {
+		name     string
+		failures int // start attempts answered 503 before a 202
+		want     int32
+	}{
+		{name: "fails twice then succeeds", failures: 2, want: 0},
+		{name: "retry budget exhausted", failures: 3, want: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			notifications.Store(0)
+			var starts int
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if req.Method == http.MethodPost {
+					starts++
+					if starts <= tt.failures {
+						return jsonResponse(http.StatusServiceUnavailable, "unavailable"), nil
+					}
+					return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+				}
+				return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"completed"}}`), nil
+			})
+
+			client := newTestClient("http://example.test", transport)
+			client.retryCfg.MaxRetries = 2
+			client.notifier = notify.New(hook.URL, zerolog.Nop())
+
+			result := client.TriggerAll(context.Background(), "/test")
+			client.WaitNotifications(context.Background())
+
+			if result.Success != (tt.want == 0) {
+				t.Fatalf("expected success %v, got %v (%v)", tt.want == 0, result.Success, result.Error)
+			}
+			if got := notifications.Load(); got != tt.want {
+				t.Fatalf("expected %d failure notifications, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPollRequestTimeoutFailsHungPollFast(t *testing.T) {
+	var polls int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		polls++
+		if polls == 1 {
+			<-req.Context().Done() // hang until the per-request timeout
+			return nil, req.Context().Err()
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"completed"}}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.pollCfg.RequestTimeout = 20 * time.Millisecond
+	client.pollCfg.MaxWaitTime = time.Minute
+
+	start := time.Now()
+	result := client.TriggerAll(context.Background(), "/v1/internal/pipelines/post-game")
+	if !result.Success {
+		t.Fatalf("expected success after the hung poll, got error: %v", result.Error)
+	}
+	if polls != 2 {
+		t.Fatalf("expected 2 polls, got %d", polls)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected the hung poll to time out quickly, took %v", elapsed)
+	}
+}
+
+func TestPollRequestTimeoutKeepsParentCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		cancel()
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.pollCfg.RequestTimeout = time.Minute
+	client.pollCfg.MaxWaitTime = time.Minute
+
+	done := make(chan TriggerResult, 1)
+	go func() { done <- client.TriggerAll(ctx, "/v1/internal/pipelines/post-game") }()
+	select {
+	case result := <-done:
+		if !errors.Is(result.Error, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", result.Error)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected cancelling the run to abort the in-flight poll")
+	}
+}
+
+func TestTriggerAllReusesIdempotencyKeyAcrossRetries(t *testing.T) {
+	var keys []string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodPost {
+			return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"completed"}}`), nil
+		}
+		keys = append(keys, req.Header.Get("X-Request-Key"))
+		if len(keys)%2 == 1 {
+			return nil, errors.New("connection reset by peer") // response lost
+		}
+		return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.retryCfg.MaxRetries = 1
+	client.idempotencyHeader = "X-Request-Key"
+
+	for range 2 {
+		if result := client.TriggerAll(context.Background(), "/v1/internal/pipelines/post-game"); !result.Success {
+			t.Fatalf("expected success after a retried start, got error: %v", result.Error)
+		}
+	}
+	if len(keys) != 4 || keys[0] == "" {
+		t.Fatalf("expected 4 keyed start requests, got %q", keys)
+	}
+	if keys[0] != keys[1] || keys[2] != keys[3] {
+		t.Fatalf("expected each run to reuse its key across retries, got %q", keys)
+	}
+	if keys[0] == keys[2] {
+		t.Fatalf("expected a new key per run, got %q", keys)
+	}
+
+	client.idempotencyHeader = ""
+	keys = nil
+	client.TriggerAll(context.Background(), "/v1/internal/pipelines/post-game")
+	if len(keys) == 0 || keys[0] != "" {
+		t.Fatalf("expected no key when disabled, got %q", keys)
+	}
+}
+
+func TestStatusPolicyKeepsPipelinesFailedCount(t *testing.T) {
+	js := &JobStatus{
+		Status:          "completed",
+		PipelinesFailed: 1,
+		Results:         map[string]PipelineResult{"a": {Status: "completed"}, "b": {Status: "error"}},
+	}
+	if _, _, ok := StatusPolicy(nil).evaluate(js); ok {
+		t.Fatalf("expected pipelines_failed=1 to fail the run under the default policy")
+	}
+	if _, _, ok := (StatusPolicy{"skipped": OutcomePass}).evaluate(js); ok {
+		t.Fatalf("expected a policy that remaps none of the reported statuses to keep the failure")
+	}
+	js.Results["c"] = PipelineResult{Status: "skipped"}
+	js.PipelinesFailed = 2
+	if _, _, ok := (StatusPolicy{"skipped": OutcomePass}).evaluate(js); ok {
+		t.Fatalf("expected one remapped result not to excuse two failures")
+	}
+	delete(js.Results, "c")
+	js.PipelinesFailed = 1
+	if _, degraded, ok := (StatusPolicy{"error": OutcomeWarn}).evaluate(js); !ok || len(degraded) != 1 {
+		t.Fatalf("expected an explicit remap of the failing status to degrade instead, got ok=%v degraded=%v", ok, degraded)
+	}
+}
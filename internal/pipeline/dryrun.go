@@ -0,0 +1,64 @@
+package pipeline
+
+import (
+	"fmt"
+	"net/http"
+
+	"cron-runner/internal/redact"
+	"cron-runner/internal/retry"
+
+	"github.com/rs/zerolog"
+)
+
+// DryRun reports whether the client only logs the requests it would send
+// (DRY_RUN).
+func (c *Client) DryRun() bool {
+	return c.dryRun
+}
+
+// dryRunResult logs the start request a run would send, with auth redacted,
+// and the retry (and, if poll is set, polling) settings it would use, then
+// returns a synthetic success without contacting the backend.
+func (c *Client) dryRunResult(endpoint string, rc retry.Config, poll bool, log zerolog.Logger) TriggerResult {
+	req, err := http.NewRequest(http.MethodPost, c.endpointURL(endpoint), nil)
+	if err != nil {
+		return TriggerResult{DryRun: true, Error: fmt.Errorf("failed to create request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.setAuth(req)
+
+	ev := log.Warn().
+		Bool("dry_run", true).
+		Str("method", req.Method).
+		Str("url", req.URL.String()).
+		Interface("headers", c.redactedHeaders(req.Header)).
+		Int("max_retries", rc.MaxRetries).
+		Dur("initial_backoff", rc.InitialBackoff).
+		Dur("max_backoff", rc.MaxBackoff).
+		Float64("backoff_factor", rc.BackoffFactor).
+		Str("backoff_strategy", string(rc.BackoffStrategy))
+	if poll {
+		ev = ev.
+			Str("poll_mode", c.pollCfg.Mode).
+			Dur("poll_initial_interval", c.pollCfg.InitialInterval).
+			Dur("poll_max_interval", c.pollCfg.MaxInterval).
+			Dur("poll_max_wait_time", c.pollCfg.MaxWaitTime)
+	}
+	ev.Msg("DRY RUN: request not sent to backend")
+
+	return TriggerResult{Success: true, DryRun: true}
+}
+
+// redactedHeaders flattens h for logging, masking the headers on the
+// REDACT_KEYS denylist to a prefix and length.
+func (c *Client) redactedHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k := range h {
+		if c.deny.Match(k) {
+			out[k] = redact.Mask(h.Get(k))
+		} else {
+			out[k] = h.Get(k)
+		}
+	}
+	return out
+}
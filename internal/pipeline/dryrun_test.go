@@ -0,0 +1,59 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestTriggerAllDryRunSendsNothing(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected request in dry run: %s %s", req.Method, req.URL)
+		return nil, http.ErrHandlerTimeout
+	})
+	client := newTestClient("http://backend.test", transport)
+	client.dryRun = true
+	var buf bytes.Buffer
+	client.log = zerolog.New(&buf)
+
+	result := client.TriggerAll(context.Background(), "/api/v1/pipelines/all")
+
+	if !result.Success || !result.DryRun {
+		t.Fatalf("result = %+v, want synthetic dry-run success", result)
+	}
+	if got := result.Summary(); !strings.Contains(got, "dry_run") {
+		t.Errorf("Summary() = %q, want dry_run outcome", got)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`"dry_run":true`,
+		`"url":"http://backend.test/api/v1/pipelines/all"`,
+		`"Authorization":"Bear…(17 chars)"`,
+		`"poll_mode"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log missing %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "test-token") {
+		t.Errorf("log leaked the auth token:\n%s", out)
+	}
+}
+
+func TestTriggerEndpointDryRun(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected request in dry run: %s %s", req.Method, req.URL)
+		return nil, http.ErrHandlerTimeout
+	})
+	client := newTestClient("http://backend.test", transport)
+	client.dryRun = true
+
+	result := client.TriggerEndpoint(context.Background(), "/api/v1/pipelines/one")
+	if !result.Success || !result.DryRun {
+		t.Fatalf("result = %+v, want synthetic dry-run success", result)
+	}
+}
@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// requestTiming records the phases of the latest attempt of one request, for
+// HTTP_TIMING_DEBUG. Phases that did not happen (no DNS for an IP address,
+// no dial or handshake on a reused connection) stay zero.
+type requestTiming struct {
+	mu sync.Mutex // trace hooks may run on dialer goroutines
+	p  phaseTimes
+}
+
+type phaseTimes struct {
+	start               time.Time
+	dnsStart, dnsDone   time.Time
+	connStart, connDone time.Time
+	tlsStart, tlsDone   time.Time
+	firstByte           time.Time
+	reused              bool
+}
+
+// withRequestTiming returns a context whose requests report their phase
+// timings into the returned requestTiming. Each new attempt resets it.
+func withRequestTiming(ctx context.Context) (context.Context, *requestTiming) {
+	t := &requestTiming{}
+	at := func(field *time.Time) {
+		t.mu.Lock()
+		*field = time.Now()
+		t.mu.Unlock()
+	}
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			t.p = phaseTimes{start: time.Now()}
+			t.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.p.reused = info.Reused
+			t.mu.Unlock()
+		},
+		DNSStart:             func(httptrace.DNSStartInfo) { at(&t.p.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { at(&t.p.dnsDone) },
+		ConnectStart:         func(string, string) { at(&t.p.connStart) },
+		ConnectDone:          func(string, string, error) { at(&t.p.connDone) },
+		TLSHandshakeStart:    func() { at(&t.p.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { at(&t.p.tlsDone) },
+		GotFirstResponseByte: func() { at(&t.p.firstByte) },
+	}
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+// phase returns end - start, or 0 if the phase did not complete.
+func phase(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// log writes the latest attempt's timings: DNS lookup, TCP connect, TLS
+// handshake and time to first response byte, measured from when the
+// attempt asked for a connection.
+func (t *requestTiming) log(log zerolog.Logger, msg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.p
+	log.Info().
+		Dur("dns", phase(p.dnsStart, p.dnsDone)).
+		Dur("connect", phase(p.connStart, p.connDone)).
+		Dur("tls", phase(p.tlsStart, p.tlsDone)).
+		Dur("ttfb", phase(p.start, p.firstByte)).
+		Bool("conn_reused", p.reused).
+		Msg(msg)
+}
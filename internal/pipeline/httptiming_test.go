@@ -0,0 +1,59 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestStartRequestTimingLogged(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"data":{"job_id":"job-1"}}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"job_id":"job-1","status":"completed"}}`))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	client := newTestClient(srv.URL, srv.Client().Transport)
+	client.log = zerolog.New(&buf)
+	client.httpTimingDebug = true
+
+	if result := client.TriggerAll(context.Background(), "/v1/internal/pipelines/post-game"); !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+
+	var timing map[string]any
+	for _, line := range bytes.Split(buf.Bytes(), []byte("\n")) {
+		var entry map[string]any
+		if json.Unmarshal(line, &entry) == nil && entry["message"] == "start request timing" {
+			timing = entry
+		}
+	}
+	if timing == nil {
+		t.Fatalf("expected a start request timing line, got:\n%s", buf.String())
+	}
+	for _, field := range []string{"dns", "connect", "tls", "ttfb"} {
+		if _, ok := timing[field].(float64); !ok {
+			t.Fatalf("expected numeric %s, got %v", field, timing)
+		}
+	}
+	// 127.0.0.1 needs no DNS lookup, but a fresh TLS connection has every
+	// other phase.
+	for _, field := range []string{"connect", "tls", "ttfb"} {
+		if timing[field].(float64) <= 0 {
+			t.Fatalf("expected %s to be measured, got %v", field, timing)
+		}
+	}
+	if timing["conn_reused"] != false {
+		t.Fatalf("expected a fresh connection, got %v", timing)
+	}
+}
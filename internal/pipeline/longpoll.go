@@ -0,0 +1,75 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// longPollJobCompletion waits for a job using the backend's blocking
+// GET /pipelines/jobs/{id}/result endpoint. Each request is held server-side
+// for up to LongPollTimeout; non-terminal or timed-out responses are re-polled
+// until the overall MaxWaitTime deadline.
+func (c *Client) longPollJobCompletion(ctx context.Context, jobID string, log zerolog.Logger) (*JobStatus, error) {
+	url := c.runEndpointURL(ctx, "/v1/internal/pipelines/jobs/"+neturl.PathEscape(jobID)+"/result")
+	deadline := time.Now().Add(c.pollCfg.MaxWaitTime)
+
+	// The shared client's Timeout would cut long polls short; the per-request
+	// context below bounds each request instead.
+	hc := &http.Client{Transport: c.httpClient.Transport, Jar: c.httpClient.Jar}
+
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, fmt.Errorf("polling timeout after %v", c.pollCfg.MaxWaitTime)
+		}
+
+		reqTimeout := c.pollCfg.LongPollTimeout
+		if reqTimeout <= 0 || reqTimeout > remaining {
+			reqTimeout = remaining
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx, reqTimeout)
+		status, _, err := c.fetchJobStatus(reqCtx, hc, url)
+		timedOut := reqCtx.Err() == context.DeadlineExceeded
+		cancel()
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		switch {
+		case c.isPermanentPollError(err):
+			return nil, fmt.Errorf("failed to long poll job result: %w", err)
+		case err != nil && timedOut:
+			log.Debug().
+				Str("job_id", jobID).
+				Dur("remaining", time.Until(deadline)).
+				Msg("long poll timed out, re-polling")
+			continue
+		case err != nil:
+			log.Warn().
+				Err(err).
+				Str("job_id", jobID).
+				Msg("failed to long poll job result, will retry")
+		case isTerminalStatus(status.Status):
+			return status, nil
+		default:
+			c.progress.update(status)
+			log.Debug().
+				Str("job_id", jobID).
+				Str("status", status.Status).
+				Msg("long poll returned non-terminal status, re-polling")
+			continue
+		}
+
+		// Back off briefly after an error so a failing endpoint isn't hammered.
+		if err := sleepCtx(ctx, c.pollCfg.InitialInterval); err != nil {
+			return nil, err
+		}
+	}
+}
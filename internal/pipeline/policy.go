@@ -0,0 +1,49 @@
+package pipeline
+
+import "sort"
+
+// Per-pipeline outcomes a status can be mapped to.
+const (
+	OutcomePass = "pass"
+	OutcomeWarn = "warn"
+	OutcomeFail = "fail"
+)
+
+// StatusPolicy maps a per-pipeline status (e.g. "skipped", "warning",
+// "partial") to OutcomePass, OutcomeWarn or OutcomeFail. Statuses not in the
+// policy fall back to the default: "failed" fails, anything else passes.
+type StatusPolicy map[string]string
+
+func (p StatusPolicy) outcome(status string) string {
+	if o, ok := p[status]; ok {
+		return o
+	}
+	if status == "failed" {
+		return OutcomeFail
+	}
+	return OutcomePass
+}
+
+// evaluate aggregates a finished job's per-pipeline results under the policy
+// and returns the names of pipelines that failed or degraded the run.
+// The job's pipelines_failed count still fails the run unless the policy
+// explicitly remaps at least that many results to pass or warn: only those
+// can be backend failures that were downgraded on purpose.
+func (p StatusPolicy) evaluate(js *JobStatus) (failed, degraded []string, ok bool) {
+	remapped := 0
+	for name, r := range js.Results {
+		if o, explicit := p[r.Status]; explicit && o != OutcomeFail {
+			remapped++
+		}
+		switch p.outcome(r.Status) {
+		case OutcomeFail:
+			failed = append(failed, name)
+		case OutcomeWarn:
+			degraded = append(degraded, name)
+		}
+	}
+	sort.Strings(failed)
+	sort.Strings(degraded)
+	ok = len(failed) == 0 && js.PipelinesFailed <= remapped
+	return failed, degraded, ok
+}
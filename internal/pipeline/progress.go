@@ -0,0 +1,109 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+)
+
+// JobProgress is the latest known state of a TriggerAll run: live progress
+// while it runs, then its outcome until the next run starts.
+type JobProgress struct {
+	Running        bool       `json:"running"`
+	RunID          string     `json:"run_id"`
+	Endpoint       string     `json:"endpoint"`
+	JobID          string     `json:"job_id,omitempty"`
+	StartedAt      time.Time  `json:"started_at"`
+	ElapsedSeconds float64    `json:"elapsed_seconds"`
+	Job            *JobStatus `json:"job,omitempty"`     // latest status fetched by the poller
+	Summary        string     `json:"summary,omitempty"` // set once the run has finished
+	Canary         bool       `json:"canary,omitempty"`  // routed to CANARY_BACKEND_URL
+
+	// LastPollAt is the poller's heartbeat: when it last fetched a status.
+	// LastProgressAt is when the fetched status last changed (state,
+	// pipelines completed or current pipeline), or the start of the run.
+	LastPollAt     *time.Time `json:"last_poll_at,omitempty"`
+	LastProgressAt time.Time  `json:"last_progress_at"`
+}
+
+// progressTracker holds the JobProgress of the current or last run. The
+// poller publishes into it; GET /status reads it through Client.Progress.
+type progressTracker struct {
+	mu      sync.Mutex
+	cur     *JobProgress
+	elapsed time.Duration // final duration once the run has finished
+}
+
+func (p *progressTracker) start(runID, endpoint string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	p.cur = &JobProgress{Running: true, RunID: runID, Endpoint: endpoint, StartedAt: now, LastProgressAt: now}
+	p.elapsed = 0
+}
+
+func (p *progressTracker) setJobID(jobID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cur != nil {
+		p.cur.JobID = jobID
+	}
+}
+
+func (p *progressTracker) setCanary() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cur != nil {
+		p.cur.Canary = true
+	}
+}
+
+// update records a freshly fetched job status and the poll heartbeat.
+// Statuses are never modified after decoding, so readers may share the
+// pointer.
+func (p *progressTracker) update(status *JobStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cur == nil {
+		return
+	}
+	now := time.Now()
+	p.cur.LastPollAt = &now
+	if prev := p.cur.Job; prev == nil || prev.Status != status.Status ||
+		prev.PipelinesCompleted != status.PipelinesCompleted ||
+		prev.CurrentPipeline != status.CurrentPipeline {
+		p.cur.LastProgressAt = now
+	}
+	p.cur.Job = status
+}
+
+func (p *progressTracker) finish(result TriggerResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cur == nil {
+		return
+	}
+	p.cur.Running = false
+	p.cur.Summary = result.Summary()
+	if result.JobDetails != nil {
+		p.cur.Job = result.JobDetails
+	}
+	p.elapsed = result.Duration
+}
+
+// Progress returns the progress of the running TriggerAll, or the outcome of
+// the last one when none is running; ok is false before the first run.
+func (c *Client) Progress() (progress JobProgress, ok bool) {
+	p := &c.progress
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cur == nil {
+		return JobProgress{}, false
+	}
+	progress = *p.cur
+	if progress.Running {
+		progress.ElapsedSeconds = time.Since(progress.StartedAt).Seconds()
+	} else {
+		progress.ElapsedSeconds = p.elapsed.Seconds()
+	}
+	return progress, true
+}
@@ -0,0 +1,49 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+)
+
+// emitResultRecord emits the finished run as one OpenTelemetry log record,
+// for OTEL_RESULT_LOGS. ctx carries the run's span, so the record is
+// correlated with its trace.
+func (c *Client) emitResultRecord(ctx context.Context, endpoint string, result TriggerResult) {
+	if c.resultLogs == nil {
+		return
+	}
+	var rec otellog.Record
+	rec.SetTimestamp(time.Now())
+	rec.SetSeverity(otellog.SeverityInfo)
+	if !result.Success {
+		rec.SetSeverity(otellog.SeverityError)
+	}
+	rec.SetSeverityText(rec.Severity().String())
+	rec.SetBody(otellog.StringValue(result.Summary()))
+	rec.AddAttributes(
+		otellog.String("event.name", "run_result"),
+		otellog.String("run_id", result.RunID),
+		otellog.String("endpoint", endpoint),
+		otellog.String("job_id", result.JobID),
+		otellog.Bool("success", result.Success),
+		otellog.Bool("degraded", result.Degraded),
+		otellog.Bool("skipped", result.Skipped),
+		otellog.Bool("dry_run", result.DryRun),
+		otellog.Int("attempts", result.Attempts),
+		otellog.Float64("duration_seconds", result.Duration.Seconds()),
+	)
+	if result.Error != nil {
+		rec.AddAttributes(otellog.String("error", result.Error.Error()))
+	}
+	if js := result.JobDetails; js != nil {
+		rec.AddAttributes(
+			otellog.String("job_status", js.Status),
+			otellog.Int("pipelines_completed", js.PipelinesCompleted),
+			otellog.Int("pipelines_failed", js.PipelinesFailed),
+			otellog.Int("pipelines_total", js.PipelinesTotal),
+		)
+	}
+	c.resultLogs.Emit(ctx, rec)
+}
@@ -0,0 +1,85 @@
+package pipeline
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// memoryLogExporter keeps exported log records in memory.
+type memoryLogExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (e *memoryLogExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range records {
+		e.records = append(e.records, r.Clone())
+	}
+	return nil
+}
+
+func (e *memoryLogExporter) Shutdown(context.Context) error   { return nil }
+func (e *memoryLogExporter) ForceFlush(context.Context) error { return nil }
+
+func TestTriggerAllEmitsResultLogRecord(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"completed","pipelines_total":2,"pipelines_completed":2}}`), nil
+	})
+
+	exporter := &memoryLogExporter{}
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	client := newTestClient("http://example.test", transport)
+	client.resultLogs = lp.Logger("test")
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent")
+	result := client.TriggerAll(WithRunID(ctx, "run-1"), "/v1/internal/pipelines/post-game")
+	span.End()
+	if !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+
+	if len(exporter.records) != 1 {
+		t.Fatalf("expected one result record, got %d", len(exporter.records))
+	}
+	rec := exporter.records[0]
+	if rec.Severity() != otellog.SeverityInfo || rec.Body().AsString() != result.Summary() {
+		t.Fatalf("expected an info record with the run summary, got %v %q", rec.Severity(), rec.Body().AsString())
+	}
+	if rec.TraceID() != span.SpanContext().TraceID() {
+		t.Fatalf("expected the record correlated with trace %s, got %s", span.SpanContext().TraceID(), rec.TraceID())
+	}
+
+	attrs := make(map[string]otellog.Value)
+	rec.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+	want := map[string]otellog.Value{
+		"event.name":          otellog.StringValue("run_result"),
+		"run_id":              otellog.StringValue("run-1"),
+		"endpoint":            otellog.StringValue("/v1/internal/pipelines/post-game"),
+		"job_id":              otellog.StringValue("job-1"),
+		"success":             otellog.BoolValue(true),
+		"pipelines_completed": otellog.IntValue(2),
+	}
+	for k, v := range want {
+		if !attrs[k].Equal(v) {
+			t.Fatalf("expected %s=%v, got %v", k, v, attrs[k])
+		}
+	}
+	if _, ok := attrs["error"]; ok {
+		t.Fatalf("expected no error attribute on success")
+	}
+}
@@ -0,0 +1,32 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+type runIDKey struct{}
+
+// NewRunID returns a short random hex ID that ties together every log line
+// of one TriggerAll: start, retries and polls.
+func NewRunID() string {
+	b := make([]byte, 8)
+	rand.Read(b) // never returns an error
+	return hex.EncodeToString(b)
+}
+
+// WithRunID returns a context that makes TriggerAll, TriggerOne and
+// TriggerOneWithRetry use id as the run ID instead of generating one, so a
+// caller can report the ID before the run finishes.
+func WithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// runIDFrom returns the run ID set by WithRunID, or a new one.
+func runIDFrom(ctx context.Context) string {
+	if id, ok := ctx.Value(runIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	return NewRunID()
+}
@@ -0,0 +1,75 @@
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestTriggerAllTagsEveryLogWithRunID(t *testing.T) {
+	var posts int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			posts++
+			if posts == 1 {
+				return jsonResponse(http.StatusServiceUnavailable, "busy"), nil
+			}
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"completed","current_pipeline":"box-scores"}}`), nil
+	})
+
+	var buf bytes.Buffer
+	client := newTestClient("http://example.test", transport)
+	client.log = zerolog.New(&buf)
+	client.retryCfg.MaxRetries = 1
+
+	result := client.TriggerAll(context.Background(), "/v1/internal/pipelines/post-game")
+	if !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+	if len(result.RunID) != 16 {
+		t.Fatalf("expected a 16-char hex run ID, got %q", result.RunID)
+	}
+
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var entry struct {
+			Message string `json:"message"`
+			RunID   string `json:"run_id"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &entry); err != nil {
+			t.Fatalf("decode log: %v", err)
+		}
+		if entry.RunID != result.RunID {
+			t.Errorf("log %q: expected run_id %q, got %q", entry.Message, result.RunID, entry.RunID)
+		}
+		seen[entry.Message] = true
+	}
+	for _, msg := range []string{"starting pipeline job", "retrying after backoff", "pipeline started", "all pipelines completed successfully"} {
+		if !seen[msg] {
+			t.Errorf("expected a %q log line, got %v", msg, seen)
+		}
+	}
+}
+
+func TestTriggerAllUsesRunIDFromContext(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"completed"}}`), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	result := client.TriggerAll(WithRunID(context.Background(), "abc123"), "/v1/internal/pipelines/post-game")
+	if result.RunID != "abc123" {
+		t.Fatalf("expected run ID from context, got %q", result.RunID)
+	}
+}
@@ -0,0 +1,133 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	neturl "net/url"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// sessionTransport authenticates requests with a session cookie instead of
+// a static bearer token. It logs in on first use by POSTing credentials to
+// loginURL, keeps the returned cookies in a jar, and on a 401 logs in again
+// and resends the request once.
+type sessionTransport struct {
+	base     http.RoundTripper
+	loginURL string
+	username string
+	password string
+	jar      http.CookieJar
+	log      zerolog.Logger
+
+	mu       sync.Mutex
+	loggedIn bool
+}
+
+func newSessionTransport(base http.RoundTripper, loginURL, username, password string, log zerolog.Logger) *sessionTransport {
+	jar, _ := cookiejar.New(nil) // only errors on a bad PublicSuffixList option
+	return &sessionTransport{
+		base:     base,
+		loginURL: loginURL,
+		username: username,
+		password: password,
+		jar:      jar,
+		log:      log,
+	}
+}
+
+func (t *sessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.ensureSession(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.send(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// The body has to be sent again; give up on the re-login if it can't be.
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	t.log.Warn().Str("url", req.URL.String()).Msg("session rejected, logging in again")
+	t.mu.Lock()
+	t.loggedIn = false
+	t.mu.Unlock()
+	if err := t.ensureSession(req); err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return t.send(retry)
+}
+
+// send attaches the session cookies to a copy of req and records any
+// cookies set by the response.
+func (t *sessionTransport) send(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.Header.Del("Cookie")
+	for _, c := range t.jar.Cookies(r.URL) {
+		r.AddCookie(c)
+	}
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	if cookies := resp.Cookies(); len(cookies) > 0 {
+		t.jar.SetCookies(r.URL, cookies)
+	}
+	return resp, nil
+}
+
+// ensureSession logs in unless a session is already established.
+func (t *sessionTransport) ensureSession(req *http.Request) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.loggedIn {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]string{"username": t.username, "password": t.password})
+	if err != nil {
+		return fmt.Errorf("failed to encode login request: %w", err)
+	}
+	login, err := http.NewRequestWithContext(req.Context(), http.MethodPost, t.loginURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create login request: %w", err)
+	}
+	login.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.base.RoundTrip(login)
+	if err != nil {
+		return fmt.Errorf("session login failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("session login returned status %d: %s",
+			resp.StatusCode, truncateForLog(string(b), endpointErrorBodyMaxLen))
+	}
+	cookies := resp.Cookies()
+	if len(cookies) == 0 {
+		return fmt.Errorf("session login returned no cookie")
+	}
+
+	u, _ := neturl.Parse(t.loginURL) // already parsed by NewRequest above
+	t.jar.SetCookies(u, cookies)
+	t.loggedIn = true
+	t.log.Info().Int("cookies", len(cookies)).Msg("session login succeeded")
+	return nil
+}
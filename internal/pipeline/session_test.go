@@ -0,0 +1,97 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"cron-runner/internal/config"
+
+	"github.com/rs/zerolog"
+)
+
+func TestSessionAuthLogsInAndSendsCookie(t *testing.T) {
+	var logins int
+	session := ""
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/auth/login" {
+			var creds map[string]string
+			json.NewDecoder(r.Body).Decode(&creds)
+			if creds["username"] != "runner" || creds["password"] != "hunter2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			logins++
+			session = "s" + strconv.Itoa(logins)
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: session, Path: "/"})
+			return
+		}
+		if c, err := r.Cookie("session"); err != nil || c.Value != session {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected no bearer header in session mode, got %q", r.Header.Get("Authorization"))
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	client, err := NewClient(&config.Config{
+		BackendURL:       backend.URL,
+		SessionLoginPath: "/auth/login",
+		SessionUsername:  "runner",
+		SessionPassword:  "hunter2",
+		RequestTimeout:   time.Second,
+	}, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := client.TriggerEndpoint(context.Background(), "/v1/internal/pipelines/pre-game"); !result.Success {
+		t.Fatalf("expected success with session cookie, got: %v", result.Error)
+	}
+	if result := client.TriggerEndpoint(context.Background(), "/v1/internal/pipelines/pre-game"); !result.Success {
+		t.Fatalf("expected the session to be reused, got: %v", result.Error)
+	}
+	if logins != 1 {
+		t.Fatalf("expected 1 login, got %d", logins)
+	}
+
+	// The backend expires the session; the next trigger logs in again.
+	session = "expired"
+	if result := client.TriggerEndpoint(context.Background(), "/v1/internal/pipelines/pre-game"); !result.Success {
+		t.Fatalf("expected success after re-login, got: %v", result.Error)
+	}
+	if logins != 2 {
+		t.Fatalf("expected a re-login on 401, got %d logins", logins)
+	}
+}
+
+func TestSessionAuthLoginFailure(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer backend.Close()
+
+	client, err := NewClient(&config.Config{
+		BackendURL:       backend.URL,
+		SessionLoginPath: "/auth/login",
+		SessionUsername:  "runner",
+		SessionPassword:  "wrong",
+		RequestTimeout:   time.Second,
+	}, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := client.TriggerEndpoint(context.Background(), "/v1/internal/pipelines/pre-game")
+	if result.Success || result.Error == nil {
+		t.Fatalf("expected login failure to fail the trigger")
+	}
+}
@@ -0,0 +1,134 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"cron-runner/internal/config"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshTunnel dials backend connections through an SSH server
+// (SSH_TUNNEL_HOST), like "ssh -L" without a sidecar. The SSH connection is
+// opened on first use and shared by all backend connections; once it drops,
+// the next dial opens a new one.
+type sshTunnel struct {
+	addr    string
+	config  *ssh.ClientConfig
+	timeout time.Duration // bounds the TCP dial and SSH handshake
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// newSSHTunnel builds the tunnel from the SSH_TUNNEL_* settings. The key
+// and known_hosts files are read here; nothing is dialed yet.
+func newSSHTunnel(cfg *config.Config) (*sshTunnel, error) {
+	var auth []ssh.AuthMethod
+	if cfg.SSHTunnelKeyFile != "" {
+		pem, err := os.ReadFile(cfg.SSHTunnelKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SSH tunnel key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(pem)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SSH tunnel key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if cfg.SSHTunnelPassword != "" {
+		auth = append(auth, ssh.Password(cfg.SSHTunnelPassword))
+	}
+
+	var hostKey ssh.HostKeyCallback
+	switch {
+	case cfg.SSHTunnelKnownHosts != "":
+		cb, err := knownhosts.New(cfg.SSHTunnelKnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SSH tunnel known_hosts: %w", err)
+		}
+		hostKey = cb
+	case cfg.SSHTunnelInsecureHostKey:
+		hostKey = ssh.InsecureIgnoreHostKey()
+	default:
+		return nil, errors.New("SSH tunnel needs SSH_TUNNEL_KNOWN_HOSTS or SSH_TUNNEL_INSECURE_HOST_KEY=true")
+	}
+
+	addr := cfg.SSHTunnelHost
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+	return &sshTunnel{
+		addr: addr,
+		config: &ssh.ClientConfig{
+			User:            cfg.SSHTunnelUser,
+			Auth:            auth,
+			HostKeyCallback: hostKey,
+		},
+		timeout: cfg.RequestTimeout,
+	}, nil
+}
+
+// DialContext opens a connection to addr from the SSH server's side; it
+// has the signature of http.Transport.DialContext.
+func (t *sshTunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := t.sshClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("ssh tunnel via %s: %w", t.addr, err)
+	}
+	return conn, nil
+}
+
+// sshClient returns the shared SSH connection, opening it if needed.
+func (t *sshTunnel) sshClient(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return nil, fmt.Errorf("ssh tunnel: failed to connect to %s: %w", t.addr, err)
+	}
+	// The SSH handshake does not take a context; bound it by the same
+	// deadline instead.
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, t.addr, t.config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh tunnel: handshake with %s failed: %w", t.addr, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	client := ssh.NewClient(c, chans, reqs)
+	t.client = client
+	go func() {
+		client.Wait()
+		t.mu.Lock()
+		if t.client == client {
+			t.client = nil
+		}
+		t.mu.Unlock()
+	}()
+	return client, nil
+}
@@ -0,0 +1,241 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cron-runner/internal/config"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshForwarder is a minimal SSH server that accepts one client key and
+// serves "direct-tcpip" channels (what "ssh -L" and the tunnel use),
+// counting the connections it forwards.
+type sshForwarder struct {
+	addr      string
+	hostKey   ssh.PublicKey
+	forwarded atomic.Int32
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func startSSHForwarder(t *testing.T, clientKey ssh.PublicKey) *sshForwarder {
+	t.Helper()
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, io.EOF
+			}
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &sshForwarder{addr: ln.Addr().String(), hostKey: hostSigner.PublicKey()}
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		ln.Close()
+		f.mu.Lock()
+		for _, c := range f.conns {
+			c.Close()
+		}
+		f.mu.Unlock()
+		wg.Wait()
+	})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns = append(f.conns, conn)
+			f.mu.Unlock()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				f.serve(conn, cfg)
+			}()
+		}
+	}()
+	return f
+}
+
+func (f *sshForwarder) serve(conn net.Conn, cfg *ssh.ServerConfig) {
+	sc, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer sc.Close()
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "direct-tcpip" {
+			nc.Reject(ssh.UnknownChannelType, "only direct-tcpip")
+			continue
+		}
+		var target struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if err := ssh.Unmarshal(nc.ExtraData(), &target); err != nil {
+			nc.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		backend, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			nc.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		ch, chReqs, err := nc.Accept()
+		if err != nil {
+			backend.Close()
+			continue
+		}
+		f.forwarded.Add(1)
+		go ssh.DiscardRequests(chReqs)
+		go func() {
+			io.Copy(backend, ch)
+			backend.Close()
+		}()
+		go func() {
+			io.Copy(ch, backend)
+			ch.Close()
+		}()
+	}
+}
+
+func TestSSHTunnelCarriesBackendTraffic(t *testing.T) {
+	var hits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	dir := t.TempDir()
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(clientPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fwd := startSSHForwarder(t, sshPub)
+	knownHostsPath := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{fwd.addr}, fwd.hostKey) + "\n"
+	if err := os.WriteFile(knownHostsPath, []byte(line), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		BackendURL:          backend.URL,
+		PipelineAuth:        "test-token",
+		RequestTimeout:      5 * time.Second,
+		SSHTunnelHost:       fwd.addr,
+		SSHTunnelUser:       "runner",
+		SSHTunnelKeyFile:    keyPath,
+		SSHTunnelKnownHosts: knownHostsPath,
+	}
+	client, err := NewClient(cfg, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.httpClient.CloseIdleConnections()
+
+	result := client.TriggerEndpoint(context.Background(), "/v1/internal/alerts")
+	if !result.Success {
+		t.Fatalf("expected success through the tunnel, got %+v", result)
+	}
+	if hits.Load() != 1 || fwd.forwarded.Load() != 1 {
+		t.Fatalf("expected 1 request over 1 forwarded connection, got %d requests, %d forwarded", hits.Load(), fwd.forwarded.Load())
+	}
+}
+
+func TestSSHTunnelRejectsUnknownHostKey(t *testing.T) {
+	dir := t.TempDir()
+	clientPub, clientPriv, _ := ed25519.GenerateKey(rand.Reader)
+	block, _ := ssh.MarshalPrivateKey(clientPriv, "")
+	keyPath := filepath.Join(dir, "id_ed25519")
+	os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600)
+	sshPub, _ := ssh.NewPublicKey(clientPub)
+	fwd := startSSHForwarder(t, sshPub)
+
+	// known_hosts lists a different key for the server's address.
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	otherKey, _ := ssh.NewPublicKey(otherPub)
+	knownHostsPath := filepath.Join(dir, "known_hosts")
+	os.WriteFile(knownHostsPath, []byte(knownhosts.Line([]string{fwd.addr}, otherKey)+"\n"), 0o600)
+
+	tunnel, err := newSSHTunnel(&config.Config{
+		SSHTunnelHost:       fwd.addr,
+		SSHTunnelUser:       "runner",
+		SSHTunnelKeyFile:    keyPath,
+		SSHTunnelKnownHosts: knownHostsPath,
+		RequestTimeout:      5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tunnel.DialContext(context.Background(), "tcp", "127.0.0.1:1"); err == nil {
+		t.Fatal("expected the handshake to fail on a mismatched host key")
+	}
+	if fwd.forwarded.Load() != 0 {
+		t.Fatalf("expected nothing forwarded, got %d", fwd.forwarded.Load())
+	}
+}
+
+func TestSSHTunnelRequiresHostKeyVerification(t *testing.T) {
+	cfg := &config.Config{SSHTunnelHost: "bastion.invalid", SSHTunnelUser: "runner", SSHTunnelPassword: "pw"}
+	if _, err := newSSHTunnel(cfg); err == nil {
+		t.Fatal("expected an error without SSH_TUNNEL_KNOWN_HOSTS or SSH_TUNNEL_INSECURE_HOST_KEY")
+	}
+	cfg.SSHTunnelInsecureHostKey = true
+	if _, err := newSSHTunnel(cfg); err != nil {
+		t.Fatalf("expected SSH_TUNNEL_INSECURE_HOST_KEY to allow the tunnel: %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package pipeline
+
+import (
+	"net/http"
+	"time"
+)
+
+// maxStartupBackoff caps the doubling wait between startup check attempts.
+const maxStartupBackoff = 30 * time.Second
+
+// doStartupCheck sends req for a startup check (backend version, token
+// scope). Requests that never reach the backend, such as DNS failures or
+// refused connections while the pod's network comes up, are retried up to
+// STARTUP_CHECK_RETRIES times, waiting STARTUP_CHECK_BACKOFF and doubling.
+// Any HTTP response, even an error status, is returned as is.
+func (c *Client) doStartupCheck(req *http.Request) (*http.Response, error) {
+	backoff := c.startupBackoff
+	for attempt := 1; ; attempt++ {
+		resp, err := c.httpClient.Do(req)
+		if err == nil || attempt > c.startupRetries {
+			return resp, err
+		}
+		c.log.Warn().
+			Err(err).
+			Str("url", req.URL.String()).
+			Int("attempt", attempt).
+			Dur("backoff", backoff).
+			Msg("startup check could not reach backend, retrying")
+		if sleepCtx(req.Context(), backoff) != nil {
+			return nil, err
+		}
+		backoff = min(backoff*2, maxStartupBackoff)
+	}
+}
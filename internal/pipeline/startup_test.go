@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStartupCheckRetriesUntilDNSResolves(t *testing.T) {
+	var calls int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls <= 2 {
+			return nil, &net.DNSError{Err: "no such host", Name: req.URL.Hostname(), IsNotFound: true}
+		}
+		resp := jsonResponse(http.StatusOK, `{}`)
+		resp.Header.Set(BackendVersionHeader, "2.3.0")
+		return resp, nil
+	})
+	client := newTestClient("http://backend.test", transport)
+	client.startupRetries = 3
+	client.startupBackoff = time.Millisecond
+
+	if err := client.CheckBackendVersion(context.Background(), "/version", "2.0"); err != nil {
+		t.Fatalf("expected startup check to succeed once DNS resolves, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestStartupCheckGivesUpAfterRetries(t *testing.T) {
+	var calls int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return nil, &net.DNSError{Err: "no such host", Name: req.URL.Hostname(), IsNotFound: true}
+	})
+	client := newTestClient("http://backend.test", transport)
+	client.startupRetries = 2
+	client.startupBackoff = time.Millisecond
+
+	if err := client.CheckTokenScope(context.Background(), "/introspect", "pipelines:run"); err == nil {
+		t.Fatal("expected an error once retries are exhausted")
+	}
+	if calls != 3 {
+		t.Fatalf("expected 1 attempt plus 2 retries, got %d", calls)
+	}
+}
+
+func TestStartupCheckDoesNotRetryHTTPErrors(t *testing.T) {
+	var calls int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(http.StatusServiceUnavailable, `{}`), nil
+	})
+	client := newTestClient("http://backend.test", transport)
+	client.startupRetries = 3
+	client.startupBackoff = time.Millisecond
+
+	if err := client.CheckBackendVersion(context.Background(), "/version", "2.0"); err == nil {
+		t.Fatal("expected an error for a 503 version response")
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls)
+	}
+}
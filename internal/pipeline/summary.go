@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Summary returns a compact one-line description of the run for chatops,
+// e.g. "run abc123: success, 5/5 pipelines, 12.3s, 10k records".
+func (r TriggerResult) Summary() string {
+	id := r.JobID
+	if id == "" {
+		id = "-"
+	}
+
+	outcome := "success"
+	switch {
+	case r.Skipped:
+		outcome = "skipped"
+	case r.DryRun:
+		outcome = "dry_run"
+	case !r.Success:
+		outcome = "failure"
+	case r.Degraded:
+		outcome = "degraded"
+	}
+	parts := []string{outcome}
+
+	if js := r.JobDetails; js != nil {
+		p := fmt.Sprintf("%d/%d pipelines", js.PipelinesCompleted, js.PipelinesTotal)
+		if js.PipelinesFailed > 0 {
+			p += fmt.Sprintf(" (%d failed)", js.PipelinesFailed)
+		}
+		parts = append(parts, p)
+	}
+
+	parts = append(parts, fmt.Sprintf("%.1fs", r.Duration.Seconds()))
+
+	if js := r.JobDetails; js != nil && len(js.Results) > 0 {
+		records := 0
+		for _, pr := range js.Results {
+			records += pr.RecordsProcessed
+		}
+		parts = append(parts, shortCount(records)+" records")
+	}
+
+	if !r.Success && r.Error != nil {
+		parts = append(parts, "error: "+r.Error.Error())
+	}
+
+	return "run " + id + ": " + strings.Join(parts, ", ")
+}
+
+// Slowest returns the pipeline with the longest duration_seconds, the one
+// that dominated the run. Pipelines without a duration are ignored; ok is
+// false when none has one. Ties go to the first name in sort order, so the
+// answer does not depend on map iteration.
+func (js *JobStatus) Slowest() (name string, seconds float64, ok bool) {
+	if js == nil {
+		return "", 0, false
+	}
+	for n, pr := range js.Results {
+		d := float64(pr.DurationSeconds)
+		if d <= 0 {
+			continue
+		}
+		if !ok || d > seconds || (d == seconds && n < name) {
+			name, seconds, ok = n, d, true
+		}
+	}
+	return name, seconds, ok
+}
+
+// shortCount abbreviates n with a k/M suffix, truncated to one decimal
+// place: 950, 10k, 12.3k, 1.2M.
+func shortCount(n int) string {
+	var unit int
+	var suffix string
+	switch {
+	case n >= 1_000_000:
+		unit, suffix = 1_000_000, "M"
+	case n >= 1000:
+		unit, suffix = 1000, "k"
+	default:
+		return strconv.Itoa(n)
+	}
+	tenths := n * 10 / unit
+	if tenths%10 == 0 {
+		return strconv.Itoa(tenths/10) + suffix
+	}
+	return fmt.Sprintf("%d.%d%s", tenths/10, tenths%10, suffix)
+}
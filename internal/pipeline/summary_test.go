@@ -0,0 +1,125 @@
+package pipeline
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTriggerResultSummary(t *testing.T) {
+	tests := []struct {
+		name   string
+		result TriggerResult
+		want   string
+	}{
+		{
+			name: "success",
+			result: TriggerResult{
+				Success:  true,
+				JobID:    "abc123",
+				Duration: 12340 * time.Millisecond,
+				JobDetails: &JobStatus{
+					PipelinesTotal:     5,
+					PipelinesCompleted: 5,
+					Results: map[string]PipelineResult{
+						"games":   {RecordsProcessed: 4000},
+						"players": {RecordsProcessed: 6000},
+					},
+				},
+			},
+			want: "run abc123: success, 5/5 pipelines, 12.3s, 10k records",
+		},
+		{
+			name: "failure",
+			result: TriggerResult{
+				JobID:    "abc124",
+				Duration: 3 * time.Second,
+				Error:    errors.New("job ended with status failed"),
+				JobDetails: &JobStatus{
+					PipelinesTotal:     5,
+					PipelinesCompleted: 3,
+					PipelinesFailed:    2,
+					Results: map[string]PipelineResult{
+						"games": {RecordsProcessed: 1250},
+					},
+				},
+			},
+			want: "run abc124: failure, 3/5 pipelines (2 failed), 3.0s, 1.2k records, error: job ended with status failed",
+		},
+		{
+			name: "failure before a job was started",
+			result: TriggerResult{
+				Duration: 500 * time.Millisecond,
+				Error:    errors.New("unexpected status 500"),
+			},
+			want: "run -: failure, 0.5s, error: unexpected status 500",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.Summary(); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJobStatusSlowest(t *testing.T) {
+	tests := []struct {
+		name     string
+		results  map[string]PipelineResult
+		wantName string
+		wantSecs float64
+		wantOK   bool
+	}{
+		{
+			name: "varying durations",
+			results: map[string]PipelineResult{
+				"games":    {DurationSeconds: 12.5},
+				"players":  {DurationSeconds: 48.25},
+				"rankings": {DurationSeconds: 3},
+			},
+			wantName: "players", wantSecs: 48.25, wantOK: true,
+		},
+		{
+			name: "tie goes to first name",
+			results: map[string]PipelineResult{
+				"teams":   {DurationSeconds: 20},
+				"players": {DurationSeconds: 20},
+				"games":   {DurationSeconds: 5},
+			},
+			wantName: "players", wantSecs: 20, wantOK: true,
+		},
+		{
+			name: "missing durations ignored",
+			results: map[string]PipelineResult{
+				"games":   {},
+				"players": {DurationSeconds: 1.5},
+			},
+			wantName: "players", wantSecs: 1.5, wantOK: true,
+		},
+		{
+			name:    "no durations",
+			results: map[string]PipelineResult{"games": {}, "players": {}},
+		},
+		{
+			name: "no results",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js := &JobStatus{Results: tt.results}
+			for i := 0; i < 10; i++ { // map order varies between calls
+				name, secs, ok := js.Slowest()
+				if name != tt.wantName || secs != tt.wantSecs || ok != tt.wantOK {
+					t.Fatalf("expected (%q, %v, %v), got (%q, %v, %v)", tt.wantName, tt.wantSecs, tt.wantOK, name, secs, ok)
+				}
+			}
+		})
+	}
+
+	var js *JobStatus
+	if _, _, ok := js.Slowest(); ok {
+		t.Fatalf("expected no slowest pipeline without job details")
+	}
+}
@@ -0,0 +1,44 @@
+package pipeline
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// token returns the current bearer token.
+func (c *Client) token() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.authToken
+}
+
+// setAuth sets the bearer token on req. In session-auth mode there may be no
+// token; the session cookie authenticates the request instead.
+func (c *Client) setAuth(req *http.Request) {
+	if tok := c.token(); tok != "" {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+}
+
+// reloadToken re-reads the bearer token from the configured token file.
+func (c *Client) reloadToken() error {
+	b, err := os.ReadFile(c.tokenFile)
+	if err != nil {
+		return fmt.Errorf("failed to read token file: %w", err)
+	}
+	tok := strings.TrimSpace(string(b))
+	if tok == "" {
+		return fmt.Errorf("token file %s is empty", c.tokenFile)
+	}
+
+	c.tokenMu.Lock()
+	c.authToken = tok
+	c.tokenMu.Unlock()
+	return nil
+}
+
+func isAuthFailure(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
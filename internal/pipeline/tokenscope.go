@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// CheckTokenScope calls the backend's token introspection endpoint and fails
+// if the token is rejected, reported inactive, or lacks scope. Scopes are read
+// from a space-separated "scope" field or a "scopes" array, at the top level
+// or under "data".
+func (c *Client) CheckTokenScope(ctx context.Context, endpoint, scope string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpointURL(endpoint), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setAuth(req)
+
+	resp, err := c.doStartupCheck(req)
+	if err != nil {
+		return fmt.Errorf("failed to introspect token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read introspection response: %w", err)
+	}
+	if isAuthFailure(resp.StatusCode) {
+		return fmt.Errorf("token rejected by backend (status %d)", resp.StatusCode)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("introspection endpoint returned status %d: %s",
+			resp.StatusCode, truncateForLog(string(body), endpointErrorBodyMaxLen))
+	}
+
+	type claims struct {
+		Active *bool    `json:"active"`
+		Scope  string   `json:"scope"`
+		Scopes []string `json:"scopes"`
+	}
+	var v struct {
+		claims
+		Data claims `json:"data"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("failed to parse introspection response: %w", err)
+	}
+
+	active := v.Active
+	if active == nil {
+		active = v.Data.Active
+	}
+	if active != nil && !*active {
+		return fmt.Errorf("token is not active")
+	}
+
+	granted := append(strings.Fields(v.Scope), v.Scopes...)
+	granted = append(granted, strings.Fields(v.Data.Scope)...)
+	granted = append(granted, v.Data.Scopes...)
+	for _, g := range granted {
+		if g == scope {
+			c.log.Info().
+				Str("scope", scope).
+				Msg("token scope verified")
+			return nil
+		}
+	}
+	return fmt.Errorf("token lacks required scope %q (granted: %s)", scope, strings.Join(granted, " "))
+}
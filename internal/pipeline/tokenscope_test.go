@@ -0,0 +1,50 @@
+package pipeline
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckTokenScope(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "scope string", status: http.StatusOK, body: `{"active":true,"scope":"read pipelines:trigger"}`},
+		{name: "data scopes", status: http.StatusOK, body: `{"data":{"scopes":["pipelines:trigger"]}}`},
+		{name: "insufficient scope", status: http.StatusOK, body: `{"active":true,"scope":"read"}`, wantErr: "lacks required scope"},
+		{name: "inactive", status: http.StatusOK, body: `{"active":false}`, wantErr: "not active"},
+		{name: "rejected", status: http.StatusUnauthorized, body: `{}`, wantErr: "token rejected"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/v1/auth/whoami" {
+					t.Errorf("unexpected path %q", req.URL.Path)
+				}
+				if got := req.Header.Get("Authorization"); got != "Bearer test-token" {
+					t.Errorf("unexpected Authorization header %q", got)
+				}
+				return jsonResponse(tc.status, tc.body), nil
+			})
+
+			client := newTestClient("http://example.test", transport)
+			err := client.CheckTokenScope(context.Background(), "/v1/auth/whoami", "pipelines:trigger")
+
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected token to be accepted, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
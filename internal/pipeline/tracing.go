@@ -0,0 +1,55 @@
+package pipeline
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the run, start and poll spans. It is a no-op until a tracer
+// provider is installed (see internal/tracing); each request attempt gets its
+// own span in internal/retry.
+var tracer = otel.Tracer("cron-runner/internal/pipeline")
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// endRunSpan annotates a TriggerAll span with the run's outcome and ends it.
+func endRunSpan(span trace.Span, result TriggerResult) {
+	status := "success"
+	switch {
+	case result.Degraded:
+		status = "degraded"
+	case !result.Success:
+		status = "failure"
+	}
+	span.SetAttributes(
+		attribute.String("job.id", result.JobID),
+		attribute.Int("attempts", result.Attempts),
+		attribute.String("run.status", status),
+	)
+	if result.JobDetails != nil {
+		span.SetAttributes(attribute.String("job.status", result.JobDetails.Status))
+	}
+	if result.Error == nil && !result.Success {
+		span.SetStatus(codes.Error, "pipeline job failed")
+	}
+	endSpan(span, result.Error)
+}
+
+// injectTraceContext adds the trace-context headers for ctx's span to req,
+// for requests sent outside retry.Do.
+func injectTraceContext(ctx context.Context, req *http.Request) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+}
@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTriggerAllRecordsSpans(t *testing.T) {
+	// The package tracers delegate to the first provider installed, so this
+	// is the only test that installs one.
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var posts int
+	var traceparents []string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		traceparents = append(traceparents, req.Header.Get("traceparent"))
+		if req.Method == http.MethodPost {
+			posts++
+			if posts == 1 {
+				return jsonResponse(http.StatusBadGateway, "busy"), nil
+			}
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"job_id":"job-1","status":"completed"}}`), nil
+	})
+	client := newTestClient("http://example.test", transport)
+	client.retryCfg.MaxRetries = 1
+
+	if result := client.TriggerAll(context.Background(), "/v1/internal/pipelines/post-game"); !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+
+	for i, tp := range traceparents {
+		if tp == "" {
+			t.Errorf("request %d: expected a traceparent header", i+1)
+		}
+	}
+
+	spans := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, s := range rec.Ended() {
+		spans[s.Name()] = append(spans[s.Name()], s)
+	}
+	if n := len(spans["retry.attempt"]); n != 2 {
+		t.Fatalf("expected 2 attempt spans, got %d", n)
+	}
+	for _, name := range []string{"pipeline.trigger_all", "pipeline.start_job", "pipeline.poll"} {
+		if len(spans[name]) != 1 {
+			t.Fatalf("expected one ended %s span, got %d", name, len(spans[name]))
+		}
+	}
+
+	root := spans["pipeline.trigger_all"][0]
+	want := map[attribute.Key]attribute.Value{
+		"job.id":     attribute.StringValue("job-1"),
+		"attempts":   attribute.IntValue(2),
+		"run.status": attribute.StringValue("success"),
+		"job.status": attribute.StringValue("completed"),
+	}
+	got := make(map[attribute.Key]attribute.Value)
+	for _, kv := range root.Attributes() {
+		got[kv.Key] = kv.Value
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("attribute %s: expected %v, got %v", k, v.Emit(), got[k].Emit())
+		}
+	}
+	for _, s := range rec.Ended() {
+		if s.Name() != "pipeline.trigger_all" && s.Parent().TraceID() != root.SpanContext().TraceID() {
+			t.Errorf("span %s is not in the run's trace", s.Name())
+		}
+	}
+
+	// An error path still ends the run span, marked as an error.
+	failing := newTestClient("http://example.test", roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusBadRequest, "bad"), nil
+	}))
+	rec2 := len(rec.Ended())
+	failing.TriggerAll(context.Background(), "/v1/internal/pipelines/post-game")
+	var failed sdktrace.ReadOnlySpan
+	for _, s := range rec.Ended()[rec2:] {
+		if s.Name() == "pipeline.trigger_all" {
+			failed = s
+		}
+	}
+	if failed == nil || failed.Status().Code != codes.Error {
+		t.Fatalf("expected an ended error run span, got %v", failed)
+	}
+}
@@ -0,0 +1,135 @@
+package pipeline
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"cron-runner/internal/config"
+)
+
+// newTransport builds the HTTP transport used for all backend requests.
+// It starts from http.DefaultTransport, so HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY are honored and idle connections are pooled (MaxIdleConns 100,
+// IdleConnTimeout 90s); PIPELINE_PROXY_URL sends every request through one
+// proxy instead, and SSH_TUNNEL_HOST dials every connection through an
+// SSH tunnel. An error loading the mTLS or CA files (already checked by
+// config.Validate), parsing the proxy URL or setting up the tunnel is
+// returned rather than connecting without them.
+func newTransport(cfg *config.Config) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.ProxyURL != "" {
+		u, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	t.TLSClientConfig.MinVersion = tlsMinVersion(cfg.TLSMinVersion)
+	t.TLSClientConfig.InsecureSkipVerify = cfg.TLSInsecureSkipVerify
+	t.DisableKeepAlives = cfg.DisableKeepAlives
+	if err := loadTLSFiles(t.TLSClientConfig, cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.SSHTunnelHost != "" {
+		tunnel, err := newSSHTunnel(cfg)
+		if err != nil {
+			return nil, err
+		}
+		t.DialContext = tunnel.DialContext
+	}
+
+	if len(cfg.TLSSkipVerifyHosts) > 0 && !t.TLSClientConfig.InsecureSkipVerify {
+		skipVerifyForHosts(t.TLSClientConfig, cfg.TLSSkipVerifyHosts)
+	}
+
+	return t, nil
+}
+
+// loadTLSFiles adds the client certificate from TLS_CLIENT_CERT and
+// TLS_CLIENT_KEY and the CAs from TLS_CA_CERT to tc. The CAs are trusted in
+// addition to the system roots, so public backends keep verifying.
+func loadTLSFiles(tc *tls.Config, cfg *config.Config) error {
+	if cfg.TLSClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSClientCert, cfg.TLSClientKey)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.TLSCACert != "" {
+		pem, err := os.ReadFile(cfg.TLSCACert)
+		if err != nil {
+			return fmt.Errorf("failed to read TLS CA certificate: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("TLS CA certificate %s contains no PEM certificates", cfg.TLSCACert)
+		}
+		tc.RootCAs = pool
+	}
+	return nil
+}
+
+// tlsMinVersion maps TLS_MIN_VERSION to a crypto/tls version, defaulting to TLS 1.2.
+func tlsMinVersion(v string) uint16 {
+	if v == "1.3" {
+		return tls.VersionTLS13
+	}
+	return tls.VersionTLS12
+}
+
+// skipVerifyForHosts makes tc skip certificate verification only for
+// servers named in skipHosts; every other server is verified as usual
+// against tc.RootCAs. The check is keyed on the server name (SNI) the
+// handshake was made for, so it holds through proxies and keeps HTTP/2
+// available. A server addressed by IP sends no server name, so it cannot be
+// matched or verified this way and is refused; config.Validate rejects IP
+// addresses in skipHosts for the same reason.
+func skipVerifyForHosts(tc *tls.Config, skipHosts []string) {
+	skip := make(map[string]bool, len(skipHosts))
+	for _, h := range skipHosts {
+		skip[strings.ToLower(h)] = true
+	}
+
+	// Standard verification is replaced by VerifyConnection below, which
+	// redoes it for every host not on the list.
+	tc.InsecureSkipVerify = true
+	tc.VerifyConnection = func(cs tls.ConnectionState) error {
+		if cs.ServerName == "" {
+			return errors.New("tls: cannot verify a server addressed by IP address while TLS_SKIP_VERIFY_HOSTS is set")
+		}
+		if skip[strings.ToLower(cs.ServerName)] {
+			return nil
+		}
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("tls: server presented no certificate")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         tc.RootCAs,
+			DNSName:       cs.ServerName,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+			return &tls.CertificateVerificationError{UnverifiedCertificates: cs.PeerCertificates, Err: err}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,244 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cron-runner/internal/config"
+
+	"github.com/rs/zerolog"
+)
+
+func TestTLSSkipVerifyHostsOnlySkipsListedHost(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	// Every host name resolves to the test server.
+	tr := mustTransport(t, &config.Config{TLSSkipVerifyHosts: []string{"backend.internal"}})
+	dialer := &net.Dialer{}
+	tr.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	client := &http.Client{Timeout: time.Second, Transport: tr}
+	u, _ := url.Parse(srv.URL)
+
+	resp, err := client.Get("https://backend.internal:" + u.Port())
+	if err != nil {
+		t.Fatalf("expected listed host to skip verification, got: %v", err)
+	}
+	resp.Body.Close()
+	if proto := resp.Header.Get("X-Proto"); proto != "HTTP/2.0" {
+		t.Fatalf("expected HTTP/2 to stay available, got %s", proto)
+	}
+
+	for _, host := range []string{"other.internal", "127.0.0.1"} {
+		_, err = client.Get("https://" + host + ":" + u.Port())
+		if err == nil {
+			t.Fatalf("expected unlisted host %s to fail verification", host)
+		}
+		if !strings.Contains(err.Error(), "certificate") && !strings.Contains(err.Error(), "IP address") {
+			t.Fatalf("expected a verification error for %s, got: %v", host, err)
+		}
+	}
+}
+
+func TestTLSMinVersionRefusesOlderServer(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS11}
+	srv.StartTLS()
+	defer srv.Close()
+
+	client := &http.Client{
+		Timeout: time.Second,
+		Transport: mustTransport(t, &config.Config{
+			TLSMinVersion:      "1.2",
+			TLSSkipVerifyHosts: []string{"127.0.0.1"},
+		}),
+	}
+
+	_, err := client.Get(srv.URL)
+	if err == nil {
+		t.Fatalf("expected handshake below the minimum TLS version to be refused")
+	}
+	if !strings.Contains(err.Error(), "protocol version") {
+		t.Fatalf("expected protocol version error, got: %v", err)
+	}
+}
+
+func TestDisableKeepAlivesFromConfig(t *testing.T) {
+	if mustTransport(t, &config.Config{}).DisableKeepAlives {
+		t.Fatalf("expected keep-alives enabled by default")
+	}
+	if !mustTransport(t, &config.Config{DisableKeepAlives: true}).DisableKeepAlives {
+		t.Fatalf("expected DisableKeepAlives set from config")
+	}
+}
+
+func mustTransport(t *testing.T, cfg *config.Config) *http.Transport {
+	t.Helper()
+	tr, err := newTransport(cfg)
+	if err != nil {
+		t.Fatalf("newTransport: %v", err)
+	}
+	return tr
+}
+
+// writeClientCert writes a self-signed client certificate and key to dir
+// and returns their paths and the certificate, to act as its own CA.
+func writeClientCert(t *testing.T, dir string) (certPath, keyPath string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "cron-runner"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath, keyPath = filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	cert, _ = x509.ParseCertificate(der)
+	return certPath, keyPath, cert
+}
+
+func TestMutualTLSWithPrivateCA(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath, clientCert := writeClientCert(t, dir)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+
+	// The test server's certificate stands in for the private CA.
+	caPath := filepath.Join(dir, "ca.crt")
+	os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600)
+
+	client := &http.Client{Timeout: time.Second, Transport: mustTransport(t, &config.Config{
+		TLSClientCert: certPath,
+		TLSClientKey:  keyPath,
+		TLSCACert:     caPath,
+	})}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("expected mTLS request to succeed, got: %v", err)
+	}
+	resp.Body.Close()
+
+	// Without the client certificate the server rejects the handshake.
+	client.Transport = mustTransport(t, &config.Config{TLSCACert: caPath})
+	if resp, err := client.Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Fatalf("expected handshake without a client certificate to fail")
+	}
+
+	// Without the CA the server certificate is not trusted.
+	client.Transport = mustTransport(t, &config.Config{TLSClientCert: certPath, TLSClientKey: keyPath})
+	if _, err := client.Get(srv.URL); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("expected certificate error without the CA, got: %v", err)
+	}
+}
+
+func TestInsecureSkipVerifyAppliesToAllHosts(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	for _, cfg := range []*config.Config{
+		{TLSInsecureSkipVerify: true},
+		{TLSInsecureSkipVerify: true, TLSSkipVerifyHosts: []string{"other.example"}},
+	} {
+		client := &http.Client{Timeout: time.Second, Transport: mustTransport(t, cfg)}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("expected unverified request to succeed, got: %v", err)
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestProxyURLRoutesRequestsThroughProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.Method+" "+r.URL.String())
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer proxy.Close()
+
+	client := &http.Client{Timeout: time.Second, Transport: mustTransport(t, &config.Config{ProxyURL: proxy.URL})}
+	resp, err := client.Post("http://backend.invalid/v1/internal/pipelines/post-game", "application/json", nil)
+	if err != nil {
+		t.Fatalf("expected request via proxy to succeed, got: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected the proxy's 202, got %d", resp.StatusCode)
+	}
+	if len(proxied) != 1 || proxied[0] != "POST http://backend.invalid/v1/internal/pipelines/post-game" {
+		t.Fatalf("expected one proxied request for the backend URL, got %v", proxied)
+	}
+
+	if mustTransport(t, &config.Config{}).Proxy == nil {
+		t.Fatalf("expected HTTP_PROXY/HTTPS_PROXY/NO_PROXY to be honored by default")
+	}
+}
+
+func TestNewClientFailsWhenTransportSettingsCannotBeApplied(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	for name, cfg := range map[string]*config.Config{
+		"proxy":  {ProxyURL: "http://proxy.invalid:bad-port"},
+		"ca":     {TLSCACert: missing},
+		"tunnel": {SSHTunnelHost: "bastion.invalid", SSHTunnelUser: "runner", SSHTunnelKeyFile: missing, SSHTunnelKnownHosts: missing},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg.BackendURL = "http://example.test"
+			if client, err := NewClient(cfg, zerolog.Nop()); err == nil || client != nil {
+				t.Fatalf("expected NewClient to fail rather than connect without the settings")
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// BackendVersionHeader is checked before the response body for the backend version.
+const BackendVersionHeader = "X-Backend-Version"
+
+// CheckBackendVersion fetches the backend's version from endpoint and fails
+// if it is older than minVersion. The version is read from the
+// X-Backend-Version header, or a "version" (or "data.version") body field.
+func (c *Client) CheckBackendVersion(ctx context.Context, endpoint, minVersion string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpointURL(endpoint), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setAuth(req)
+
+	resp, err := c.doStartupCheck(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch backend version: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read version response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("version endpoint returned status %d: %s",
+			resp.StatusCode, truncateForLog(string(body), endpointErrorBodyMaxLen))
+	}
+
+	version := resp.Header.Get(BackendVersionHeader)
+	if version == "" {
+		var v struct {
+			Version string `json:"version"`
+			Data    struct {
+				Version string `json:"version"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &v); err == nil {
+			version = v.Version
+			if version == "" {
+				version = v.Data.Version
+			}
+		}
+	}
+	if version == "" {
+		return fmt.Errorf("backend did not report a version")
+	}
+
+	cmp, err := compareVersions(version, minVersion)
+	if err != nil {
+		return err
+	}
+	if cmp < 0 {
+		return fmt.Errorf("backend version %s is older than the minimum supported %s", version, minVersion)
+	}
+
+	c.log.Info().
+		Str("backend_version", version).
+		Str("min_version", minVersion).
+		Msg("backend version supported")
+	return nil
+}
+
+// compareVersions compares dotted numeric versions ("v1.4.2", "2.0"),
+// ignoring any pre-release or build suffix. Missing components count as 0.
+func compareVersions(a, b string) (int, error) {
+	pa, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	pb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func parseVersion(v string) ([]int, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	out := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", v)
+		}
+		out[i] = n
+	}
+	return out, nil
+}
@@ -0,0 +1,51 @@
+package pipeline
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckBackendVersion(t *testing.T) {
+	cases := []struct {
+		name    string
+		header  string
+		body    string
+		wantErr string
+	}{
+		{name: "header compatible", header: "2.3.0", body: `{}`},
+		{name: "body compatible", body: `{"version":"v2.10"}`},
+		{name: "data body compatible", body: `{"data":{"version":"3.0.0-rc1"}}`},
+		{name: "incompatible", header: "2.2.9", body: `{}`, wantErr: "older than the minimum"},
+		{name: "missing", body: `{}`, wantErr: "did not report a version"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/version" {
+					t.Errorf("unexpected path %q", req.URL.Path)
+				}
+				resp := jsonResponse(http.StatusOK, tc.body)
+				if tc.header != "" {
+					resp.Header.Set(BackendVersionHeader, tc.header)
+				}
+				return resp, nil
+			})
+
+			client := newTestClient("http://example.test", transport)
+			err := client.CheckBackendVersion(context.Background(), "/version", "2.3")
+
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected compatible version, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
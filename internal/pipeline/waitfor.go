@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// defaultWaitForInterval is the delay between dependency readiness polls.
+const defaultWaitForInterval = 2 * time.Second
+
+// ErrDependencyNotReady is wrapped by a trigger's error when WAIT_FOR_URL
+// did not answer 2xx within WAIT_FOR_TIMEOUT.
+var ErrDependencyNotReady = errors.New("dependency not ready")
+
+// waitForDependency polls c.waitForURL with GET until it answers 2xx, or
+// fails once c.waitForTimeout has elapsed. It is a no-op when no dependency
+// is configured. The dependency is probed with a plain HTTP client: none of
+// the backend's token, session, proxy, TLS or tunnel settings apply to it.
+func (c *Client) waitForDependency(ctx context.Context, log zerolog.Logger) error {
+	if c.waitForURL == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.waitForTimeout)
+	defer cancel()
+
+	start := time.Now()
+	for polls := 1; ; polls++ {
+		status, err := c.checkDependency(ctx)
+		if err == nil && status >= 200 && status < 300 {
+			log.Info().
+				Str("url", c.waitForURL).
+				Int("polls", polls).
+				Dur("waited", time.Since(start)).
+				Msg("dependency ready")
+			return nil
+		}
+
+		ev := log.Debug().Str("url", c.waitForURL).Int("polls", polls)
+		if err != nil {
+			ev = ev.Err(err)
+		} else {
+			ev = ev.Int("status", status)
+		}
+		ev.Msg("dependency not ready yet")
+
+		if sleepCtx(ctx, c.waitForInterval) != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				last := fmt.Sprintf("status %d", status)
+				if err != nil {
+					last = err.Error()
+				}
+				log.Error().
+					Str("url", c.waitForURL).
+					Int("polls", polls).
+					Dur("timeout", c.waitForTimeout).
+					Str("last_result", last).
+					Msg("dependency not ready before timeout")
+				return fmt.Errorf("%w: %s after %s (last: %s)", ErrDependencyNotReady, c.waitForURL, c.waitForTimeout, last)
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// checkDependency sends one readiness GET and returns its status code.
+func (c *Client) checkDependency(ctx context.Context) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.waitForURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.waitForClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,84 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTriggerAllWaitsForDependency(t *testing.T) {
+	var depPolls int
+	var started bool
+	dep := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Authorization") != "" {
+			t.Errorf("backend token sent to dependency")
+		}
+		depPolls++
+		if depPolls < 3 {
+			return jsonResponse(http.StatusServiceUnavailable, "starting"), nil
+		}
+		return jsonResponse(http.StatusOK, "ok"), nil
+	})
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case req.URL.Host == "dep.test":
+			t.Errorf("dependency probed through the backend transport")
+			return nil, errors.New("unexpected dependency request")
+		case req.Method == http.MethodPost:
+			if depPolls < 3 {
+				t.Errorf("job started before dependency was ready (polls=%d)", depPolls)
+			}
+			started = true
+			return jsonResponse(http.StatusAccepted, `{"data":{"job_id":"job-1"}}`), nil
+		default:
+			return jsonResponse(http.StatusOK, `{"data":{"status":"completed","pipelines_total":1,"pipelines_completed":1}}`), nil
+		}
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.waitForClient = &http.Client{Transport: dep}
+	client.waitForURL = "http://dep.test/healthz"
+	client.waitForTimeout = time.Second
+	client.waitForInterval = time.Millisecond
+
+	result := client.TriggerAll(context.Background(), "/v1/internal/pipelines/live-stats")
+	if !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+	if depPolls != 3 {
+		t.Fatalf("expected 3 dependency polls, got %d", depPolls)
+	}
+	if !started {
+		t.Fatal("expected job to be started")
+	}
+}
+
+func TestTriggerEndpointFailsWhenDependencyNeverReady(t *testing.T) {
+	var posted bool
+	dep := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusServiceUnavailable, "down"), nil
+	})
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		posted = true
+		return jsonResponse(http.StatusOK, "ok"), nil
+	})
+
+	client := newTestClient("http://example.test", transport)
+	client.waitForClient = &http.Client{Transport: dep}
+	client.waitForURL = "http://dep.test/healthz"
+	client.waitForTimeout = 20 * time.Millisecond
+	client.waitForInterval = time.Millisecond
+
+	result := client.TriggerEndpoint(context.Background(), "/v1/internal/alerts")
+	if result.Success {
+		t.Fatal("expected failure when dependency is not ready")
+	}
+	if !errors.Is(result.Error, ErrDependencyNotReady) {
+		t.Fatalf("expected ErrDependencyNotReady, got %v", result.Error)
+	}
+	if posted {
+		t.Fatal("expected no trigger to be sent")
+	}
+}
@@ -0,0 +1,79 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// maskPrefixLen is how many leading characters Mask keeps; secrets shorter
+// than twice this show no prefix at all.
+const maskPrefixLen = 4
+
+// Mask hides a secret for logging, keeping only a short prefix and the
+// length so two values can still be told apart: "s3cr…(24 chars)".
+func Mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	prefix := ""
+	if len(secret) >= 2*maskPrefixLen {
+		prefix = secret[:maskPrefixLen]
+	}
+	return fmt.Sprintf("%s…(%d chars)", prefix, len(secret))
+}
+
+// stringFieldRE matches a JSON string field at any nesting depth,
+// capturing the key and the raw value.
+var stringFieldRE = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"\s*:\s*"((?:[^"\\]|\\.)*)"`)
+
+// scrubWriter masks secrets in JSON log lines before passing them on:
+// every string field whose key is on the denylist ("authorization" and
+// "token" at least), and every literal occurrence of a known secret value
+// wherever it appears.
+type scrubWriter struct {
+	out     io.Writer
+	deny    *Denylist
+	secrets [][]byte
+	masked  [][]byte
+}
+
+// NewWriter returns a writer that masks secrets in the JSON lines written
+// to it, for the logger and other streams of JSON lines such as run events.
+// Each Write must hold whole lines.
+func NewWriter(out io.Writer, deny *Denylist, secrets ...string) io.Writer {
+	w := &scrubWriter{out: out, deny: deny}
+	for _, s := range secrets {
+		if s == "" {
+			continue
+		}
+		w.secrets = append(w.secrets, []byte(s))
+		w.masked = append(w.masked, []byte(Mask(s)))
+	}
+	return w
+}
+
+func (w *scrubWriter) Write(p []byte) (int, error) {
+	line := stringFieldRE.ReplaceAllFunc(p, func(m []byte) []byte {
+		sub := stringFieldRE.FindSubmatch(m)
+		if !w.deny.Match(string(sub[1])) {
+			return m
+		}
+		value, err := strconv.Unquote(`"` + string(sub[2]) + `"`)
+		if err != nil {
+			value = string(sub[2])
+		}
+		masked, _ := json.Marshal(Mask(value))
+		return append(append(append([]byte{'"'}, sub[1]...), `":`...), masked...)
+	})
+	for i, s := range w.secrets {
+		line = bytes.ReplaceAll(line, s, w.masked[i])
+	}
+	if _, err := w.out.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
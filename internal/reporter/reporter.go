@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -18,33 +19,44 @@ const (
 
 // RunReport is the payload sent to data-platform after a cron job executes.
 type RunReport struct {
-	JobName         string    `json:"job_name"`
-	TriggeredAt     time.Time `json:"triggered_at"`
-	CompletedAt     time.Time `json:"completed_at"`
-	DurationMs      int64     `json:"duration_ms"`
-	Result          string    `json:"result"` // "success" | "failure"
-	HTTPStatus      *int      `json:"http_status,omitempty"`
-	Attempts        int       `json:"attempts"`
-	ErrorMessage    *string   `json:"error_message,omitempty"`
-	ResponseSnippet *string   `json:"response_snippet,omitempty"`
+	JobName         string            `json:"job_name"`
+	TriggeredAt     time.Time         `json:"triggered_at"`
+	CompletedAt     time.Time         `json:"completed_at"`
+	DurationMs      int64             `json:"duration_ms"`
+	Result          string            `json:"result"` // "success" | "failure"
+	HTTPStatus      *int              `json:"http_status,omitempty"`
+	Attempts        int               `json:"attempts"`
+	ErrorMessage    *string           `json:"error_message,omitempty"`
+	ResponseSnippet *string           `json:"response_snippet,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
 }
 
 // Reporter sends job execution reports to the data-platform after each trigger.
 // All calls are fire-and-forget — failures are logged but never block job execution.
 type Reporter struct {
-	url    string
-	token  string
-	client *http.Client
-	log    zerolog.Logger
+	url      string
+	token    string
+	metadata map[string]string // attached to every report; may be nil
+	client   *http.Client
+	log      zerolog.Logger
 }
 
-// New creates a Reporter that POSTs to baseURL/v1/internal/cron/job-runs.
-func New(baseURL, token string, log zerolog.Logger) *Reporter {
+// New creates a Reporter that POSTs to baseURL/basePath/v1/internal/cron/job-runs.
+// metadata (e.g. trigger source and commit) is included in every report.
+// Reports are sent through transport, or http.DefaultTransport when nil; an
+// empty token sends no Authorization header, for transports that
+// authenticate requests themselves.
+func New(baseURL, basePath, token string, transport http.RoundTripper, metadata map[string]string, log zerolog.Logger) *Reporter {
+	u, err := url.JoinPath(baseURL, basePath, "/v1/internal/cron/job-runs")
+	if err != nil {
+		u = baseURL + basePath + "/v1/internal/cron/job-runs"
+	}
 	return &Reporter{
-		url:    baseURL + "/v1/internal/cron/job-runs",
-		token:  token,
-		client: &http.Client{Timeout: reportTimeout},
-		log:    log.With().Str("component", "reporter").Logger(),
+		url:      u,
+		token:    token,
+		metadata: metadata,
+		client:   &http.Client{Timeout: reportTimeout, Transport: transport},
+		log:      log.With().Str("component", "reporter").Logger(),
 	}
 }
 
@@ -72,6 +84,7 @@ func (r *Reporter) Report(jobName string, triggeredAt time.Time, completedAt tim
 		Attempts:        attempts,
 		ErrorMessage:    errMsg,
 		ResponseSnippet: snippet,
+		Metadata:        r.metadata,
 	}
 
 	go r.send(report)
@@ -93,7 +106,9 @@ func (r *Reporter) send(report RunReport) {
 		return
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", r.token))
+	if r.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", r.token))
+	}
 
 	resp, err := r.client.Do(req)
 	if err != nil {
@@ -0,0 +1,66 @@
+package reporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestReportIncludesMetadata(t *testing.T) {
+	got := make(chan RunReport, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report RunReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Errorf("invalid report body: %v", err)
+		}
+		got <- report
+	}))
+	defer srv.Close()
+
+	md := map[string]string{"trigger_source": "ci", "trigger_commit": "abc123"}
+	rep := New(srv.URL, "", "token", nil, md, zerolog.Nop())
+
+	now := time.Now()
+	rep.Report("pre-game", now, now, 0, "success", nil, 1, nil, "")
+
+	select {
+	case report := <-got:
+		if report.Metadata["trigger_source"] != "ci" || report.Metadata["trigger_commit"] != "abc123" {
+			t.Fatalf("expected metadata in report, got %v", report.Metadata)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected report to be sent")
+	}
+}
+
+func TestReportUsesTransportWithoutToken(t *testing.T) {
+	got := make(chan *http.Request, 1)
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got <- req
+		return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody}, nil
+	})
+
+	rep := New("http://example.test", "/api", "", transport, nil, zerolog.Nop())
+	now := time.Now()
+	rep.Report("pre-game", now, now, 0, "success", nil, 1, nil, "")
+
+	select {
+	case req := <-got:
+		if req.URL.String() != "http://example.test/api/v1/internal/cron/job-runs" {
+			t.Fatalf("unexpected report URL %s", req.URL)
+		}
+		if auth := req.Header.Get("Authorization"); auth != "" {
+			t.Fatalf("expected no Authorization header without a token, got %q", auth)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected report to go through the transport")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
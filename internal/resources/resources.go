@@ -0,0 +1,76 @@
+// Package resources checks that the node has room for a run before one is
+// started, so heavy runs are not launched on a resource-starved node.
+package resources
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Limits are the thresholds a run must clear. A zero limit is not checked.
+type Limits struct {
+	DiskPath    string // filesystem whose free space is checked
+	MinDiskFree uint64 // bytes that must be available at DiskPath
+	MaxMemory   uint64 // bytes the process may hold from the OS
+}
+
+// Probe reads the current resource levels.
+type Probe interface {
+	// DiskFree returns the bytes available to unprivileged users at path.
+	DiskFree(path string) (uint64, error)
+	// Memory returns the bytes of memory the process holds from the OS.
+	Memory() uint64
+}
+
+// Checker rejects runs while a limit is exceeded. A nil Checker allows
+// every run.
+type Checker struct {
+	limits Limits
+	probe  Probe
+}
+
+// New returns a Checker for l using the real system probe, or nil when no
+// limit is set.
+func New(l Limits) *Checker {
+	return NewWithProbe(l, systemProbe{})
+}
+
+// NewWithProbe is New with the resource levels read from p.
+func NewWithProbe(l Limits, p Probe) *Checker {
+	if l.MinDiskFree == 0 && l.MaxMemory == 0 {
+		return nil
+	}
+	return &Checker{limits: l, probe: p}
+}
+
+// Check returns an error describing the first limit that is exceeded, or
+// nil when a run may start. A disk that cannot be read counts as exceeded.
+func (c *Checker) Check() error {
+	if c == nil {
+		return nil
+	}
+	if need := c.limits.MinDiskFree; need > 0 {
+		free, err := c.probe.DiskFree(c.limits.DiskPath)
+		if err != nil {
+			return fmt.Errorf("checking free disk at %s: %w", c.limits.DiskPath, err)
+		}
+		if free < need {
+			return fmt.Errorf("free disk at %s is %d MB, below the %d MB minimum",
+				c.limits.DiskPath, free>>20, need>>20)
+		}
+	}
+	if limit := c.limits.MaxMemory; limit > 0 {
+		if used := c.probe.Memory(); used > limit {
+			return fmt.Errorf("process memory is %d MB, above the %d MB limit", used>>20, limit>>20)
+		}
+	}
+	return nil
+}
+
+type systemProbe struct{}
+
+func (systemProbe) Memory() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.Sys
+}
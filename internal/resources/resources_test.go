@@ -0,0 +1,65 @@
+package resources
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type fakeProbe struct {
+	disk    uint64
+	diskErr error
+	mem     uint64
+}
+
+func (p fakeProbe) DiskFree(string) (uint64, error) { return p.disk, p.diskErr }
+func (p fakeProbe) Memory() uint64                  { return p.mem }
+
+func TestCheck(t *testing.T) {
+	const mb = 1 << 20
+	limits := Limits{DiskPath: "/data", MinDiskFree: 100 * mb, MaxMemory: 512 * mb}
+	tests := []struct {
+		name    string
+		probe   fakeProbe
+		wantErr string
+	}{
+		{"within limits", fakeProbe{disk: 200 * mb, mem: 256 * mb}, ""},
+		{"low disk", fakeProbe{disk: 50 * mb, mem: 256 * mb}, "free disk at /data is 50 MB, below the 100 MB minimum"},
+		{"high memory", fakeProbe{disk: 200 * mb, mem: 600 * mb}, "process memory is 600 MB, above the 512 MB limit"},
+		{"unreadable disk", fakeProbe{diskErr: errors.New("no such file"), mem: 256 * mb}, "checking free disk at /data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewWithProbe(limits, tt.probe).Check()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNoLimitsDisablesChecks(t *testing.T) {
+	c := NewWithProbe(Limits{DiskPath: "/"}, fakeProbe{mem: 1 << 40})
+	if c != nil {
+		t.Fatalf("expected nil checker without limits")
+	}
+	if err := c.Check(); err != nil {
+		t.Fatalf("nil checker should allow runs, got %v", err)
+	}
+}
+
+func TestSystemProbeReadsDisk(t *testing.T) {
+	free, err := systemProbe{}.DiskFree(t.TempDir())
+	if err != nil {
+		t.Skipf("statfs unavailable: %v", err)
+	}
+	if free == 0 {
+		t.Fatalf("expected some free disk in the temp dir")
+	}
+}
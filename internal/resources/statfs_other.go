@@ -0,0 +1,9 @@
+//go:build !unix
+
+package resources
+
+import "errors"
+
+func (systemProbe) DiskFree(path string) (uint64, error) {
+	return 0, errors.New("disk checks are not supported on this platform")
+}
@@ -2,12 +2,22 @@ package retry
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Config holds retry configuration.
@@ -16,8 +26,74 @@ type Config struct {
 	InitialBackoff time.Duration
 	MaxBackoff     time.Duration
 	BackoffFactor  float64
+
+	// Jitter randomizes each computed backoff down by up to this fraction
+	// (0.0–1.0), so instances retrying together spread out. Retry-After
+	// waits are never jittered.
+	Jitter float64
+
+	// MaxElapsedTime bounds the whole retry loop by wall-clock time: a retry
+	// whose backoff would end past this budget (measured from the first
+	// attempt) is not made. 0 = no budget, only MaxRetries applies.
+	MaxElapsedTime time.Duration
+
+	// RetryableStatusCodes, when non-empty, replaces the default retryable
+	// status logic of IsRetryable: only these codes are retried. Network
+	// errors are retried either way.
+	RetryableStatusCodes []int
+
+	// IdempotentOnly refuses to retry requests that are not safe to repeat:
+	// anything other than GET/HEAD without an Idempotency-Key header.
+	IdempotentOnly bool
+
+	// IdempotencyHeader names the header that marks a request as safe to
+	// retry under IdempotentOnly. "" = IdempotencyKeyHeader.
+	IdempotencyHeader string
+
+	// BackoffStrategy selects how backoffs are computed. The zero value is
+	// StrategyExponential.
+	BackoffStrategy Strategy
 }
 
+// Strategy is a backoff algorithm.
+type Strategy string
+
+const (
+	// StrategyExponential grows the backoff by BackoffFactor per attempt,
+	// randomized down by Jitter.
+	StrategyExponential Strategy = "exponential"
+
+	// StrategyDecorrelated is AWS's "decorrelated jitter": each backoff is
+	// drawn uniformly from [InitialBackoff, 3*previous backoff], capped at
+	// MaxBackoff. Jitter and BackoffFactor are not used.
+	StrategyDecorrelated Strategy = "decorrelated"
+)
+
+// IdempotencyKeyHeader marks a non-GET request as safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// CanRepeat reports whether cfg allows req to be sent more than once: always,
+// unless IdempotentOnly is set and req is not idempotent.
+func (cfg Config) CanRepeat(req *http.Request) bool {
+	return !cfg.IdempotentOnly || isIdempotent(req, cfg.IdempotencyHeader)
+}
+
+// isIdempotent reports whether req can be repeated without side effects.
+// header is the idempotency key header name; "" = IdempotencyKeyHeader.
+func isIdempotent(req *http.Request, header string) bool {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return true
+	}
+	if header == "" {
+		header = IdempotencyKeyHeader
+	}
+	return req.Header.Get(header) != ""
+}
+
+// ErrBudgetExceeded is wrapped by Result.FinalError when retrying stopped
+// because the next attempt would have exceeded Config.MaxElapsedTime.
+var ErrBudgetExceeded = errors.New("retry time budget exceeded")
+
 // Result contains the outcome of a retried operation.
 type Result struct {
 	Response   *http.Response
@@ -29,7 +105,7 @@ type Result struct {
 // IsRetryable determines if an HTTP response should trigger a retry.
 func IsRetryable(resp *http.Response, err error) bool {
 	if err != nil {
-		return true // Network errors are retryable
+		return !isPermanentError(err) // Network errors are retryable
 	}
 	if resp == nil {
 		return true
@@ -54,45 +130,164 @@ func IsRetryable(resp *http.Response, err error) bool {
 	return false
 }
 
-// CalculateBackoff computes the next backoff duration with exponential growth.
-func CalculateBackoff(cfg Config, attempt int, resp *http.Response) time.Duration {
-	// Check for Retry-After header on 429 responses
-	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
-		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
-			if seconds, err := strconv.Atoi(retryAfter); err == nil {
-				return time.Duration(seconds) * time.Second
-			}
-			if t, err := time.Parse(time.RFC1123, retryAfter); err == nil {
-				return time.Until(t)
-			}
+// ParseRetryAfter parses a Retry-After header value, given either as
+// delay-seconds or an HTTP date. ok is false if v is empty or malformed.
+func ParseRetryAfter(v string) (d time.Duration, ok bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := time.Parse(time.RFC1123, v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// serverDelay returns the wait requested by resp's Retry-After header, on any
+// status, capped at cfg.MaxBackoff. ok is false if there is no usable header.
+func serverDelay(cfg Config, resp *http.Response) (d time.Duration, ok bool) {
+	if resp == nil {
+		return 0, false
+	}
+	d, ok = ParseRetryAfter(resp.Header.Get("Retry-After"))
+	if !ok {
+		return 0, false
+	}
+	if d < 0 {
+		d = 0
+	}
+	if cfg.MaxBackoff > 0 && d > cfg.MaxBackoff {
+		d = cfg.MaxBackoff
+	}
+	return d, true
+}
+
+// isPermanentError reports whether a request error can't be fixed by
+// retrying: cancellation, a malformed URL, or an unsupported scheme.
+// Timeouts and connection errors are transient.
+func isPermanentError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return true
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if ue, ok := e.(*url.Error); ok && ue.Op == "parse" {
+			return true
 		}
 	}
+	msg := err.Error()
+	return strings.Contains(msg, "unsupported protocol scheme") ||
+		strings.Contains(msg, "no Host in request URL")
+}
+
+// Retryable is IsRetryable using cfg.RetryableStatusCodes when set.
+func (cfg Config) Retryable(resp *http.Response, err error) bool {
+	if len(cfg.RetryableStatusCodes) == 0 || err != nil || resp == nil {
+		return IsRetryable(resp, err)
+	}
+	for _, code := range cfg.RetryableStatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// CalculateBackoff computes the next backoff duration using
+// cfg.BackoffStrategy. prev is the previous backoff (0 before the first
+// retry); only the decorrelated strategy uses it.
+func CalculateBackoff(cfg Config, attempt int, prev time.Duration, resp *http.Response) time.Duration {
+	// A server-provided Retry-After (429, 503, ...) wins over the computed backoff
+	if d, ok := serverDelay(cfg, resp); ok {
+		return d
+	}
+	if cfg.BackoffStrategy == StrategyDecorrelated {
+		return decorrelatedBackoff(cfg, prev)
+	}
 
 	// Exponential backoff: initial * factor^attempt
 	backoff := float64(cfg.InitialBackoff) * math.Pow(cfg.BackoffFactor, float64(attempt))
 	if backoff > float64(cfg.MaxBackoff) {
 		backoff = float64(cfg.MaxBackoff)
 	}
+	if cfg.Jitter > 0 {
+		backoff -= backoff * math.Min(cfg.Jitter, 1) * rand.Float64()
+	}
 
 	return time.Duration(backoff)
 }
 
+// decorrelatedBackoff returns min(MaxBackoff, random(InitialBackoff, prev*3)).
+func decorrelatedBackoff(cfg Config, prev time.Duration) time.Duration {
+	base := cfg.InitialBackoff
+	if prev < base {
+		prev = base
+	}
+	upper := 3 * prev
+	if cfg.MaxBackoff > 0 && upper > cfg.MaxBackoff {
+		upper = cfg.MaxBackoff
+	}
+	if upper <= base {
+		return upper
+	}
+	return base + rand.N(upper-base+1)
+}
+
 // Do executes an HTTP request with retry logic.
 func Do(ctx context.Context, client *http.Client, req *http.Request, cfg Config, log zerolog.Logger) Result {
 	start := time.Now()
 	var lastResp *http.Response
 	var lastErr error
+	var backoff time.Duration
 
-	for attempt := 0; attempt <= cfg.MaxRetries; attempt++ {
+	maxRetries := cfg.MaxRetries
+	if !cfg.CanRepeat(req) {
+		log.Debug().
+			Str("method", req.Method).
+			Str("host", req.URL.Host).
+			Msg("retries disabled for non-idempotent request")
+		maxRetries = 0
+	}
+
+	for attempt := 0; attempt <= maxRetries; attempt++ {
 		if attempt > 0 {
-			backoff := CalculateBackoff(cfg, attempt-1, lastResp)
+			backoff = CalculateBackoff(cfg, attempt-1, backoff, lastResp)
+			if cfg.MaxElapsedTime > 0 && time.Since(start)+backoff > cfg.MaxElapsedTime {
+				log.Warn().
+					Int("attempts", attempt).
+					Str("host", req.URL.Host).
+					Dur("backoff", backoff).
+					Dur("elapsed", time.Since(start)).
+					Dur("budget", cfg.MaxElapsedTime).
+					Msg("retry budget exhausted, giving up")
+				return Result{
+					Attempts:   attempt,
+					TotalTime:  time.Since(start),
+					FinalError: budgetError(cfg.MaxElapsedTime, attempt, lastResp, lastErr),
+				}
+			}
+			if _, ok := serverDelay(cfg, lastResp); ok {
+				log.Info().
+					Int("status", lastResp.StatusCode).
+					Str("host", req.URL.Host).
+					Str("retry_after", lastResp.Header.Get("Retry-After")).
+					Dur("backoff", backoff).
+					Msg("honoring server Retry-After")
+			}
 			log.Info().
 				Int("attempt", attempt+1).
+				Str("host", req.URL.Host).
 				Dur("backoff", backoff).
 				Msg("retrying after backoff")
 
 			select {
 			case <-ctx.Done():
+				log.Warn().
+					Err(ctx.Err()).
+					Int("attempts", attempt).
+					Str("host", req.URL.Host).
+					Msg("retry cancelled by context")
 				return Result{
 					Attempts:   attempt,
 					TotalTime:  time.Since(start),
@@ -102,15 +297,31 @@ func Do(ctx context.Context, client *http.Client, req *http.Request, cfg Config,
 			}
 		}
 
+		if attempt > 0 && attempt == maxRetries {
+			log.Warn().
+				Int("attempt", attempt+1).
+				Int("max_attempts", maxRetries+1).
+				Str("host", req.URL.Host).
+				Msg("final retry attempt")
+		}
+
 		// Clone the request for retry (body needs to be re-readable)
 		reqCopy := req.Clone(ctx)
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return Result{Attempts: attempt, TotalTime: time.Since(start), FinalError: err}
+			}
+			reqCopy.Body = body
+		}
 
 		log.Debug().
 			Int("attempt", attempt+1).
+			Str("host", req.URL.Host).
 			Str("url", req.URL.String()).
 			Msg("sending request")
 
-		resp, err := client.Do(reqCopy)
+		resp, err := sendAttempt(client, reqCopy, attempt+1)
 		lastResp = resp
 		lastErr = err
 
@@ -118,20 +329,26 @@ func Do(ctx context.Context, client *http.Client, req *http.Request, cfg Config,
 			log.Warn().
 				Err(err).
 				Int("attempt", attempt+1).
+				Str("host", req.URL.Host).
 				Msg("request failed with error")
 
-			if IsRetryable(nil, err) && attempt < cfg.MaxRetries {
+			if cfg.Retryable(nil, err) && attempt < maxRetries {
 				continue
 			}
-			break
+			return Result{
+				Attempts:   attempt + 1,
+				TotalTime:  time.Since(start),
+				FinalError: err,
+			}
 		}
 
 		log.Debug().
 			Int("status", resp.StatusCode).
 			Int("attempt", attempt+1).
+			Str("host", req.URL.Host).
 			Msg("received response")
 
-		if !IsRetryable(resp, nil) {
+		if !cfg.Retryable(resp, nil) {
 			return Result{
 				Response:  resp,
 				Attempts:  attempt + 1,
@@ -139,18 +356,65 @@ func Do(ctx context.Context, client *http.Client, req *http.Request, cfg Config,
 			}
 		}
 
-		if attempt < cfg.MaxRetries {
+		if attempt < maxRetries {
 			log.Warn().
 				Int("status", resp.StatusCode).
 				Int("attempt", attempt+1).
+				Str("host", req.URL.Host).
 				Msg("retryable error received")
 		}
 	}
 
 	return Result{
 		Response:   lastResp,
-		Attempts:   cfg.MaxRetries + 1,
+		Attempts:   maxRetries + 1,
 		TotalTime:  time.Since(start),
 		FinalError: lastErr,
 	}
 }
+
+// tracer creates one span per attempt. It is a no-op until a tracer provider
+// is installed (see internal/tracing).
+var tracer = otel.Tracer("cron-runner/internal/retry")
+
+// sendAttempt sends one attempt in its own client span and propagates the
+// trace context to the server in the request headers.
+func sendAttempt(client *http.Client, req *http.Request, attempt int) (*http.Response, error) {
+	ctx, span := tracer.Start(req.Context(), "retry.attempt",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+		))
+	defer span.End()
+
+	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 500 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// budgetError describes why retrying stopped, including the last failure.
+// Any last response body is closed, since the caller only gets the error.
+func budgetError(budget time.Duration, attempts int, lastResp *http.Response, lastErr error) error {
+	err := fmt.Errorf("%w (%v) after %d attempts", ErrBudgetExceeded, budget, attempts)
+	if lastResp != nil {
+		lastResp.Body.Close()
+		return fmt.Errorf("%w: last status %d", err, lastResp.StatusCode)
+	}
+	if lastErr != nil {
+		return fmt.Errorf("%w: %w", err, lastErr)
+	}
+	return err
+}
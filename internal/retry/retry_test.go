@@ -0,0 +1,508 @@
+package retry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func testConfig(maxRetries int) Config {
+	return Config{
+		MaxRetries:     maxRetries,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		BackoffFactor:  1,
+	}
+}
+
+// logLines decodes every JSON log line written to buf.
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	sc := bufio.NewScanner(buf)
+	for sc.Scan() {
+		var m map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			t.Fatalf("invalid log line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestDoLogsHost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	log := zerolog.New(&buf).Level(zerolog.DebugLevel)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	result := Do(context.Background(), srv.Client(), req, testConfig(1), log)
+	if result.Response != nil {
+		result.Response.Body.Close()
+	}
+
+	u, _ := url.Parse(srv.URL)
+	lines := logLines(t, &buf)
+	if len(lines) == 0 {
+		t.Fatalf("expected log output")
+	}
+	for _, line := range lines {
+		if line["host"] != u.Host {
+			t.Fatalf("expected host %q on log %q, got %v", u.Host, line["message"], line["host"])
+		}
+	}
+}
+
+func TestDoIdempotentOnlySkipsRetryForPost(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(3)
+	cfg.IdempotentOnly = true
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, nil)
+	result := Do(context.Background(), srv.Client(), req, cfg, zerolog.Nop())
+	if result.Response != nil {
+		result.Response.Body.Close()
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+	if result.Attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %d", result.Attempts)
+	}
+}
+
+func TestDoIdempotentOnlyRetriesPostWithKey(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(2)
+	cfg.IdempotentOnly = true
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, nil)
+	req.Header.Set(IdempotencyKeyHeader, "abc")
+	result := Do(context.Background(), srv.Client(), req, cfg, zerolog.Nop())
+	if result.Response != nil {
+		result.Response.Body.Close()
+	}
+
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+
+	// A custom key header counts only once configured.
+	req, _ = http.NewRequest(http.MethodPost, srv.URL, nil)
+	req.Header.Set("X-Request-Key", "abc")
+	for _, c := range []struct {
+		header string
+		want   int
+	}{{"", 1}, {"X-Request-Key", 3}} {
+		calls = 0
+		cfg.IdempotencyHeader = c.header
+		result := Do(context.Background(), srv.Client(), req, cfg, zerolog.Nop())
+		if result.Response != nil {
+			result.Response.Body.Close()
+		}
+		if calls != c.want {
+			t.Fatalf("header %q: expected %d calls, got %d", c.header, c.want, calls)
+		}
+	}
+}
+
+func TestDoLogsFinalAttemptOnce(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	result := Do(context.Background(), srv.Client(), req, testConfig(3), zerolog.New(&buf))
+	if result.Response != nil {
+		result.Response.Body.Close()
+	}
+
+	var finals []map[string]any
+	for _, line := range logLines(t, &buf) {
+		if line["message"] == "final retry attempt" {
+			finals = append(finals, line)
+		}
+	}
+	if len(finals) != 1 {
+		t.Fatalf("expected 1 final attempt log, got %d", len(finals))
+	}
+	if finals[0]["attempt"] != float64(4) {
+		t.Fatalf("expected final attempt to be attempt 4, got %v", finals[0]["attempt"])
+	}
+}
+
+func TestDoNoFinalAttemptLogWithoutRetries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	result := Do(context.Background(), srv.Client(), req, testConfig(0), zerolog.New(&buf))
+	if result.Response != nil {
+		result.Response.Body.Close()
+	}
+
+	for _, line := range logLines(t, &buf) {
+		if line["message"] == "final retry attempt" {
+			t.Fatalf("expected no final attempt log when retries are disabled")
+		}
+	}
+}
+
+func TestCalculateBackoffJitterStaysInRange(t *testing.T) {
+	cfg := Config{
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		BackoffFactor:  2,
+		Jitter:         0.5,
+	}
+
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 50; i++ {
+		d := CalculateBackoff(cfg, 2, 0, nil) // 4s before jitter
+		if d < 2*time.Second || d > 4*time.Second {
+			t.Fatalf("expected backoff in [2s, 4s], got %v", d)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("expected jittered backoffs to vary, got %v", seen)
+	}
+}
+
+func TestCalculateBackoffDecorrelatedStaysInBounds(t *testing.T) {
+	cfg := Config{
+		InitialBackoff:  time.Second,
+		MaxBackoff:      30 * time.Second,
+		BackoffStrategy: StrategyDecorrelated,
+	}
+
+	var prev time.Duration
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 1000; i++ {
+		d := CalculateBackoff(cfg, i, prev, nil)
+		upper := 3 * max(prev, cfg.InitialBackoff)
+		if d < cfg.InitialBackoff || d > min(upper, cfg.MaxBackoff) {
+			t.Fatalf("iteration %d: backoff %v outside [%v, min(%v, %v)] (prev %v)",
+				i, d, cfg.InitialBackoff, upper, cfg.MaxBackoff, prev)
+		}
+		seen[d] = true
+		prev = d
+	}
+	if len(seen) < 10 {
+		t.Fatalf("expected decorrelated backoffs to vary, got %d distinct values", len(seen))
+	}
+}
+
+func TestCalculateBackoffDecorrelatedFirstRetryFromBase(t *testing.T) {
+	cfg := Config{
+		InitialBackoff:  time.Second,
+		MaxBackoff:      time.Minute,
+		BackoffStrategy: StrategyDecorrelated,
+	}
+	for i := 0; i < 200; i++ {
+		if d := CalculateBackoff(cfg, 0, 0, nil); d < time.Second || d > 3*time.Second {
+			t.Fatalf("expected first backoff in [1s, 3s], got %v", d)
+		}
+	}
+}
+
+func TestCalculateBackoffDecorrelatedCappedAtMax(t *testing.T) {
+	cfg := Config{
+		InitialBackoff:  time.Second,
+		MaxBackoff:      5 * time.Second,
+		BackoffStrategy: StrategyDecorrelated,
+	}
+	for i := 0; i < 200; i++ {
+		if d := CalculateBackoff(cfg, 10, time.Hour, nil); d < time.Second || d > 5*time.Second {
+			t.Fatalf("expected backoff capped at 5s, got %v", d)
+		}
+	}
+}
+
+func TestDoDecorrelatedThreadsPreviousBackoff(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	cfg := Config{
+		MaxRetries:      4,
+		InitialBackoff:  time.Millisecond,
+		MaxBackoff:      50 * time.Millisecond,
+		BackoffStrategy: StrategyDecorrelated,
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	result := Do(context.Background(), srv.Client(), req, cfg, log)
+	if result.Response != nil {
+		result.Response.Body.Close()
+	}
+	if calls != 5 {
+		t.Fatalf("expected 5 attempts, got %d", calls)
+	}
+
+	// Each backoff must lie within [base, 3*previous], capped at MaxBackoff.
+	prev := cfg.InitialBackoff
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var entry struct {
+			Message string  `json:"message"`
+			Backoff float64 `json:"backoff"`
+		}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatalf("decode log: %v", err)
+		}
+		if entry.Message != "retrying after backoff" {
+			continue
+		}
+		d := time.Duration(entry.Backoff * float64(time.Millisecond))
+		if d < cfg.InitialBackoff || d > min(3*prev, cfg.MaxBackoff) {
+			t.Fatalf("backoff %v outside [%v, %v]", d, cfg.InitialBackoff, min(3*prev, cfg.MaxBackoff))
+		}
+		prev = d
+	}
+}
+
+func TestCalculateBackoffRetryAfterNotJittered(t *testing.T) {
+	cfg := Config{
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		BackoffFactor:  2,
+		Jitter:         1,
+	}
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"7"}},
+	}
+
+	for i := 0; i < 10; i++ {
+		if d := CalculateBackoff(cfg, 0, 0, resp); d != 7*time.Second {
+			t.Fatalf("expected exact Retry-After of 7s, got %v", d)
+		}
+	}
+}
+
+func TestCalculateBackoffRetryAfterOn503(t *testing.T) {
+	cfg := Config{
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		BackoffFactor:  2,
+	}
+
+	resp := &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"Retry-After": []string{"12"}},
+	}
+	if d := CalculateBackoff(cfg, 0, 0, resp); d != 12*time.Second {
+		t.Fatalf("expected Retry-After of 12s on 503, got %v", d)
+	}
+
+	resp.Header.Set("Retry-After", time.Now().Add(2*time.Hour).UTC().Format(http.TimeFormat))
+	if d := CalculateBackoff(cfg, 0, 0, resp); d != time.Minute {
+		t.Fatalf("expected HTTP-date Retry-After capped at MaxBackoff, got %v", d)
+	}
+}
+
+func TestDoLogsHonoredRetryAfter(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	result := Do(context.Background(), srv.Client(), req, testConfig(1), zerolog.New(&buf))
+	if result.Response != nil {
+		result.Response.Body.Close()
+	}
+	if result.Response == nil || result.Response.StatusCode != http.StatusOK {
+		t.Fatalf("expected retry to succeed, got %+v", result)
+	}
+
+	var honored int
+	for _, line := range logLines(t, &buf) {
+		if line["message"] == "honoring server Retry-After" {
+			honored++
+			if line["status"] != float64(http.StatusServiceUnavailable) {
+				t.Fatalf("expected status 503 in log, got %v", line["status"])
+			}
+		}
+	}
+	if honored != 1 {
+		t.Fatalf("expected 1 Retry-After log, got %d", honored)
+	}
+}
+
+func TestDoStopsWhenRetryBudgetExceeded(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := Config{
+		MaxRetries:     10,
+		InitialBackoff: 40 * time.Millisecond,
+		MaxBackoff:     40 * time.Millisecond,
+		BackoffFactor:  1,
+		MaxElapsedTime: 100 * time.Millisecond,
+	}
+
+	var buf bytes.Buffer
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	result := Do(context.Background(), srv.Client(), req, cfg, zerolog.New(&buf))
+
+	if !errors.Is(result.FinalError, ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got: %v", result.FinalError)
+	}
+	if calls >= 11 || calls < 2 {
+		t.Fatalf("expected the budget to cut retries short, got %d calls", calls)
+	}
+	if result.Attempts != calls {
+		t.Fatalf("expected %d attempts, got %d", calls, result.Attempts)
+	}
+	if result.TotalTime > cfg.MaxElapsedTime {
+		t.Fatalf("expected to stop within the budget, took %v", result.TotalTime)
+	}
+
+	var exhausted, cancelled bool
+	for _, line := range logLines(t, &buf) {
+		switch line["message"] {
+		case "retry budget exhausted, giving up":
+			exhausted = true
+		case "retry cancelled by context":
+			cancelled = true
+		}
+	}
+	if !exhausted || cancelled {
+		t.Fatalf("expected a budget log and no cancellation log, got exhausted=%v cancelled=%v", exhausted, cancelled)
+	}
+}
+
+func TestRetryableStatusCodesOverrideDefault(t *testing.T) {
+	resp := func(code int) *http.Response { return &http.Response{StatusCode: code} }
+
+	def := Config{}
+	for _, code := range []int{429, 500, 502, 503, 504} {
+		if !def.Retryable(resp(code), nil) {
+			t.Fatalf("expected %d to be retryable by default", code)
+		}
+	}
+
+	cfg := Config{RetryableStatusCodes: []int{429, 503}}
+	if !cfg.Retryable(resp(503), nil) {
+		t.Fatalf("expected configured 503 to be retryable")
+	}
+	if cfg.Retryable(resp(500), nil) {
+		t.Fatalf("expected 500 not to be retryable when not configured")
+	}
+	if !cfg.Retryable(nil, errors.New("connection reset")) {
+		t.Fatalf("expected network errors to stay retryable")
+	}
+}
+
+func TestDoUsesConfiguredRetryableStatusCodes(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(3)
+	cfg.RetryableStatusCodes = []int{502, 503, 504}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	result := Do(context.Background(), srv.Client(), req, cfg, zerolog.Nop())
+	if result.Response != nil {
+		result.Response.Body.Close()
+	}
+	if calls != 1 {
+		t.Fatalf("expected a 500 not to be retried, got %d calls", calls)
+	}
+}
+
+func TestIsRetryableErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"context canceled", &url.Error{Op: "Get", URL: "http://x", Err: context.Canceled}, false},
+		{"malformed url", &url.Error{Op: "parse", URL: "http://[::1", Err: errors.New("missing ']' in host")}, false},
+		{"unsupported scheme", &url.Error{Op: "Get", URL: "ftp://x", Err: errors.New(`unsupported protocol scheme "ftp"`)}, false},
+		{"timeout", &url.Error{Op: "Get", URL: "http://x", Err: context.DeadlineExceeded}, true},
+		{"connection refused", &url.Error{Op: "Get", URL: "http://x", Err: errors.New("dial tcp: connection refused")}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsRetryable(nil, tc.err); got != tc.want {
+				t.Fatalf("expected IsRetryable=%v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDoDoesNotRetryPermanentErrors(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "ftp://example.test/file", nil)
+	result := Do(context.Background(), http.DefaultClient, req, testConfig(3), zerolog.Nop())
+	if result.FinalError == nil {
+		t.Fatalf("expected an error for an unsupported scheme")
+	}
+	if result.Attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %d", result.Attempts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ = http.NewRequest(http.MethodGet, "http://example.test/", nil)
+	result = Do(ctx, http.DefaultClient, req, testConfig(3), zerolog.Nop())
+	if !errors.Is(result.FinalError, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", result.FinalError)
+	}
+	if result.Attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %d", result.Attempts)
+	}
+}
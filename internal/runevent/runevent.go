@@ -0,0 +1,85 @@
+// Package runevent writes a machine-readable "run_completed" event for every
+// finished run, one JSON object per line, for sidecar log processors that
+// match on a specific event type amid the regular logs.
+//
+// The schema is stable: fields are only ever added, never renamed, removed or
+// retyped, and TypeRunCompleted never changes. A breaking change would bump
+// SchemaVersion.
+package runevent
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// TypeRunCompleted is the value of the "event" field.
+	TypeRunCompleted = "run_completed"
+
+	// SchemaVersion is the value of the "schema_version" field.
+	SchemaVersion = 1
+)
+
+// Event is one run_completed record. Result is "success", "degraded" or
+// "failure"; JobID, HTTPStatus, Error and Metadata are omitted when empty.
+type Event struct {
+	Event         string            `json:"event"`
+	SchemaVersion int               `json:"schema_version"`
+	Job           string            `json:"job"`
+	Endpoint      string            `json:"endpoint"`
+	JobID         string            `json:"job_id,omitempty"`
+	Result        string            `json:"result"`
+	Success       bool              `json:"success"`
+	Attempts      int               `json:"attempts"`
+	HTTPStatus    int               `json:"http_status,omitempty"`
+	TriggeredAt   time.Time         `json:"triggered_at"`
+	CompletedAt   time.Time         `json:"completed_at"`
+	DurationMs    int64             `json:"duration_ms"`
+	Error         string            `json:"error,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+}
+
+// Emitter writes Events as JSON lines to an output stream, independent of
+// LOG_FORMAT. Lines are written whole, so they never interleave with each
+// other. Write failures are logged and never fail the run.
+type Emitter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	metadata map[string]string
+	log      zerolog.Logger
+}
+
+// New creates an Emitter writing to w. metadata (RUN_METADATA) is attached
+// to every event.
+func New(w io.Writer, metadata map[string]string, log zerolog.Logger) *Emitter {
+	return &Emitter{
+		w:        w,
+		metadata: metadata,
+		log:      log.With().Str("component", "run-events").Logger(),
+	}
+}
+
+// RunCompleted fills in the type, schema version and metadata of ev and
+// writes it.
+func (e *Emitter) RunCompleted(ev Event) {
+	ev.Event = TypeRunCompleted
+	ev.SchemaVersion = SchemaVersion
+	ev.Metadata = e.metadata
+
+	line, err := json.Marshal(ev)
+	if err != nil {
+		e.log.Warn().Err(err).Msg("run_event_marshal_failed")
+		return
+	}
+	line = append(line, '\n')
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.w.Write(line); err != nil {
+		e.log.Warn().Err(err).Msg("run_event_write_failed")
+	}
+}
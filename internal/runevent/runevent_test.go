@@ -0,0 +1,104 @@
+package runevent
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"cron-runner/internal/redact"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRunCompletedHasStableSchema(t *testing.T) {
+	var buf bytes.Buffer
+	e := New(&buf, map[string]string{"env": "prod"}, zerolog.Nop())
+
+	triggeredAt := time.Date(2026, 3, 9, 22, 0, 0, 0, time.UTC)
+	e.RunCompleted(Event{
+		Job:         "post-game",
+		Endpoint:    "/v1/internal/pipelines/post-game",
+		JobID:       "job-1",
+		Result:      "failure",
+		Attempts:    2,
+		HTTPStatus:  502,
+		TriggeredAt: triggeredAt,
+		CompletedAt: triggeredAt.Add(1500 * time.Millisecond),
+		DurationMs:  1500,
+		Error:       "boom",
+	})
+
+	line := buf.String()
+	if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+		t.Fatalf("expected exactly one JSON line, got %q", line)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(line), &got); err != nil {
+		t.Fatalf("event is not JSON: %v", err)
+	}
+	if got["event"] != "run_completed" {
+		t.Fatalf("expected event %q, got %v", "run_completed", got["event"])
+	}
+	if got["schema_version"] != float64(1) {
+		t.Fatalf("expected schema_version 1, got %v", got["schema_version"])
+	}
+
+	// The documented schema; renaming any of these breaks downstream consumers.
+	want := map[string]any{
+		"job":          "post-game",
+		"endpoint":     "/v1/internal/pipelines/post-game",
+		"job_id":       "job-1",
+		"result":       "failure",
+		"success":      false,
+		"attempts":     float64(2),
+		"http_status":  float64(502),
+		"triggered_at": "2026-03-09T22:00:00Z",
+		"completed_at": "2026-03-09T22:00:01.5Z",
+		"duration_ms":  float64(1500),
+		"error":        "boom",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("field %q: expected %v, got %v", k, v, got[k])
+		}
+	}
+	if md, _ := got["metadata"].(map[string]any); md["env"] != "prod" {
+		t.Errorf("expected metadata env=prod, got %v", got["metadata"])
+	}
+}
+
+func TestRunCompletedOmitsEmptyOptionalFields(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, nil, zerolog.Nop()).RunCompleted(Event{Job: "pre-game", Result: "success", Success: true})
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("event is not JSON: %v", err)
+	}
+	for _, k := range []string{"job_id", "http_status", "error", "metadata"} {
+		if _, ok := got[k]; ok {
+			t.Errorf("expected %q to be omitted, got %v", k, got[k])
+		}
+	}
+}
+
+func TestRunCompletedThroughScrubWriterMasksSecrets(t *testing.T) {
+	const token = "sk_live_0123456789abcdef"
+	var buf bytes.Buffer
+	e := New(redact.NewWriter(&buf, nil, token), nil, zerolog.Nop())
+	e.RunCompleted(Event{Job: "post-game", Result: "failure", Error: "unexpected status 401: bad token " + token})
+
+	if strings.Contains(buf.String(), token) {
+		t.Fatalf("run event leaked the token: %s", buf.String())
+	}
+	var got Event
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("scrubbed event is not JSON: %v", err)
+	}
+	if !strings.Contains(got.Error, "sk_l…(24 chars)") {
+		t.Fatalf("expected the masked token in the error, got %q", got.Error)
+	}
+}
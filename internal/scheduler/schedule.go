@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// secondsParser matches the parser gocron uses for 6-field crontabs.
+var secondsParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ParseSchedule parses a cron expression the way the scheduler will, so a bad
+// expression fails at startup with the offending field named instead of
+// being rejected later or never firing.
+func ParseSchedule(expr string, withSeconds bool) (cron.Schedule, error) {
+	parse := cron.ParseStandard
+	if withSeconds {
+		parse = secondsParser.Parse
+	}
+	sched, err := parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	return sched, nil
+}
+
+// NextRuns returns the next n fire times of a cron expression after from, in UTC.
+func NextRuns(expr string, withSeconds bool, from time.Time, n int) ([]time.Time, error) {
+	sched, err := ParseSchedule(expr, withSeconds)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]time.Time, 0, n)
+	t := from.UTC()
+	for i := 0; i < n; i++ {
+		t = sched.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs, nil
+}
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseScheduleRejectsInvalidExpression(t *testing.T) {
+	tests := []struct {
+		expr        string
+		withSeconds bool
+	}{
+		{"0/15 14-23 * *", false},    // too few fields
+		{"0 25 * * *", false},        // hour out of range
+		{"*/30 * * * * *", false},    // seconds field without WithSeconds
+		{"*/30 * 16-23 * * x", true}, // bad day-of-week
+	}
+	for _, tt := range tests {
+		_, err := ParseSchedule(tt.expr, tt.withSeconds)
+		if err == nil {
+			t.Fatalf("expected error for %q", tt.expr)
+		}
+		if !strings.Contains(err.Error(), tt.expr) {
+			t.Fatalf("expected error to name the expression, got: %v", err)
+		}
+	}
+}
+
+func TestNextRuns(t *testing.T) {
+	from := time.Date(2025, 1, 10, 13, 50, 0, 0, time.UTC)
+	runs, err := NextRuns("0/15 14-23,0-1 * * *", false, from, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []time.Time{
+		time.Date(2025, 1, 10, 14, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 10, 14, 15, 0, 0, time.UTC),
+		time.Date(2025, 1, 10, 14, 30, 0, 0, time.UTC),
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("expected %d runs, got %v", len(want), runs)
+	}
+	for i := range want {
+		if !runs[i].Equal(want[i]) {
+			t.Fatalf("run %d: expected %v, got %v", i, want[i], runs[i])
+		}
+	}
+}
+
+func TestNextRunsWithSeconds(t *testing.T) {
+	from := time.Date(2025, 1, 10, 16, 0, 10, 0, time.UTC)
+	runs, err := NextRuns("*/30 * 16-23,0-6 * * *", true, from, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runs) != 2 || runs[0] != from.Add(20*time.Second) || runs[1] != from.Add(50*time.Second) {
+		t.Fatalf("unexpected runs %v", runs)
+	}
+}
+
+func TestRegisterRejectsInvalidSchedule(t *testing.T) {
+	s := New(zerolog.Nop(), Config{})
+	err := s.Register(JobDef{Name: "bad", Schedule: "61 * * * *"})
+	if err == nil {
+		t.Fatalf("expected error for invalid schedule")
+	}
+	if len(s.Statuses()) != 0 {
+		t.Fatalf("expected the job not to be registered")
+	}
+}
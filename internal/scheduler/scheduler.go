@@ -3,9 +3,12 @@ package scheduler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"cron-runner/internal/resources"
 	"cron-runner/internal/task"
 
 	"github.com/go-co-op/gocron/v2"
@@ -13,60 +16,105 @@ import (
 	"github.com/rs/zerolog"
 )
 
-const recentRunsMax = 20
+// defaultHistorySize is how many recent runs are kept per job when
+// Config.HistorySize is unset.
+const defaultHistorySize = 10
 
 // JobDef is the declarative description of a scheduled job.
 // Adding a new job means adding one JobDef to the registry — nothing else changes.
 type JobDef struct {
 	Name        string
-	Schedule    string        // cron expression; 5-field by default, 6-field if WithSeconds is true
-	WithSeconds bool          // if true, Schedule's first field is seconds (e.g. "*/30 * * * * *")
+	Schedule    string // cron expression; 5-field by default, 6-field if WithSeconds is true
+	WithSeconds bool   // if true, Schedule's first field is seconds (e.g. "*/30 * * * * *")
 	Task        task.Task
 	Singleton   bool          // if true, a new run is dropped if the previous is still running
 	Timeout     time.Duration // 0 = no timeout; cancels the job's context when exceeded
 }
 
 // RecentRun captures basic outcome data for a single job execution.
-// Stored in a bounded in-memory ring buffer per job (last recentRunsMax runs).
+// Stored in a bounded in-memory ring buffer per job (last Config.HistorySize runs).
 type RecentRun struct {
 	TriggeredAt time.Time
 	Duration    time.Duration
-	Result      string // "success" | "failure"
+	Result      string // "success" | "degraded" | "dry_run" | "failure"
+	Error       string // set for failures
+
+	SlowestPipeline        string // set when the job reported per-pipeline durations
+	SlowestPipelineSeconds float64
+}
+
+// recentRunWire is the JSON form of a RecentRun, shared by /status and the
+// state file.
+type recentRunWire struct {
+	TriggeredAt time.Time `json:"triggered_at"`
+	DurationMs  int64     `json:"duration_ms"`
+	Result      string    `json:"result"`
+	Error       string    `json:"error,omitempty"`
+
+	SlowestPipeline        string  `json:"slowest_pipeline,omitempty"`
+	SlowestPipelineSeconds float64 `json:"slowest_pipeline_seconds,omitempty"`
 }
 
 func (r RecentRun) MarshalJSON() ([]byte, error) {
-	type wire struct {
-		TriggeredAt time.Time `json:"triggered_at"`
-		DurationMs  int64     `json:"duration_ms"`
-		Result      string    `json:"result"`
-	}
-	return json.Marshal(wire{
-		TriggeredAt: r.TriggeredAt,
-		DurationMs:  r.Duration.Milliseconds(),
-		Result:      r.Result,
+	return json.Marshal(recentRunWire{
+		TriggeredAt:            r.TriggeredAt,
+		DurationMs:             r.Duration.Milliseconds(),
+		Result:                 r.Result,
+		Error:                  r.Error,
+		SlowestPipeline:        r.SlowestPipeline,
+		SlowestPipelineSeconds: r.SlowestPipelineSeconds,
 	})
 }
 
+func (r *RecentRun) UnmarshalJSON(b []byte) error {
+	var w recentRunWire
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+	*r = RecentRun{
+		TriggeredAt:            w.TriggeredAt,
+		Duration:               time.Duration(w.DurationMs) * time.Millisecond,
+		Result:                 w.Result,
+		Error:                  w.Error,
+		SlowestPipeline:        w.SlowestPipeline,
+		SlowestPipelineSeconds: w.SlowestPipelineSeconds,
+	}
+	return nil
+}
+
 // appendRun appends a run to the ring buffer, evicting the oldest if over capacity.
-func appendRun(runs []RecentRun, r RecentRun) []RecentRun {
+func appendRun(runs []RecentRun, r RecentRun, max int) []RecentRun {
 	runs = append(runs, r)
-	if len(runs) > recentRunsMax {
-		runs = runs[len(runs)-recentRunsMax:]
+	if len(runs) > max {
+		runs = runs[len(runs)-max:]
 	}
 	return runs
 }
 
+// withRunInfo fills r with the details the finished run reported, consuming
+// them so they never leak into a later run. Callers hold s.mu.
+func withRunInfo(r RecentRun, st *jobState) RecentRun {
+	if info := st.runInfo; info != nil {
+		r.SlowestPipeline = info.SlowestPipeline
+		r.SlowestPipelineSeconds = info.SlowestPipelineSeconds
+	}
+	st.runInfo = nil
+	return r
+}
+
 // JobStatus is the runtime state of a registered job, reported by GET /status.
 type JobStatus struct {
-	Name         string      `json:"name"`
-	Schedule     string      `json:"schedule"`
-	LastRun      *time.Time  `json:"last_run,omitempty"`
-	NextRun      *time.Time  `json:"next_run,omitempty"`
-	LastResult   string      `json:"last_result"` // "never", "running", "success", "failure"
-	LastError    string      `json:"last_error,omitempty"`
-	LastDuration string      `json:"last_duration,omitempty"`
-	RunCount     uint64      `json:"run_count"`
-	RecentRuns   []RecentRun `json:"recent_runs,omitempty"`
+	Name                string      `json:"name"`
+	Schedule            string      `json:"schedule"`
+	LastRun             *time.Time  `json:"last_run,omitempty"`
+	NextRun             *time.Time  `json:"next_run,omitempty"`
+	LastResult          string      `json:"last_result"` // "never", "running", "success", "degraded", "dry_run", "failure"
+	LastError           string      `json:"last_error,omitempty"`
+	LastDuration        string      `json:"last_duration,omitempty"`
+	LastSuccess         *time.Time  `json:"last_success,omitempty"`
+	RunCount            uint64      `json:"run_count"`
+	ConsecutiveFailures int         `json:"consecutive_failures"` // failed runs since the last success
+	RecentRuns          []RecentRun `json:"recent_runs,omitempty"`
 }
 
 type jobState struct {
@@ -75,8 +123,13 @@ type jobState struct {
 	lastResult   string
 	lastError    string
 	lastDuration time.Duration
+	lastSuccess  *time.Time
 	runCount     uint64
-	recentRuns   []RecentRun // bounded ring, newest appended at end
+	failStreak   int           // consecutive failures since the last success
+	recentRuns   []RecentRun   // bounded ring, newest appended at end
+	runInfo      *task.RunInfo // details reported by the run that just finished
+	forcedAt     time.Time     // last watchdog-forced run
+	lastDryRun   time.Time     // last finished dry run; the watchdog counts it as fresh
 }
 
 // Scheduler wraps gocron/v2 with status tracking and structured logging.
@@ -89,43 +142,143 @@ type Scheduler struct {
 	running map[string]time.Time // job name → start time (empty if not running)
 	log     zerolog.Logger
 	started time.Time
+	cfg     Config
+	now     func() time.Time // clock for the watchdog
+
+	failStreak int // consecutive failed runs across all jobs; reset by any success
+
+	saved  map[string]savedJob // state file entries not yet claimed by Register
+	saveMu sync.Mutex          // serializes state file writes
+}
+
+// Config holds scheduler-wide behavior settings.
+type Config struct {
+	// Adaptive cool-down: once a job fails CooldownAfter times in a row, its
+	// scheduled runs are skipped until CooldownBase has passed since the last
+	// run, doubling per additional failure up to CooldownMax. A success resets
+	// the streak. CooldownAfter <= 0 disables it.
+	CooldownAfter int
+	CooldownBase  time.Duration
+	CooldownMax   time.Duration
+
+	// HistorySize is how many recent runs per job /status reports
+	// (<= 0 = default of 10).
+	HistorySize int
+
+	// WatchdogMaxInterval forces a run of any job that has not succeeded
+	// for this long, as a backstop against missed ticks (0 = disabled).
+	WatchdogMaxInterval time.Duration
+
+	// Resources, when set, skips runs while the node is short on disk or
+	// memory.
+	Resources *resources.Checker
+
+	// StateFile, when set, is where job state is saved after every run and
+	// restored from at startup, so /status survives a restart.
+	StateFile string
+
+	// CompressState gzips the state file (PERSIST_COMPRESS). Either form
+	// is read back at startup, so it can be toggled between restarts.
+	CompressState bool
 }
 
-func New(log zerolog.Logger) *Scheduler {
+func New(log zerolog.Logger, cfg Config) *Scheduler {
 	s, _ := gocron.NewScheduler(gocron.WithLocation(time.UTC))
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Scheduler{
+	sched := &Scheduler{
 		s:       s,
 		ctx:     ctx,
 		cancel:  cancel,
 		states:  make(map[string]*jobState),
 		running: make(map[string]time.Time),
 		log:     log.With().Str("component", "scheduler").Logger(),
+		cfg:     cfg,
+		now:     time.Now,
+	}
+	sched.loadState()
+	return sched
+}
+
+// historySize returns the configured per-job run history length.
+func (s *Scheduler) historySize() int {
+	if s.cfg.HistorySize > 0 {
+		return s.cfg.HistorySize
+	}
+	return defaultHistorySize
+}
+
+// cooldownFor returns how long to hold off after a failure streak of the given length.
+func (s *Scheduler) cooldownFor(streak int) time.Duration {
+	if s.cfg.CooldownAfter <= 0 || streak < s.cfg.CooldownAfter {
+		return 0
+	}
+	d := s.cfg.CooldownBase
+	for i := s.cfg.CooldownAfter; i < streak && d < s.cfg.CooldownMax; i++ {
+		d *= 2
+	}
+	if s.cfg.CooldownMax > 0 && d > s.cfg.CooldownMax {
+		d = s.cfg.CooldownMax
+	}
+	return d
+}
+
+// beforeRun marks a job as running, or returns an error (which makes gocron
+// skip this run) while the node is short on resources or the job is cooling
+// down after repeated failures.
+func (s *Scheduler) beforeRun(jobName string) error {
+	if err := s.cfg.Resources.Check(); err != nil {
+		s.log.Warn().
+			Str("job", jobName).
+			Err(err).
+			Msg("job_skipped_resources")
+		return fmt.Errorf("job %s skipped: %w", jobName, err)
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	if st, ok := s.states[jobName]; ok {
+		if cooldown := s.cooldownFor(st.failStreak); cooldown > 0 && st.lastRun != nil {
+			if resumeAt := st.lastRun.Add(cooldown); now.Before(resumeAt) {
+				streak := st.failStreak
+				s.mu.Unlock()
+				s.log.Warn().
+					Str("job", jobName).
+					Int("consecutive_failures", streak).
+					Dur("cooldown", cooldown).
+					Time("resume_at", resumeAt).
+					Msg("job_skipped_cooldown")
+				return fmt.Errorf("job %s cooling down until %s", jobName, resumeAt.Format(time.RFC3339))
+			}
+		}
+		st.lastResult = "running"
 	}
+	s.running[jobName] = now
+	s.mu.Unlock()
+	s.log.Info().Str("job", jobName).Msg("job_started")
+	return nil
 }
 
 // Register adds a job to the scheduler. Must be called before Start.
 func (s *Scheduler) Register(def JobDef) error {
+	if _, err := ParseSchedule(def.Schedule, def.WithSeconds); err != nil {
+		return err
+	}
+
 	state := &jobState{
 		def:        def,
 		lastResult: "never",
 	}
 
 	s.mu.Lock()
+	s.restore(def.Name, state)
 	s.states[def.Name] = state
 	s.mu.Unlock()
 
 	opts := []gocron.JobOption{
 		gocron.WithName(def.Name),
 		gocron.WithEventListeners(
-			gocron.BeforeJobRuns(func(jobID uuid.UUID, jobName string) {
-				s.mu.Lock()
-				s.running[jobName] = time.Now()
-				if st, ok := s.states[jobName]; ok {
-					st.lastResult = "running"
-				}
-				s.mu.Unlock()
-				s.log.Info().Str("job", jobName).Msg("job_started")
+			gocron.BeforeJobRunsSkipIfBeforeFuncErrors(func(jobID uuid.UUID, jobName string) error {
+				return s.beforeRun(jobName)
 			}),
 			gocron.AfterJobRuns(func(jobID uuid.UUID, jobName string) {
 				now := time.Now()
@@ -134,19 +287,43 @@ func (s *Scheduler) Register(def JobDef) error {
 				delete(s.running, jobName)
 				dur := now.Sub(startTime)
 				if st, ok := s.states[jobName]; ok {
-					st.lastResult = "success"
+					// A dry run sent nothing to the backend, so it is kept
+					// apart from real successes and does not reset staleness.
+					// It still shows the tick fired, which is all the
+					// watchdog checks for.
+					// A degraded run is a success that some pipelines only
+					// passed with warnings.
+					result := "success"
+					switch {
+					case st.runInfo != nil && st.runInfo.DryRun:
+						result = "dry_run"
+						st.lastDryRun = now
+					case st.runInfo != nil && st.runInfo.Degraded:
+						result = "degraded"
+						st.lastSuccess = &now
+					default:
+						st.lastSuccess = &now
+					}
+					st.lastResult = result
 					st.lastRun = &now
 					st.lastError = ""
 					st.lastDuration = dur
 					st.runCount++
-					st.recentRuns = appendRun(st.recentRuns, RecentRun{
+					st.failStreak = 0
+					st.recentRuns = appendRun(st.recentRuns, withRunInfo(RecentRun{
 						TriggeredAt: startTime,
 						Duration:    dur,
-						Result:      "success",
-					})
+						Result:      result,
+					}, st), s.historySize())
 				}
+				s.failStreak = 0
 				s.mu.Unlock()
-				s.log.Info().Str("job", jobName).Msg("job_completed")
+				s.saveState()
+				s.log.Info().
+					Str("job", jobName).
+					Dur("duration", dur).
+					Float64("duration_seconds", dur.Seconds()).
+					Msg("job_completed")
 			}),
 			gocron.AfterJobRunsWithError(func(jobID uuid.UUID, jobName string, err error) {
 				now := time.Now()
@@ -160,14 +337,23 @@ func (s *Scheduler) Register(def JobDef) error {
 					st.lastError = err.Error()
 					st.lastDuration = dur
 					st.runCount++
-					st.recentRuns = appendRun(st.recentRuns, RecentRun{
+					st.failStreak++
+					st.recentRuns = appendRun(st.recentRuns, withRunInfo(RecentRun{
 						TriggeredAt: startTime,
 						Duration:    dur,
 						Result:      "failure",
-					})
+						Error:       err.Error(),
+					}, st), s.historySize())
 				}
+				s.failStreak++
 				s.mu.Unlock()
-				s.log.Error().Str("job", jobName).Err(err).Msg("job_failed")
+				s.saveState()
+				s.log.Error().
+					Str("job", jobName).
+					Err(err).
+					Dur("duration", dur).
+					Float64("duration_seconds", dur.Seconds()).
+					Msg("job_failed")
 			}),
 		),
 	}
@@ -190,7 +376,14 @@ func (s *Scheduler) Register(def JobDef) error {
 				runCtx, cancel = context.WithTimeout(s.ctx, jobDef.Timeout)
 				defer cancel()
 			}
-			return jobDef.Task.Run(runCtx)
+			runCtx, info := task.WithRunInfo(runCtx)
+			err := jobDef.Task.Run(runCtx)
+			s.mu.Lock()
+			if st, ok := s.states[jobDef.Name]; ok {
+				st.runInfo = info
+			}
+			s.mu.Unlock()
+			return err
 		}),
 		opts...,
 	)
@@ -210,9 +403,91 @@ func (s *Scheduler) Register(def JobDef) error {
 
 // Start begins the scheduler. Non-blocking.
 func (s *Scheduler) Start() {
-	s.started = time.Now()
+	s.started = s.now()
 	s.s.Start()
 	s.log.Info().Msg("scheduler_started")
+
+	if s.cfg.WatchdogMaxInterval > 0 {
+		go s.runWatchdog(watchdogCheckInterval(s.cfg.WatchdogMaxInterval))
+	}
+
+	for _, j := range s.s.Jobs() {
+		if nr, err := j.NextRun(); err == nil && !nr.IsZero() {
+			s.log.Info().
+				Str("job", j.Name()).
+				Time("next_run", nr).
+				Msg("job_next_run")
+		}
+	}
+}
+
+// watchdogCheckInterval is how often the watchdog looks for overdue jobs:
+// a tenth of maxInterval, between once a second and once a minute.
+func watchdogCheckInterval(maxInterval time.Duration) time.Duration {
+	return max(min(maxInterval/10, time.Minute), time.Second)
+}
+
+// runWatchdog calls checkWatchdog every interval until shutdown.
+func (s *Scheduler) runWatchdog(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-t.C:
+			s.checkWatchdog()
+		}
+	}
+}
+
+// checkWatchdog forces a run of every job with no success in the last
+// WatchdogMaxInterval (counted from Start for jobs that never succeeded).
+// Under DRY_RUN a finished dry run counts as fresh, since it never records
+// a success.
+// The run goes through RunNow, so the cool-down and singleton limits still
+// apply, and a job that is running is left alone. A job that keeps failing
+// is forced at most once per interval.
+func (s *Scheduler) checkWatchdog() {
+	now := s.now()
+	var overdue []string
+	var since []time.Duration
+
+	s.mu.Lock()
+	for name, st := range s.states {
+		if _, running := s.running[name]; running {
+			continue
+		}
+		success := s.started
+		if st.lastSuccess != nil && st.lastSuccess.After(success) {
+			success = *st.lastSuccess
+		}
+		due := success
+		if st.forcedAt.After(due) {
+			due = st.forcedAt
+		}
+		if st.lastDryRun.After(due) {
+			due = st.lastDryRun
+		}
+		if now.Sub(due) < s.cfg.WatchdogMaxInterval {
+			continue
+		}
+		st.forcedAt = now
+		overdue = append(overdue, name)
+		since = append(since, now.Sub(success))
+	}
+	s.mu.Unlock()
+
+	for i, name := range overdue {
+		s.log.Warn().
+			Str("job", name).
+			Dur("since_last_success", since[i]).
+			Dur("watchdog_max_interval", s.cfg.WatchdogMaxInterval).
+			Msg("watchdog_forced_run")
+		if err := s.RunNow(name); err != nil {
+			s.log.Error().Err(err).Str("job", name).Msg("watchdog_run_failed")
+		}
+	}
 }
 
 // Shutdown cancels all running tasks and waits for them to complete.
@@ -250,13 +525,15 @@ func (s *Scheduler) Statuses() []JobStatus {
 	statuses := make([]JobStatus, 0, len(s.states))
 	for _, st := range s.states {
 		js := JobStatus{
-			Name:       st.def.Name,
-			Schedule:   st.def.Schedule,
-			LastRun:    st.lastRun,
-			LastResult: st.lastResult,
-			LastError:  st.lastError,
-			RunCount:   st.runCount,
-			RecentRuns: st.recentRuns,
+			Name:                st.def.Name,
+			Schedule:            st.def.Schedule,
+			LastRun:             st.lastRun,
+			LastResult:          st.lastResult,
+			LastError:           st.lastError,
+			RunCount:            st.runCount,
+			RecentRuns:          st.recentRuns,
+			LastSuccess:         st.lastSuccess,
+			ConsecutiveFailures: st.failStreak,
 		}
 		if st.lastDuration > 0 {
 			js.LastDuration = st.lastDuration.Round(time.Millisecond).String()
@@ -270,6 +547,47 @@ func (s *Scheduler) Statuses() []JobStatus {
 	return statuses
 }
 
+// RunNow runs the named job once, immediately, without changing its schedule.
+// Singleton limits still apply, so a job that is already running is not doubled up.
+func (s *Scheduler) RunNow(name string) error {
+	for _, j := range s.s.Jobs() {
+		if j.Name() == name {
+			return j.RunNow()
+		}
+	}
+	return fmt.Errorf("unknown job %q", name)
+}
+
+// InFlight returns the number of jobs currently running.
+func (s *Scheduler) InFlight() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.running)
+}
+
+// DegradedJobs returns, sorted, the jobs whose last finished run succeeded
+// with some pipelines in a "warn" status.
+func (s *Scheduler) DegradedJobs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var names []string
+	for name, st := range s.states {
+		if settledResult(st) == "degraded" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ConsecutiveFailures returns how many runs, across all jobs, have failed
+// in a row since the last successful run.
+func (s *Scheduler) ConsecutiveFailures() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.failStreak
+}
+
 // Uptime returns a human-readable uptime string.
 func (s *Scheduler) Uptime() string {
 	if s.started.IsZero() {
@@ -0,0 +1,277 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cron-runner/internal/task"
+
+	"github.com/rs/zerolog"
+)
+
+func TestCooldownGrowsWithStreakAndIsCapped(t *testing.T) {
+	s := New(zerolog.Nop(), Config{
+		CooldownAfter: 2,
+		CooldownBase:  time.Minute,
+		CooldownMax:   5 * time.Minute,
+	})
+
+	want := map[int]time.Duration{
+		0: 0,
+		1: 0,
+		2: time.Minute,
+		3: 2 * time.Minute,
+		4: 4 * time.Minute,
+		5: 5 * time.Minute,
+		9: 5 * time.Minute,
+	}
+	for streak, d := range want {
+		if got := s.cooldownFor(streak); got != d {
+			t.Errorf("cooldownFor(%d) = %v, want %v", streak, got, d)
+		}
+	}
+}
+
+func TestCooldownSkipsRunsUntilSuccessResets(t *testing.T) {
+	s := New(zerolog.Nop(), Config{
+		CooldownAfter: 2,
+		CooldownBase:  time.Hour,
+		CooldownMax:   time.Hour,
+	})
+	lastRun := time.Now()
+	s.states["job"] = &jobState{lastResult: "failure", lastRun: &lastRun, failStreak: 2}
+
+	if err := s.beforeRun("job"); err == nil {
+		t.Fatalf("expected run to be skipped during cool-down")
+	}
+	if s.InFlight() != 0 {
+		t.Fatalf("expected skipped run not to be marked running")
+	}
+
+	// A success resets the streak, so the next run goes ahead.
+	s.states["job"].failStreak = 0
+	if err := s.beforeRun("job"); err != nil {
+		t.Fatalf("expected run after reset, got: %v", err)
+	}
+	if s.InFlight() != 1 {
+		t.Fatalf("expected run to be marked running")
+	}
+}
+
+func TestCooldownDisabledByDefault(t *testing.T) {
+	s := New(zerolog.Nop(), Config{})
+	if got := s.cooldownFor(100); got != 0 {
+		t.Fatalf("expected no cool-down when disabled, got %v", got)
+	}
+}
+
+func TestAppendRunKeepsLastN(t *testing.T) {
+	var runs []RecentRun
+	for i := 0; i < 25; i++ {
+		runs = appendRun(runs, RecentRun{Duration: time.Duration(i)}, 10)
+	}
+	if len(runs) != 10 {
+		t.Fatalf("expected 10 runs, got %d", len(runs))
+	}
+	if runs[0].Duration != 15 || runs[9].Duration != 24 {
+		t.Fatalf("expected runs 15..24, got %v..%v", runs[0].Duration, runs[9].Duration)
+	}
+}
+
+func TestHistorySizeDefault(t *testing.T) {
+	if got := New(zerolog.Nop(), Config{}).historySize(); got != 10 {
+		t.Fatalf("expected default history size 10, got %d", got)
+	}
+	if got := New(zerolog.Nop(), Config{HistorySize: 5}).historySize(); got != 5 {
+		t.Fatalf("expected configured history size 5, got %d", got)
+	}
+}
+
+func TestWithRunInfoRecordsSlowestPipelineOnce(t *testing.T) {
+	st := &jobState{runInfo: &task.RunInfo{SlowestPipeline: "players", SlowestPipelineSeconds: 42.5}}
+
+	r := withRunInfo(RecentRun{Result: "success"}, st)
+	if r.SlowestPipeline != "players" || r.SlowestPipelineSeconds != 42.5 {
+		t.Fatalf("expected slowest players 42.5s, got %q %v", r.SlowestPipeline, r.SlowestPipelineSeconds)
+	}
+	body, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"slowest_pipeline":"players","slowest_pipeline_seconds":42.5`) {
+		t.Fatalf("expected slowest pipeline in history JSON, got %s", body)
+	}
+
+	if r := withRunInfo(RecentRun{Result: "success"}, st); r.SlowestPipeline != "" {
+		t.Fatalf("expected run info consumed by the first run, got %q", r.SlowestPipeline)
+	}
+	body, _ = json.Marshal(RecentRun{Result: "failure"})
+	if strings.Contains(string(body), "slowest_pipeline") {
+		t.Fatalf("expected no slowest pipeline when unknown, got %s", body)
+	}
+}
+
+// failingTask reports each run on runs and always fails.
+type failingTask struct{ runs chan struct{} }
+
+func (t *failingTask) Name() string { return "failing" }
+
+func (t *failingTask) Run(ctx context.Context) error {
+	t.runs <- struct{}{}
+	return errors.New("still failing")
+}
+
+func TestWatchdogForcesOverdueRun(t *testing.T) {
+	s := New(zerolog.Nop(), Config{WatchdogMaxInterval: 24 * time.Hour})
+	clock := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return clock }
+
+	tk := &failingTask{runs: make(chan struct{}, 1)}
+	// Yearly, so only the watchdog runs it during the test.
+	if err := s.Register(JobDef{Name: "nightly", Schedule: "0 0 1 1 *", Task: tk, Singleton: true}); err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	defer s.Shutdown(context.Background())
+
+	expectRun := func(want bool) {
+		t.Helper()
+		s.checkWatchdog()
+		wait := 100 * time.Millisecond
+		if want {
+			wait = 2 * time.Second
+		}
+		select {
+		case <-tk.runs:
+			if !want {
+				t.Fatalf("unexpected forced run at %v", clock)
+			}
+			for deadline := time.Now().Add(time.Second); s.InFlight() > 0; time.Sleep(time.Millisecond) {
+				if time.Now().After(deadline) {
+					t.Fatalf("forced run did not finish")
+				}
+			}
+		case <-time.After(wait):
+			if want {
+				t.Fatalf("expected a forced run at %v", clock)
+			}
+		}
+	}
+
+	clock = clock.Add(23 * time.Hour)
+	expectRun(false)
+
+	clock = clock.Add(2 * time.Hour) // 25h without a success
+	expectRun(true)
+
+	// The forced run failed; it is not forced again until another interval passes.
+	clock = clock.Add(time.Hour)
+	expectRun(false)
+	clock = clock.Add(24 * time.Hour)
+	expectRun(true)
+}
+
+func TestWatchdogCountsDryRunsAsFresh(t *testing.T) {
+	s := New(zerolog.Nop(), Config{WatchdogMaxInterval: 24 * time.Hour})
+	clock := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return clock }
+
+	tk := &failingTask{runs: make(chan struct{}, 1)}
+	if err := s.Register(JobDef{Name: "nightly", Schedule: "0 0 1 1 *", Task: tk, Singleton: true}); err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	defer s.Shutdown(context.Background())
+
+	clock = clock.Add(25 * time.Hour) // no success since startup
+	s.mu.Lock()
+	s.states["nightly"].lastDryRun = clock.Add(-time.Hour)
+	s.mu.Unlock()
+
+	s.checkWatchdog()
+	select {
+	case <-tk.runs:
+		t.Fatalf("expected no forced run an hour after a dry run")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestStateFileSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	ran := time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)
+
+	s := New(zerolog.Nop(), Config{StateFile: path})
+	s.states["nightly"] = &jobState{
+		lastRun:      &ran,
+		lastResult:   "running", // a later run in progress is not saved as the result
+		lastError:    "boom",
+		lastDuration: 90 * time.Second,
+		runCount:     3,
+		failStreak:   1,
+		recentRuns:   []RecentRun{{TriggeredAt: ran, Duration: 90 * time.Second, Result: "failure", Error: "boom"}},
+	}
+	s.failStreak = 1
+	s.saveState()
+
+	restarted := New(zerolog.Nop(), Config{StateFile: path})
+	tk := &failingTask{runs: make(chan struct{}, 1)}
+	if err := restarted.Register(JobDef{Name: "nightly", Schedule: "0 0 1 1 *", Task: tk}); err != nil {
+		t.Fatal(err)
+	}
+	st := restarted.Statuses()[0]
+	if st.LastRun == nil || !st.LastRun.Equal(ran) || st.LastResult != "failure" || st.LastError != "boom" ||
+		st.LastDuration != "1m30s" || st.RunCount != 3 || st.ConsecutiveFailures != 1 {
+		t.Fatalf("expected restored state, got %+v", st)
+	}
+	if len(st.RecentRuns) != 1 || st.RecentRuns[0].Duration != 90*time.Second || st.RecentRuns[0].Error != "boom" {
+		t.Fatalf("expected restored history, got %+v", st.RecentRuns)
+	}
+	if restarted.ConsecutiveFailures() != 1 {
+		t.Fatalf("expected restored failure streak, got %d", restarted.ConsecutiveFailures())
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fresh := New(zerolog.Nop(), Config{StateFile: path})
+	if err := fresh.Register(JobDef{Name: "nightly", Schedule: "0 0 1 1 *", Task: tk}); err != nil {
+		t.Fatal(err)
+	}
+	if st := fresh.Statuses()[0]; st.LastResult != "never" || st.RunCount != 0 {
+		t.Fatalf("expected a corrupt state file to start fresh, got %+v", st)
+	}
+}
+
+func TestCompressedStateFileSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json.gz")
+	ran := time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)
+
+	s := New(zerolog.Nop(), Config{StateFile: path, CompressState: true})
+	s.states["nightly"] = &jobState{lastRun: &ran, lastResult: "success", runCount: 4}
+	s.saveState()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(b, []byte{0x1f, 0x8b}) {
+		t.Fatalf("expected a gzipped state file, got %q", b)
+	}
+
+	// Turning compression off between restarts still reads the old file.
+	restarted := New(zerolog.Nop(), Config{StateFile: path})
+	tk := &failingTask{runs: make(chan struct{}, 1)}
+	if err := restarted.Register(JobDef{Name: "nightly", Schedule: "0 0 1 1 *", Task: tk}); err != nil {
+		t.Fatal(err)
+	}
+	if st := restarted.Statuses()[0]; st.LastResult != "success" || st.RunCount != 4 {
+		t.Fatalf("expected restored state, got %+v", st)
+	}
+}
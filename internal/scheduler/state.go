@@ -0,0 +1,182 @@
+package scheduler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// savedState is the STATE_FILE document: enough of each job's state for
+// /status and /ready to carry on across a restart.
+type savedState struct {
+	SavedAt             time.Time           `json:"saved_at"`
+	ConsecutiveFailures int                 `json:"consecutive_failures"`
+	Jobs                map[string]savedJob `json:"jobs"`
+}
+
+type savedJob struct {
+	LastRun             *time.Time  `json:"last_run,omitempty"`
+	LastResult          string      `json:"last_result"`
+	LastError           string      `json:"last_error,omitempty"`
+	LastDurationMs      int64       `json:"last_duration_ms"`
+	LastSuccess         *time.Time  `json:"last_success,omitempty"`
+	RunCount            uint64      `json:"run_count"`
+	ConsecutiveFailures int         `json:"consecutive_failures"`
+	RecentRuns          []RecentRun `json:"recent_runs,omitempty"`
+}
+
+// loadState reads the state file, if configured. A missing file starts
+// fresh silently; an unreadable or corrupt one starts fresh with a warning.
+func (s *Scheduler) loadState() {
+	path := s.cfg.StateFile
+	if path == "" {
+		return
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	var st savedState
+	if err == nil {
+		b, err = gunzipIfCompressed(b)
+	}
+	if err == nil {
+		err = json.Unmarshal(b, &st)
+	}
+	if err != nil {
+		s.log.Warn().Err(err).Str("path", path).Msg("state_file_ignored")
+		return
+	}
+	s.saved = st.Jobs
+	s.failStreak = st.ConsecutiveFailures
+	s.log.Info().
+		Str("path", path).
+		Int("jobs", len(st.Jobs)).
+		Time("saved_at", st.SavedAt).
+		Msg("state_restored")
+}
+
+// restore fills a newly registered job's state from the state file. Callers
+// hold s.mu.
+func (s *Scheduler) restore(name string, st *jobState) {
+	saved, ok := s.saved[name]
+	if !ok {
+		return
+	}
+	delete(s.saved, name)
+	st.lastRun = saved.LastRun
+	st.lastResult = saved.LastResult
+	if st.lastResult == "" {
+		st.lastResult = "never"
+	}
+	st.lastError = saved.LastError
+	st.lastDuration = time.Duration(saved.LastDurationMs) * time.Millisecond
+	st.lastSuccess = saved.LastSuccess
+	st.runCount = saved.RunCount
+	st.failStreak = saved.ConsecutiveFailures
+	if runs := saved.RecentRuns; len(runs) > s.historySize() {
+		st.recentRuns = runs[len(runs)-s.historySize():]
+	} else {
+		st.recentRuns = runs
+	}
+}
+
+// saveState writes every job's state to the state file, if configured. The
+// file is replaced atomically, so a crash mid-write leaves the previous one.
+func (s *Scheduler) saveState() {
+	path := s.cfg.StateFile
+	if path == "" {
+		return
+	}
+	// Serialize writers so an older snapshot never replaces a newer one.
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.RLock()
+	doc := savedState{
+		SavedAt:             time.Now().UTC(),
+		ConsecutiveFailures: s.failStreak,
+		Jobs:                make(map[string]savedJob, len(s.states)),
+	}
+	for name, st := range s.states {
+		doc.Jobs[name] = savedJob{
+			LastRun:             st.lastRun,
+			LastResult:          settledResult(st),
+			LastError:           st.lastError,
+			LastDurationMs:      st.lastDuration.Milliseconds(),
+			LastSuccess:         st.lastSuccess,
+			RunCount:            st.runCount,
+			ConsecutiveFailures: st.failStreak,
+			RecentRuns:          st.recentRuns,
+		}
+	}
+	s.mu.RUnlock()
+
+	if err := writeStateFile(path, doc, s.cfg.CompressState); err != nil {
+		s.log.Warn().Err(err).Str("path", path).Msg("state_file_write_failed")
+	}
+}
+
+// settledResult is the job's last finished result: a run still in progress
+// is not a result worth restoring.
+func settledResult(st *jobState) string {
+	if st.lastResult != "running" {
+		return st.lastResult
+	}
+	if n := len(st.recentRuns); n > 0 {
+		return st.recentRuns[n-1].Result
+	}
+	return "never"
+}
+
+// gunzipIfCompressed returns b, decompressed first if it starts with the
+// gzip magic bytes.
+func gunzipIfCompressed(b []byte) ([]byte, error) {
+	if !bytes.HasPrefix(b, []byte{0x1f, 0x8b}) {
+		return b, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+func writeStateFile(path string, doc savedState, compress bool) error {
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	body = append(body, '\n')
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
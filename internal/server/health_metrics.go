@@ -0,0 +1,50 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"cron-runner/internal/scheduler"
+)
+
+// writeHealthMetrics renders health indicators in the Prometheus text
+// exposition format for monitors that can parse text but not scrape.
+// Per-job series are only emitted once the job has data for them.
+func writeHealthMetrics(w io.Writer, statuses []scheduler.JobStatus, now time.Time) {
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+
+	fmt.Fprintln(w, "# HELP cron_runner_up Whether the cron-runner service is up.")
+	fmt.Fprintln(w, "# TYPE cron_runner_up gauge")
+	fmt.Fprintln(w, "cron_runner_up 1")
+
+	fmt.Fprintln(w, "# HELP cron_runner_last_run_success Whether the job's last completed run succeeded.")
+	fmt.Fprintln(w, "# TYPE cron_runner_last_run_success gauge")
+	for _, st := range statuses {
+		if st.RunCount == 0 {
+			continue
+		}
+		v := 0
+		if st.ConsecutiveFailures == 0 {
+			v = 1
+		}
+		fmt.Fprintf(w, "cron_runner_last_run_success{job=%q} %d\n", st.Name, v)
+	}
+
+	fmt.Fprintln(w, "# HELP cron_runner_consecutive_failures Failed runs since the job's last success.")
+	fmt.Fprintln(w, "# TYPE cron_runner_consecutive_failures gauge")
+	for _, st := range statuses {
+		fmt.Fprintf(w, "cron_runner_consecutive_failures{job=%q} %d\n", st.Name, st.ConsecutiveFailures)
+	}
+
+	fmt.Fprintln(w, "# HELP cron_runner_seconds_since_last_success Seconds since the job last succeeded.")
+	fmt.Fprintln(w, "# TYPE cron_runner_seconds_since_last_success gauge")
+	for _, st := range statuses {
+		if st.LastSuccess == nil {
+			continue
+		}
+		fmt.Fprintf(w, "cron_runner_seconds_since_last_success{job=%q} %g\n",
+			st.Name, now.Sub(*st.LastSuccess).Seconds())
+	}
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// newPprofServer serves the net/http/pprof handlers under /debug/pprof/ on
+// their own listener, so profiles are never reachable on the port that
+// serves /trigger.
+func newPprofServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return &http.Server{
+		Addr:        addr,
+		Handler:     mux,
+		ReadTimeout: 5 * time.Second,
+		// No WriteTimeout: CPU profiles and traces stream for ?seconds=N.
+		IdleTimeout: 60 * time.Second,
+	}
+}
+
+// servePprof runs the pprof listener until Shutdown.
+func (s *Server) servePprof() {
+	s.log.Warn().Str("addr", s.pprofServer.Addr).Msg("pprof_enabled")
+	if err := s.pprofServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		s.log.Error().Err(err).Str("addr", s.pprofServer.Addr).Msg("pprof_server_error")
+	}
+}
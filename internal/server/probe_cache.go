@@ -0,0 +1,68 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// probeResponse is a computed /health or /ready response.
+type probeResponse struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+type cachedProbe struct {
+	resp    probeResponse
+	expires time.Time
+}
+
+// probeCache reuses a computed probe response for ttl, so a storm of probes
+// computes each response (and takes the scheduler's locks) at most once per
+// ttl. With ttl 0 every probe is computed.
+type probeCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedProbe
+}
+
+func newProbeCache(ttl time.Duration) *probeCache {
+	return &probeCache{ttl: ttl, now: time.Now, entries: make(map[string]cachedProbe)}
+}
+
+// get returns the cached response for key, or calls compute and caches its
+// result. Concurrent probes for the same key wait for a single computation.
+func (c *probeCache) get(key string, compute func() probeResponse) probeResponse {
+	if c.ttl <= 0 {
+		return compute()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if e, ok := c.entries[key]; ok && now.Before(e.expires) {
+		return e.resp
+	}
+	resp := compute()
+	c.entries[key] = cachedProbe{resp: resp, expires: now.Add(c.ttl)}
+	return resp
+}
+
+// invalidate drops every cached response, for state changes that must show
+// up immediately (shutdown).
+func (c *probeCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// writeProbe writes resp, omitting the body for HEAD.
+func writeProbe(w http.ResponseWriter, r *http.Request, resp probeResponse) {
+	w.Header().Set("Content-Type", resp.contentType)
+	w.WriteHeader(resp.status)
+	if r.Method != http.MethodHead {
+		w.Write(resp.body)
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"cron-runner/internal/retry"
+)
+
+// maxRetriesOverrideLimit bounds max_retries in a /trigger retry override.
+const maxRetriesOverrideLimit = 10
+
+// triggerRequest is the optional JSON body of POST /trigger/{name}.
+type triggerRequest struct {
+	Retry *retryOverride `json:"retry"`
+}
+
+// retryOverride replaces individual retry settings for a single /trigger run.
+// Unset fields keep the client's defaults. Durations use Go syntax ("500ms").
+type retryOverride struct {
+	MaxRetries     *int     `json:"max_retries"`
+	InitialBackoff string   `json:"initial_backoff"`
+	MaxBackoff     string   `json:"max_backoff"`
+	BackoffFactor  *float64 `json:"backoff_factor"`
+}
+
+// apply returns base with the override's fields applied, or an error if any
+// of them is out of range.
+func (o *retryOverride) apply(base retry.Config) (retry.Config, error) {
+	cfg := base
+	if o.MaxRetries != nil {
+		if *o.MaxRetries < 0 || *o.MaxRetries > maxRetriesOverrideLimit {
+			return base, fmt.Errorf("max_retries must be between 0 and %d, got %d", maxRetriesOverrideLimit, *o.MaxRetries)
+		}
+		cfg.MaxRetries = *o.MaxRetries
+	}
+	if o.InitialBackoff != "" {
+		d, err := time.ParseDuration(o.InitialBackoff)
+		if err != nil || d <= 0 {
+			return base, fmt.Errorf("initial_backoff must be a positive duration, got %q", o.InitialBackoff)
+		}
+		cfg.InitialBackoff = d
+	}
+	if o.MaxBackoff != "" {
+		d, err := time.ParseDuration(o.MaxBackoff)
+		if err != nil || d <= 0 {
+			return base, fmt.Errorf("max_backoff must be a positive duration, got %q", o.MaxBackoff)
+		}
+		cfg.MaxBackoff = d
+	}
+	if o.BackoffFactor != nil {
+		if *o.BackoffFactor < 1 {
+			return base, fmt.Errorf("backoff_factor must be at least 1, got %v", *o.BackoffFactor)
+		}
+		cfg.BackoffFactor = *o.BackoffFactor
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		return base, fmt.Errorf("max_backoff (%v) must not be less than initial_backoff (%v)", cfg.MaxBackoff, cfg.InitialBackoff)
+	}
+	return cfg, nil
+}
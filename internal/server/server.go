@@ -1,73 +1,559 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
 	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"cron-runner/internal/metrics"
+	"cron-runner/internal/pipeline"
+	"cron-runner/internal/resources"
 	"cron-runner/internal/scheduler"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 // Server provides health and status HTTP endpoints for Railway health checks
 // and operational visibility.
 type Server struct {
-	httpServer *http.Server
-	sched      *scheduler.Scheduler
-	log        zerolog.Logger
+	httpServer  *http.Server
+	pprofServer *http.Server // nil unless PprofPort is set
+	unixSocket  string       // optional second listener; empty = TCP only
+	sched       *scheduler.Scheduler
+	client      *pipeline.Client
+	metrics     *metrics.Metrics
+	log         zerolog.Logger
+
+	// triggerToken, when set, is the bearer token /trigger requires.
+	triggerToken string
+
+	// debounce coalesces /trigger calls for a pipeline within this long of
+	// its last accepted one onto that run (0 = off). debounceMu also
+	// serializes taking the run lock, so a burst cannot slip past it.
+	debounce   time.Duration
+	debounceMu sync.Mutex
+	lastRuns   map[string]acceptedRun // pipeline → last accepted /trigger
+
+	metadata map[string]string // RUN_METADATA, echoed in /trigger responses
+
+	maxFailures  int                // consecutive failed runs that make /ready fail; 0 = never
+	shuttingDown atomic.Bool        // set by MarkShuttingDown; makes /ready fail
+	probes       *probeCache        // /health and /ready responses, reused for ProbeCacheTTL
+	resources    *resources.Checker // nil = /trigger never checks resources
+	stuckAfter   time.Duration      // run without status change that fails /livez; 0 = never
+
+	// runCtx is the parent of runs started via /trigger; cancelled on Shutdown.
+	runCtx    context.Context
+	cancelRun context.CancelFunc
+	runs      sync.WaitGroup // in-flight /trigger runs
+}
+
+// Config holds HTTP server settings.
+type Config struct {
+	Port string
+
+	// BindAddr is the interface address to listen on, e.g. 127.0.0.1
+	// (empty = all interfaces).
+	BindAddr string
+
+	// TriggerToken is the bearer token /trigger and /cancel require
+	// (empty = open).
+	TriggerToken string
+
+	// RunMetadata (RUN_METADATA) is echoed in every /trigger response.
+	RunMetadata map[string]string
+
+	// TriggerDebounce makes /trigger calls for a pipeline within this long
+	// of an accepted one share its run instead of starting another
+	// (0 = off).
+	TriggerDebounce time.Duration
+
+	// MaxConsecutiveFailures makes /ready fail once this many runs in a row
+	// have failed, until the next success (0 = never).
+	MaxConsecutiveFailures int
+
+	// UnixSocket, when set, is a Unix domain socket path the endpoints are
+	// also served on, alongside Port.
+	UnixSocket string
+
+	// ProbeCacheTTL reuses each computed /health and /ready response for
+	// this long, so a probe storm stays cheap (0 = compute every probe).
+	ProbeCacheTTL time.Duration
+
+	// Resources, when set, makes /trigger return 503 while the node is
+	// short on disk or memory.
+	Resources *resources.Checker
+
+	// StuckThreshold makes /livez fail while a polled run has shown no
+	// status change for this long (0 = /livez always passes).
+	StuckThreshold time.Duration
+
+	// PprofPort, when set, serves /debug/pprof/ on this port of
+	// PprofBindAddr, on a listener separate from Port.
+	PprofPort     string
+	PprofBindAddr string
 }
 
-func New(port string, sched *scheduler.Scheduler, log zerolog.Logger) *Server {
+func New(cfg Config, sched *scheduler.Scheduler, client *pipeline.Client, m *metrics.Metrics, log zerolog.Logger) *Server {
+	runCtx, cancelRun := context.WithCancel(context.Background())
 	s := &Server{
-		sched: sched,
-		log:   log.With().Str("component", "http-server").Logger(),
+		sched:        sched,
+		client:       client,
+		unixSocket:   cfg.UnixSocket,
+		metrics:      m,
+		log:          log.With().Str("component", "http-server").Logger(),
+		triggerToken: cfg.TriggerToken,
+		debounce:     cfg.TriggerDebounce,
+		lastRuns:     make(map[string]acceptedRun),
+		metadata:     cfg.RunMetadata,
+		maxFailures:  cfg.MaxConsecutiveFailures,
+		probes:       newProbeCache(cfg.ProbeCacheTTL),
+		resources:    cfg.Resources,
+		stuckAfter:   cfg.StuckThreshold,
+		runCtx:       runCtx,
+		cancelRun:    cancelRun,
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/ready", s.handleReady)
+	mux.HandleFunc("/livez", s.handleLivez)
 	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("POST /trigger/{name}", s.requireTriggerToken(s.handleTrigger))
+	mux.HandleFunc("POST /cancel/{job_id}", s.requireTriggerToken(s.handleCancel))
 
 	s.httpServer = &http.Server{
-		Addr:         ":" + port,
+		Addr:         net.JoinHostPort(cfg.BindAddr, cfg.Port),
 		Handler:      mux,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	if cfg.PprofPort != "" {
+		s.pprofServer = newPprofServer(net.JoinHostPort(cfg.PprofBindAddr, cfg.PprofPort))
+	}
 
 	return s
 }
 
 // Start runs the HTTP server. Blocking — call in a goroutine.
 func (s *Server) Start() {
+	if s.unixSocket != "" {
+		go s.serveUnix()
+	}
+	if s.pprofServer != nil {
+		go s.servePprof()
+	}
 	s.log.Info().Str("addr", s.httpServer.Addr).Msg("http_server_starting")
 	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		s.log.Error().Err(err).Msg("http_server_error")
 	}
 }
 
-// Shutdown gracefully stops the HTTP server.
+// serveUnix serves the same handler on the Unix domain socket. A socket file
+// left by an unclean exit is removed first; the listener unlinks the socket
+// when Shutdown closes it.
+func (s *Server) serveUnix() {
+	if err := os.Remove(s.unixSocket); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		s.log.Error().Err(err).Str("socket", s.unixSocket).Msg("unix_socket_remove_failed")
+		return
+	}
+	ln, err := net.Listen("unix", s.unixSocket)
+	if err != nil {
+		s.log.Error().Err(err).Str("socket", s.unixSocket).Msg("unix_socket_listen_failed")
+		return
+	}
+	s.log.Info().Str("socket", s.unixSocket).Msg("http_server_starting_unix")
+	if err := s.httpServer.Serve(ln); err != nil && err != http.ErrServerClosed {
+		s.log.Error().Err(err).Str("socket", s.unixSocket).Msg("http_server_error")
+	}
+}
+
+// MarkShuttingDown makes /ready fail from now on, so traffic is routed away
+// while running jobs drain.
+func (s *Server) MarkShuttingDown() {
+	s.shuttingDown.Store(true)
+	s.probes.invalidate()
+}
+
+// Shutdown gracefully stops the HTTP server, then cancels runs started via
+// /trigger and waits for them to wind down until ctx expires.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	s.MarkShuttingDown()
+	err := s.httpServer.Shutdown(ctx)
+	if s.pprofServer != nil {
+		// In-flight profiles are not worth waiting for.
+		s.pprofServer.Close()
+	}
+	s.cancelRun()
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.log.Info().Dur("waited", time.Since(start)).Msg("trigger_runs_drained")
+	case <-ctx.Done():
+		s.log.Warn().Dur("waited", time.Since(start)).Msg("trigger_runs_drain_timeout_exceeded")
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	return err
 }
 
-// GET /health — Railway health check.
-// Returns 200 {"status":"ok"} when the service is running.
+// GET|HEAD /health — Railway health check.
+// Returns 200 {"status":"ok"} when the service is running. HEAD returns the
+// status code only; any other method is rejected with 405. With
+// CIRCUIT_FAILURE_THRESHOLD set, the body also carries the backend circuit
+// breaker's state under "circuit"; the status stays 200 either way. In
+// DRY_RUN mode it also carries "dry_run": true. While any job's last run
+// succeeded with some pipelines in a "warn" status, it returns 200
+// {"status":"degraded","degraded_jobs":[...]}.
+// ?format=prometheus returns key health indicators in Prometheus text format.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	if !allowProbeMethod(w, r) {
+		return
+	}
+	if r.URL.Query().Get("format") == "prometheus" {
+		writeProbe(w, r, s.probes.get("health_prometheus", s.healthMetrics))
+		return
+	}
+	writeProbe(w, r, s.probes.get("health", s.health))
+}
+
+func (s *Server) health() probeResponse {
+	body := map[string]any{"status": "ok"}
+	if jobs := s.sched.DegradedJobs(); len(jobs) > 0 {
+		body["status"], body["degraded_jobs"] = "degraded", jobs
+	}
+	if cs, ok := s.client.CircuitState(); ok {
+		body["circuit"] = cs
+	}
+	if s.client.DryRun() {
+		body["dry_run"] = true
+	}
+	return jsonProbe(http.StatusOK, body)
+}
+
+func (s *Server) healthMetrics() probeResponse {
+	var buf bytes.Buffer
+	writeHealthMetrics(&buf, s.sched.Statuses(), time.Now())
+	return probeResponse{
+		status:      http.StatusOK,
+		contentType: "text/plain; version=0.0.4; charset=utf-8",
+		body:        buf.Bytes(),
+	}
+}
+
+// GET|HEAD /ready — Readiness probe.
+// Returns 200 {"ready":true,...} normally, and 503 {"ready":false,...} once
+// shutdown has begun or, with MAX_CONSECUTIVE_FAILURES set, while that many
+// runs in a row have failed. The next successful run makes it ready again.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if !allowProbeMethod(w, r) {
+		return
+	}
+	writeProbe(w, r, s.probes.get("ready", s.ready))
+}
+
+func (s *Server) ready() probeResponse {
+	failures := s.sched.ConsecutiveFailures()
+	body := map[string]any{
+		"ready":                    true,
+		"consecutive_failures":     failures,
+		"max_consecutive_failures": s.maxFailures,
+	}
+	status := http.StatusOK
+	switch {
+	case s.shuttingDown.Load():
+		body["ready"], body["reason"] = false, "shutting_down"
+		status = http.StatusServiceUnavailable
+	case s.maxFailures > 0 && failures >= s.maxFailures:
+		body["ready"], body["reason"] = false, "consecutive_failures"
+		status = http.StatusServiceUnavailable
+	}
+	return jsonProbe(status, body)
+}
+
+// GET|HEAD /livez — Kubernetes liveness probe.
+// Returns 200 {"alive":true} unless a polled run has been in progress with
+// no status change for LIVENESS_STUCK_THRESHOLD, meaning the poller or the
+// backend job is wedged. Then it returns 503 with reason "stuck_poller",
+// the run and job IDs, and the time of the last status change, so the pod
+// is restarted.
+func (s *Server) handleLivez(w http.ResponseWriter, r *http.Request) {
+	if !allowProbeMethod(w, r) {
+		return
+	}
+	writeProbe(w, r, s.probes.get("livez", s.livez))
+}
+
+func (s *Server) livez() probeResponse {
+	body := map[string]any{"alive": true}
+	p, ok := s.client.Progress()
+	if s.stuckAfter <= 0 || !ok || !p.Running {
+		return jsonProbe(http.StatusOK, body)
+	}
+	if stuck := time.Since(p.LastProgressAt); stuck > s.stuckAfter {
+		body["alive"], body["reason"] = false, "stuck_poller"
+		body["run_id"] = p.RunID
+		body["job_id"] = p.JobID
+		body["last_progress_at"] = p.LastProgressAt
+		body["last_poll_at"] = p.LastPollAt
+		body["stuck_seconds"] = stuck.Seconds()
+		return jsonProbe(http.StatusServiceUnavailable, body)
+	}
+	return jsonProbe(http.StatusOK, body)
+}
+
+// jsonProbe encodes body as a JSON probe response.
+func jsonProbe(status int, body any) probeResponse {
+	b, _ := json.Marshal(body) // maps of plain values always encode
+	return probeResponse{status: status, contentType: "application/json", body: append(b, '\n')}
+}
+
+// allowProbeMethod accepts GET and HEAD for probe endpoints and writes a 405
+// for everything else. Returns false if the request was rejected.
+func allowProbeMethod(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
 }
 
 // GET /status — Current state of all registered jobs.
 // Returns scheduler uptime and per-job last_run, next_run, last_result, run_count.
+// Once a polled pipeline job has run, pipeline_job holds its live progress
+// (latest polled status, elapsed time) or, when idle, the last run's summary.
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
+	body := map[string]any{
 		"scheduler": "running",
 		"uptime":    s.sched.Uptime(),
 		"jobs":      s.sched.Statuses(),
-	})
+	}
+	if p, ok := s.client.Progress(); ok {
+		body["pipeline_job"] = p
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+// GET /metrics — Prometheus scrape endpoint.
+// Exposes per-job run counts by result, run duration histograms, retry
+// counts and whether a run is in progress, plus Go runtime and process
+// metrics.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.metrics.Handler().ServeHTTP(w, r)
+}
+
+// triggerResponse is the 202 body of /trigger.
+type triggerResponse struct {
+	Status   string            `json:"status"` // "accepted" or "coalesced"
+	Pipeline string            `json:"pipeline"`
+	RunID    string            `json:"run_id"`
+	Metadata map[string]string `json:"metadata,omitempty"` // RUN_METADATA
+}
+
+// acceptedRun is a /trigger call that started a run, for TRIGGER_DEBOUNCE.
+type acceptedRun struct {
+	runID string
+	at    time.Time
+}
+
+// maxTriggerBodyBytes bounds the /trigger request body.
+const maxTriggerBodyBytes = 4 << 10
+
+// requireTriggerToken guards /trigger and /cancel. It rejects requests
+// without "Authorization: Bearer <token>" matching the configured trigger
+// token with 401. With no token configured every request is let through.
+func (s *Server) requireTriggerToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.triggerToken == "" {
+			next(w, r)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.triggerToken)) != 1 {
+			s.log.Warn().Str("path", r.URL.Path).Str("remote_addr", r.RemoteAddr).Msg("trigger_unauthorized")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cron-runner"`)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"status": "unauthorized"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// POST /trigger/{name} — Start a single named pipeline and poll it in the background.
+// Returns 202 {"status":"accepted","run_id":"..."} once started; every log
+// line of the run carries that run_id. Returns 400 for a name outside
+// ^[a-z0-9_-]+$, and 409 {"status":"already_running"} while another run is in progress.
+// Requires a bearer token when TRIGGER_AUTH_TOKEN is set (401 otherwise).
+// Within TRIGGER_DEBOUNCE of an accepted trigger of the same pipeline, it
+// starts nothing and returns 202 {"status":"coalesced"} with that run's ID.
+// An optional body {"retry":{...}} overrides the retry settings used to start
+// this run only; invalid overrides are rejected with 400.
+func (s *Server) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	w.Header().Set("Content-Type", "application/json")
+
+	if !pipeline.ValidPipelineName(name) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"status": "invalid_pipeline", "pipeline": name})
+		return
+	}
+
+	retryCfg := s.client.RetryConfig()
+	if r.ContentLength != 0 {
+		var body triggerRequest
+		dec := json.NewDecoder(io.LimitReader(r.Body, maxTriggerBodyBytes))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&body); err != nil && err != io.EOF {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"status": "invalid_body", "pipeline": name, "error": err.Error()})
+			return
+		}
+		if body.Retry != nil {
+			cfg, err := body.Retry.apply(retryCfg)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"status": "invalid_retry", "pipeline": name, "error": err.Error()})
+				return
+			}
+			retryCfg = cfg
+			s.log.Info().
+				Str("pipeline", name).
+				Int("max_retries", cfg.MaxRetries).
+				Dur("initial_backoff", cfg.InitialBackoff).
+				Dur("max_backoff", cfg.MaxBackoff).
+				Float64("backoff_factor", cfg.BackoffFactor).
+				Msg("trigger_retry_override")
+		}
+	}
+
+	if err := s.resources.Check(); err != nil {
+		s.log.Warn().Str("pipeline", name).Err(err).Msg("trigger_rejected_resources")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "insufficient_resources", "pipeline": name, "error": err.Error()})
+		return
+	}
+
+	// Take the run lock before replying, so two concurrent requests can
+	// never both get a 202 "accepted".
+	s.debounceMu.Lock()
+	if last, ok := s.lastRuns[name]; ok && s.debounce > 0 && time.Since(last.at) < s.debounce {
+		s.debounceMu.Unlock()
+		s.log.Info().Str("pipeline", name).Str("run_id", last.runID).Msg("trigger_coalesced")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(triggerResponse{Status: "coalesced", Pipeline: name, RunID: last.runID, Metadata: s.metadata})
+		return
+	}
+	run, ok := s.client.Reserve()
+	if !ok {
+		s.debounceMu.Unlock()
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"status": "already_running", "pipeline": name})
+		return
+	}
+	runID := pipeline.NewRunID()
+	if s.debounce > 0 {
+		s.lastRuns[name] = acceptedRun{runID: runID, at: time.Now()}
+	}
+	s.debounceMu.Unlock()
+
+	// Carry the caller's OTel baggage (tenant, priority, ...) to the backend;
+	// the run itself outlives the request, so it hangs off runCtx.
+	ctx := baggage.ContextWithBaggage(s.runCtx,
+		baggage.FromContext(otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))))
+
+	s.metrics.RunStarted(name)
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		result := run.TriggerOne(pipeline.WithRunID(ctx, runID), name, retryCfg)
+		if result.Skipped {
+			s.metrics.RunSkipped(name)
+		} else {
+			s.metrics.RunFinished(name, result.Success, result.Duration, result.Attempts)
+		}
+		switch {
+		case result.Skipped:
+			s.log.Warn().Str("pipeline", name).Msg("trigger_skipped_already_running")
+		case errors.Is(result.Error, context.Canceled):
+			s.log.Warn().
+				Str("pipeline", name).
+				Str("run_id", runID).
+				Str("job_id", result.JobID).
+				Dur("duration", result.Duration).
+				Msg("trigger_cancelled")
+		case !result.Success:
+			s.log.Error().
+				Str("pipeline", name).
+				Str("run_id", runID).
+				Func(pipeline.SlowestFields(result.JobDetails)).
+				Err(result.Error).
+				Msg("trigger_failed")
+		default:
+			s.log.Info().
+				Str("pipeline", name).
+				Str("run_id", runID).
+				Str("job_id", result.JobID).
+				Dur("duration", result.Duration).
+				Bool("dry_run", result.DryRun).
+				Func(pipeline.SlowestFields(result.JobDetails)).
+				Msg("trigger_completed")
+		}
+	}()
+
+	s.log.Info().Str("pipeline", name).Str("run_id", runID).Msg("trigger_accepted")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(triggerResponse{Status: "accepted", Pipeline: name, RunID: runID, Metadata: s.metadata})
+}
+
+// POST /cancel/{job_id} — Ask the backend to abort a running pipeline job.
+// Returns 200 {"status":"cancel_requested"} once the backend accepted the
+// cancel, or if the job had already finished; 400 for a malformed job ID and
+// 502 if the backend call failed. A TriggerAll polling the job stops once it
+// sees the "cancelled" status. Requires the trigger token when configured.
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("job_id")
+	w.Header().Set("Content-Type", "application/json")
+
+	err := s.client.CancelJob(r.Context(), jobID)
+	switch {
+	case errors.Is(err, pipeline.ErrInvalidJobID):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"status": "invalid_job_id", "job_id": jobID})
+		return
+	case err != nil:
+		s.log.Error().Err(err).Str("job_id", jobID).Msg("cancel_failed")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"status": "cancel_failed", "job_id": jobID, "error": err.Error()})
+		return
+	}
+
+	s.log.Info().Str("job_id", jobID).Msg("cancel_requested")
+	json.NewEncoder(w).Encode(map[string]string{"status": "cancel_requested", "job_id": jobID})
 }
@@ -0,0 +1,1104 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cron-runner/internal/config"
+	"cron-runner/internal/metrics"
+	"cron-runner/internal/pipeline"
+	"cron-runner/internal/resources"
+	"cron-runner/internal/scheduler"
+	"cron-runner/internal/task"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func newTestServer() *Server {
+	return newTestServerWithBackend("http://127.0.0.1:0")
+}
+
+func newTestServerWithBackend(backendURL string) *Server {
+	return newTestServerWithConfig(backendURL, Config{Port: "0"})
+}
+
+func newTestServerWithConfig(backendURL string, cfg Config) *Server {
+	log := zerolog.New(io.Discard)
+	client, err := pipeline.NewClient(&config.Config{
+		BackendURL:          backendURL,
+		PipelineAuth:        "test-token",
+		RequestTimeout:      time.Second,
+		PollInitialInterval: time.Millisecond,
+		PollMaxInterval:     time.Millisecond,
+		PollMaxWaitTime:     time.Second,
+	}, log)
+	if err != nil {
+		panic(err)
+	}
+	return New(cfg, scheduler.New(log, scheduler.Config{}), client, metrics.New(), log)
+}
+
+func TestHealthGet(t *testing.T) {
+	srv := newTestServer()
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if rec.Body.Len() == 0 {
+		t.Fatalf("expected non-empty body for GET")
+	}
+}
+
+func TestHealthReportsCircuitBreaker(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	client, err := pipeline.NewClient(&config.Config{
+		BackendURL:              "http://127.0.0.1:0",
+		RequestTimeout:          time.Second,
+		CircuitFailureThreshold: 1,
+		CircuitCooldown:         time.Hour,
+	}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := New(Config{Port: "0"}, scheduler.New(log, scheduler.Config{}), client, metrics.New(), log)
+
+	health := func() map[string]any {
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		return body
+	}
+
+	if c, _ := health()["circuit"].(map[string]any); c["state"] != "closed" {
+		t.Fatalf("expected closed circuit, got %v", c)
+	}
+
+	// Nothing listens on port 0, so the trigger fails and opens the breaker.
+	client.TriggerEndpoint(context.Background(), "/v1/internal/alerts")
+	c, _ := health()["circuit"].(map[string]any)
+	if c["state"] != "open" || c["open_until"] == nil {
+		t.Fatalf("expected open circuit with open_until, got %v", c)
+	}
+}
+
+func TestHealthReportsDryRun(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	client, err := pipeline.NewClient(&config.Config{
+		BackendURL:     "http://127.0.0.1:0",
+		RequestTimeout: time.Second,
+		DryRun:         true,
+	}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := New(Config{Port: "0"}, scheduler.New(log, scheduler.Config{}), client, metrics.New(), log)
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body["dry_run"] != true {
+		t.Fatalf("expected dry_run true in /health, got %v", body)
+	}
+}
+
+func TestHealthReportsDegradedRun(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed","results":{
+			"box-scores":{"pipeline_name":"box-scores","status":"completed"},
+			"odds":{"pipeline_name":"odds","status":"warning"}}}}`)
+	}))
+	defer backend.Close()
+
+	log := zerolog.New(io.Discard)
+	client, err := pipeline.NewClient(&config.Config{
+		BackendURL:          backend.URL,
+		PipelineAuth:        "test-token",
+		RequestTimeout:      time.Second,
+		PollInitialInterval: time.Millisecond,
+		PollMaxInterval:     time.Millisecond,
+		PollMaxWaitTime:     time.Second,
+		StatusPolicy:        map[string]string{"warning": pipeline.OutcomeWarn},
+	}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := scheduler.New(log, scheduler.Config{})
+	tk := &task.PollTask{Client: client, Endpoint: "/v1/internal/pipelines/post-game", Log: log}
+	if err := sched.Register(scheduler.JobDef{Name: "post-game", Schedule: "0 0 1 1 *", Task: tk}); err != nil {
+		t.Fatal(err)
+	}
+	sched.Start()
+	defer sched.Shutdown(context.Background())
+	srv := New(Config{Port: "0"}, sched, client, metrics.New(), log)
+
+	if err := sched.RunNow("post-game"); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(2 * time.Second); sched.Statuses()[0].LastResult != "degraded"; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a degraded run, got %+v", sched.Statuses()[0])
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if rec.Code != http.StatusOK || body["status"] != "degraded" {
+		t.Fatalf("expected 200 with status degraded, got %d %v", rec.Code, body)
+	}
+	if jobs, _ := body["degraded_jobs"].([]any); len(jobs) != 1 || jobs[0] != "post-game" {
+		t.Fatalf("expected post-game in degraded_jobs, got %v", body["degraded_jobs"])
+	}
+}
+
+func TestHealthHead(t *testing.T) {
+	srv := newTestServer()
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/health", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("expected empty body for HEAD, got %q", rec.Body.String())
+	}
+}
+
+type failingTask struct{}
+
+func (failingTask) Name() string                  { return "failing" }
+func (failingTask) Run(ctx context.Context) error { return errors.New("backend unreachable") }
+
+func TestReadyFailsAfterConsecutiveFailures(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	sched := scheduler.New(log, scheduler.Config{})
+	if err := sched.Register(scheduler.JobDef{Name: "failing", Schedule: "0 0 1 1 *", Task: failingTask{}}); err != nil {
+		t.Fatal(err)
+	}
+	sched.Start()
+	defer sched.Shutdown(context.Background())
+
+	client, err := pipeline.NewClient(&config.Config{BackendURL: "http://127.0.0.1:0", PipelineAuth: "t"}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := New(Config{Port: "0", MaxConsecutiveFailures: 2}, sched, client, metrics.New(), log)
+
+	ready := func() (int, string) {
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return rec.Code, rec.Body.String()
+	}
+	failRun := func(want int) {
+		if err := sched.RunNow("failing"); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(time.Second)
+		for sched.ConsecutiveFailures() < want {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d consecutive failures, got %d", want, sched.ConsecutiveFailures())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	if code, _ := ready(); code != http.StatusOK {
+		t.Fatalf("expected ready before any failure, got %d", code)
+	}
+	failRun(1)
+	if code, body := ready(); code != http.StatusOK || !strings.Contains(body, `"consecutive_failures":1`) {
+		t.Fatalf("expected ready below the threshold, got %d %s", code, body)
+	}
+	failRun(2)
+	code, body := ready()
+	if code != http.StatusServiceUnavailable || !strings.Contains(body, `"reason":"consecutive_failures"`) {
+		t.Fatalf("expected unready at the threshold, got %d %s", code, body)
+	}
+}
+
+func TestReadyFailsWhenShuttingDown(t *testing.T) {
+	srv := newTestServer()
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	srv.MarkShuttingDown()
+	rec = httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 while shutting down, got %d", rec.Code)
+	}
+}
+
+func TestHealthPostRejected(t *testing.T) {
+	srv := newTestServer()
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/health", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status 405, got %d", rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "GET, HEAD" {
+		t.Fatalf("expected Allow header %q, got %q", "GET, HEAD", allow)
+	}
+}
+
+func TestHealthPrometheusFormat(t *testing.T) {
+	now := time.Date(2026, 3, 9, 22, 0, 0, 0, time.UTC)
+	lastSuccess := now.Add(-90 * time.Second)
+	statuses := []scheduler.JobStatus{
+		{Name: "pre-game", RunCount: 3, LastSuccess: &lastSuccess},
+		{Name: "post-game", RunCount: 2, ConsecutiveFailures: 2},
+		{Name: "live-stats"},
+	}
+
+	var buf bytes.Buffer
+	writeHealthMetrics(&buf, statuses, now)
+
+	want := []string{
+		"cron_runner_up 1",
+		`cron_runner_last_run_success{job="pre-game"} 1`,
+		`cron_runner_last_run_success{job="post-game"} 0`,
+		`cron_runner_consecutive_failures{job="post-game"} 2`,
+		`cron_runner_consecutive_failures{job="live-stats"} 0`,
+		`cron_runner_seconds_since_last_success{job="pre-game"} 90`,
+	}
+	lines := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if strings.HasPrefix(line, "#") {
+			if !strings.HasPrefix(line, "# HELP ") && !strings.HasPrefix(line, "# TYPE ") {
+				t.Fatalf("unexpected comment line %q", line)
+			}
+			continue
+		}
+		if len(strings.Fields(line)) != 2 {
+			t.Fatalf("expected \"name value\" sample, got %q", line)
+		}
+		lines[line] = true
+	}
+	for _, w := range want {
+		if !lines[w] {
+			t.Fatalf("expected line %q in output:\n%s", w, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), `cron_runner_last_run_success{job="live-stats"}`) {
+		t.Fatalf("expected no last_run_success series for a job that never ran")
+	}
+}
+
+func TestHealthPrometheusContentType(t *testing.T) {
+	srv := newTestServer()
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health?format=prometheus", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("expected text/plain content type, got %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "cron_runner_up 1") {
+		t.Fatalf("expected cron_runner_up in body, got %q", rec.Body.String())
+	}
+}
+
+func TestTriggerStartsNamedPipeline(t *testing.T) {
+	started := make(chan string, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			started <- r.URL.Path
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed"}}`)
+	}))
+	defer backend.Close()
+
+	srv := newTestServerWithBackend(backend.URL)
+	defer srv.Shutdown(context.Background())
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/trigger/post-game", nil))
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", rec.Code)
+	}
+	select {
+	case path := <-started:
+		if path != "/v1/internal/pipelines/post-game" {
+			t.Fatalf("expected per-pipeline endpoint, got %q", path)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the pipeline to be started")
+	}
+}
+
+func TestTriggerEchoesRunID(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed"}}`)
+	}))
+	defer backend.Close()
+
+	srv := newTestServerWithConfig(backend.URL, Config{Port: "0", RunMetadata: map[string]string{"trigger_commit": "abc123"}})
+	defer srv.Shutdown(context.Background())
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/trigger/post-game", nil))
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", rec.Code)
+	}
+	var body triggerResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if len(body.RunID) != 16 {
+		t.Fatalf("expected a 16-char hex run_id, got %q", body.RunID)
+	}
+	if body.Metadata["trigger_commit"] != "abc123" {
+		t.Fatalf("expected RUN_METADATA in the response, got %v", body.Metadata)
+	}
+}
+
+func TestTriggerSurvivesCallerDisconnect(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			<-release
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed"}}`)
+	}))
+	defer backend.Close()
+
+	srv := newTestServerWithBackend(backend.URL)
+	ctx, disconnect := context.WithCancel(context.Background())
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/trigger/post-game", nil).WithContext(ctx))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", rec.Code)
+	}
+
+	// The caller goes away while the job start is still in flight.
+	disconnect()
+	close(release)
+	srv.runs.Wait()
+
+	rec = httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w := `cron_runner_runs_total{job="post-game",result="success"} 1`; !strings.Contains(rec.Body.String(), w) {
+		t.Fatalf("expected the run to complete and be recorded, want %q in:\n%s", w, rec.Body.String())
+	}
+}
+
+func TestTriggerRejectsInvalidName(t *testing.T) {
+	srv := newTestServer()
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/trigger/Post.Game", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
+
+// starvedProbe reports a nearly full disk and bloated memory.
+type starvedProbe struct{}
+
+func (starvedProbe) DiskFree(string) (uint64, error) { return 1 << 20, nil }
+func (starvedProbe) Memory() uint64                  { return 4 << 30 }
+
+func TestTriggerRejectedWhenResourcesLow(t *testing.T) {
+	var calls atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer backend.Close()
+
+	srv := newTestServerWithBackend(backend.URL)
+	srv.resources = resources.NewWithProbe(resources.Limits{
+		DiskPath:    "/data",
+		MinDiskFree: 100 << 20,
+		MaxMemory:   1 << 30,
+	}, starvedProbe{})
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/trigger/post-game", nil))
+	srv.Shutdown(context.Background())
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rec.Code)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body["status"] != "insufficient_resources" || !strings.Contains(body["error"], "free disk at /data") {
+		t.Fatalf("unexpected body %v", body)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("expected no backend calls, got %d", n)
+	}
+}
+
+func TestTriggerRequiresTokenWhenConfigured(t *testing.T) {
+	srv := newTestServer()
+	srv.triggerToken = "s3cret"
+
+	for _, auth := range []string{"", "Bearer wrong", "s3cret", "Basic s3cret"} {
+		req := httptest.NewRequest(http.MethodPost, "/trigger/Post.Game", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Authorization %q: expected status 401, got %d", auth, rec.Code)
+		}
+	}
+
+	// A valid token gets through to the handler, which rejects the bad name.
+	req := httptest.NewRequest(http.MethodPost, "/trigger/Post.Game", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 past auth, got %d", rec.Code)
+	}
+
+	// Probe endpoints stay open.
+	rec = httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected /health to stay unauthenticated, got %d", rec.Code)
+	}
+}
+
+func TestCancelEndpoint(t *testing.T) {
+	var deleted string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted = r.URL.Path
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer backend.Close()
+
+	srv := newTestServerWithBackend(backend.URL)
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cancel/job-1", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if deleted != "/v1/internal/pipelines/jobs/job-1" {
+		t.Fatalf("expected the job to be cancelled on the backend, got %q", deleted)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cancel/a%5Cb", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an invalid job ID, got %d", rec.Code)
+	}
+}
+
+func TestTriggerRetryOverrideAppliesToThatRunOnly(t *testing.T) {
+	var starts atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		starts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	srv := newTestServerWithBackend(backend.URL)
+	defer srv.Shutdown(context.Background())
+
+	body := `{"retry":{"max_retries":2,"initial_backoff":"1ms","max_backoff":"2ms"}}`
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/trigger/post-game", strings.NewReader(body)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	srv.runs.Wait()
+	if n := starts.Load(); n != 3 {
+		t.Fatalf("expected 3 start attempts with max_retries 2, got %d", n)
+	}
+
+	// Without a body the client's default (no retries) applies again.
+	srv.httpServer.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/trigger/post-game", nil))
+	srv.runs.Wait()
+	if n := starts.Load(); n != 4 {
+		t.Fatalf("expected 1 more start attempt with the default config, got %d", n-3)
+	}
+}
+
+func TestTriggerRejectsInvalidRetryOverride(t *testing.T) {
+	srv := newTestServer()
+	for _, body := range []string{
+		`{"retry":{"max_retries":-1}}`,
+		`{"retry":{"max_retries":50}}`,
+		`{"retry":{"initial_backoff":"soon"}}`,
+		`{"retry":{"backoff_factor":0.5}}`,
+		`{"retry":{"initial_backoff":"10s","max_backoff":"1s"}}`,
+		`{"retries":{}}`,
+	} {
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/trigger/post-game", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("body %s: expected status 400, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestTriggerConflictWhileRunning(t *testing.T) {
+	release := make(chan struct{})
+	polling := make(chan struct{}, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		select {
+		case polling <- struct{}{}:
+		default:
+		}
+		<-release
+		io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed"}}`)
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	srv := newTestServerWithBackend(backend.URL)
+	defer srv.Shutdown(context.Background())
+	srv.httpServer.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/trigger/pre-game", nil))
+	<-polling
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/trigger/pre-game", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"already_running"`) {
+		t.Fatalf("expected already_running body, got %q", rec.Body.String())
+	}
+}
+
+func TestTriggerConcurrentRequestsAcceptOnlyOne(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		<-release
+		io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed"}}`)
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	srv := newTestServerWithBackend(backend.URL)
+	defer srv.Shutdown(context.Background())
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 20)
+	for i := 0; i < cap(codes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/trigger/pre-game", nil))
+			codes <- rec.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	accepted := 0
+	for code := range codes {
+		switch code {
+		case http.StatusAccepted:
+			accepted++
+		case http.StatusConflict:
+		default:
+			t.Fatalf("expected 202 or 409, got %d", code)
+		}
+	}
+	if accepted != 1 {
+		t.Fatalf("expected exactly one accepted trigger, got %d", accepted)
+	}
+}
+
+func TestTriggerDebounceCoalescesIntoOneRun(t *testing.T) {
+	release := make(chan struct{})
+	var starts atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			starts.Add(1)
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		<-release
+		io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed"}}`)
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	srv := newTestServerWithConfig(backend.URL, Config{Port: "0", TriggerDebounce: time.Minute})
+	defer srv.Shutdown(context.Background())
+
+	var bodies []map[string]string
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/trigger/pre-game", nil))
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("trigger %d: expected 202, got %d %s", i, rec.Code, rec.Body.String())
+		}
+		var body map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		bodies = append(bodies, body)
+	}
+
+	if bodies[0]["status"] != "accepted" || bodies[1]["status"] != "coalesced" || bodies[2]["status"] != "coalesced" {
+		t.Fatalf("expected one accepted and two coalesced triggers, got %v", bodies)
+	}
+	for _, b := range bodies[1:] {
+		if b["run_id"] != bodies[0]["run_id"] {
+			t.Fatalf("expected coalesced triggers to share run_id %q, got %v", bodies[0]["run_id"], bodies)
+		}
+	}
+	for deadline := time.Now().Add(time.Second); starts.Load() == 0 && time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+	}
+	if got := starts.Load(); got != 1 {
+		t.Fatalf("expected one backend job, got %d", got)
+	}
+}
+
+func TestTriggerPropagatesBaggageToBackend(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.Baggage{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	got := make(chan string, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			got <- r.Header.Get("Baggage")
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed"}}`)
+	}))
+	defer backend.Close()
+
+	srv := newTestServerWithBackend(backend.URL)
+	defer srv.Shutdown(context.Background())
+
+	req := httptest.NewRequest(http.MethodPost, "/trigger/pre-game", nil)
+	req.Header.Set("Baggage", "tenant=acme,priority=high")
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+
+	select {
+	case header := <-got:
+		members, err := baggage.Parse(header)
+		if err != nil {
+			t.Fatalf("backend got invalid baggage %q: %v", header, err)
+		}
+		if members.Member("tenant").Value() != "acme" || members.Member("priority").Value() != "high" {
+			t.Fatalf("expected the caller's baggage at the backend, got %q", header)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("backend never received the start request")
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	srv := newTestServer()
+	srv.metrics.RunStarted("pre-game")
+	srv.metrics.RunFinished("pre-game", true, time.Second, 2)
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("expected text/plain content type, got %q", ct)
+	}
+	for _, w := range []string{
+		`cron_runner_runs_total{job="pre-game",result="success"} 1`,
+		`cron_runner_retries_total{job="pre-game"} 1`,
+		`go_goroutines `,
+	} {
+		if !strings.Contains(rec.Body.String(), w) {
+			t.Fatalf("expected %q in body:\n%s", w, rec.Body.String())
+		}
+	}
+}
+
+func TestShutdownCancelsAndWaitsForTriggerRuns(t *testing.T) {
+	polling := make(chan struct{}, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		select {
+		case polling <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+	}))
+	defer backend.Close()
+
+	var buf bytes.Buffer
+	srv := newTestServerWithBackend(backend.URL)
+	srv.log = zerolog.New(&buf)
+	srv.httpServer.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/trigger/pre-game", nil))
+	<-polling
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("expected the run to wind down before the timeout, got: %v", err)
+	}
+	if srv.client.Busy() {
+		t.Fatalf("expected no run in progress after shutdown")
+	}
+	for _, msg := range []string{`"trigger_cancelled"`, `"trigger_runs_drained"`} {
+		if !strings.Contains(buf.String(), msg) {
+			t.Fatalf("expected %s in logs:\n%s", msg, buf.String())
+		}
+	}
+}
+
+func TestProbeCacheReusesComputationWithinTTL(t *testing.T) {
+	now := time.Now()
+	c := newProbeCache(100 * time.Millisecond)
+	c.now = func() time.Time { return now }
+
+	var mu sync.Mutex
+	computes := 0
+	compute := func() probeResponse {
+		mu.Lock()
+		defer mu.Unlock()
+		computes++
+		return probeResponse{status: http.StatusOK, body: []byte("ok")}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 500; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp := c.get("health", compute); string(resp.body) != "ok" {
+				t.Errorf("unexpected body %q", resp.body)
+			}
+		}()
+	}
+	wg.Wait()
+	if computes != 1 {
+		t.Fatalf("expected 1 computation for rapid probes, got %d", computes)
+	}
+
+	now = now.Add(100 * time.Millisecond)
+	c.get("health", compute)
+	if computes != 2 {
+		t.Fatalf("expected recomputation after the TTL, got %d computations", computes)
+	}
+}
+
+func TestProbeCacheDisabledComputesEveryProbe(t *testing.T) {
+	c := newProbeCache(0)
+	computes := 0
+	for i := 0; i < 3; i++ {
+		c.get("ready", func() probeResponse { computes++; return probeResponse{} })
+	}
+	if computes != 3 {
+		t.Fatalf("expected 3 computations, got %d", computes)
+	}
+}
+
+func TestReadyCacheInvalidatedOnShutdown(t *testing.T) {
+	srv := newTestServer()
+	srv.probes = newProbeCache(time.Hour)
+
+	probe := func() int {
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return rec.Code
+	}
+	if code := probe(); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	srv.MarkShuttingDown()
+	if code := probe(); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected shutdown to bypass the cached 200, got %d", code)
+	}
+}
+
+func TestStatusReportsPipelineJobProgress(t *testing.T) {
+	done := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		select {
+		case <-done:
+			io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed","pipelines_total":3,"pipelines_completed":3}}`)
+		default:
+			io.WriteString(w, `{"data":{"job_id":"job-1","status":"running","pipelines_total":3,"pipelines_completed":1,"current_pipeline":"players"}}`)
+		}
+	}))
+	defer backend.Close()
+
+	srv := newTestServerWithBackend(backend.URL)
+	defer srv.Shutdown(context.Background())
+
+	status := func() map[string]any {
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		return body
+	}
+	if _, ok := status()["pipeline_job"]; ok {
+		t.Fatalf("expected no pipeline_job before the first run")
+	}
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/trigger/post-game", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", rec.Code)
+	}
+
+	var job map[string]any
+	for deadline := time.Now().Add(time.Second); ; {
+		pj, _ := status()["pipeline_job"].(map[string]any)
+		if j, ok := pj["job"].(map[string]any); ok {
+			if pj["running"] != true || pj["job_id"] != "job-1" {
+				t.Fatalf("expected running job-1, got %v", pj)
+			}
+			job = j
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no polled status published, got %v", pj)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if job["current_pipeline"] != "players" || job["pipelines_completed"] != 1.0 || job["pipelines_total"] != 3.0 {
+		t.Fatalf("expected players at 1/3, got %v", job)
+	}
+
+	close(done)
+	for deadline := time.Now().Add(time.Second); srv.client.Busy(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("run did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	pj := status()["pipeline_job"].(map[string]any)
+	if pj["running"] != false {
+		t.Fatalf("expected finished run, got %v", pj)
+	}
+	if summary, _ := pj["summary"].(string); !strings.HasPrefix(summary, "run job-1: success, 3/3 pipelines") {
+		t.Fatalf("expected last run summary, got %q", summary)
+	}
+}
+
+func TestLivezFailsWhilePollerIsStuck(t *testing.T) {
+	done := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		select {
+		case <-done:
+			io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed","pipelines_total":3,"pipelines_completed":3}}`)
+		default:
+			io.WriteString(w, `{"data":{"job_id":"job-1","status":"running","pipelines_total":3,"pipelines_completed":1}}`)
+		}
+	}))
+	defer backend.Close()
+
+	srv := newTestServerWithBackend(backend.URL)
+	srv.stuckAfter = 50 * time.Millisecond
+	defer srv.Shutdown(context.Background())
+
+	livez := func() (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		return rec.Code, body
+	}
+	if code, _ := livez(); code != http.StatusOK {
+		t.Fatalf("expected 200 before any run, got %d", code)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/trigger/post-game", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", rec.Code)
+	}
+
+	for deadline := time.Now().Add(time.Second); ; {
+		code, body := livez()
+		if code == http.StatusServiceUnavailable {
+			if body["reason"] != "stuck_poller" || body["job_id"] != "job-1" || body["last_progress_at"] == nil {
+				t.Fatalf("unexpected stuck body %v", body)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected /livez to fail while the job is stuck, got %d %v", code, body)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	close(done)
+	for deadline := time.Now().Add(time.Second); srv.client.Busy(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("run did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if code, body := livez(); code != http.StatusOK {
+		t.Fatalf("expected 200 once the run finished, got %d %v", code, body)
+	}
+}
+
+func TestServesOnUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "cr") // t.TempDir paths can exceed the socket path limit
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "cron-runner.sock")
+	if err := os.WriteFile(sock, nil, 0o600); err != nil { // stale file from an unclean exit
+		t.Fatal(err)
+	}
+
+	srv := newTestServer()
+	srv.unixSocket = sock
+	srv.httpServer.Addr = "127.0.0.1:0"
+	go srv.Start()
+
+	hc := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", sock)
+		},
+	}}
+	var resp *http.Response
+	for deadline := time.Now().Add(time.Second); ; {
+		if resp, err = hc.Get("http://unix/health"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GET /health over the socket: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	hc.CloseIdleConnections()
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if _, err := os.Stat(sock); !os.IsNotExist(err) {
+		t.Fatalf("expected socket removed on shutdown, got %v", err)
+	}
+}
+
+func TestPprofServedOnlyOnItsOwnListener(t *testing.T) {
+	srv := newTestServer()
+	if srv.pprofServer != nil {
+		t.Fatalf("expected pprof disabled by default")
+	}
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected no pprof on the main listener, got %d", rec.Code)
+	}
+
+	log := zerolog.New(io.Discard)
+	srv = New(Config{Port: "8082", PprofPort: "6060", PprofBindAddr: "127.0.0.1"}, scheduler.New(log, scheduler.Config{}), srv.client, metrics.New(), log)
+	if srv.pprofServer == nil || srv.pprofServer.Addr != "127.0.0.1:6060" {
+		t.Fatalf("expected pprof listener on 127.0.0.1:6060, got %+v", srv.pprofServer)
+	}
+	rec = httptest.NewRecorder()
+	srv.pprofServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine profile") {
+		t.Fatalf("expected a goroutine profile, got %d %q", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected no pprof on the main listener, got %d", rec.Code)
+	}
+}
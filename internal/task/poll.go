@@ -3,30 +3,80 @@ package task
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"cron-runner/internal/k8sevents"
+	"cron-runner/internal/manifest"
+	"cron-runner/internal/metrics"
 	"cron-runner/internal/pipeline"
+	"cron-runner/internal/runevent"
 
 	"github.com/rs/zerolog"
 )
 
 // PollTask starts a pipeline job at an endpoint and polls until it completes.
+// If Manifest is set, a per-run manifest file is written after each run.
+// If Metrics is set, run counts, durations and retries are recorded.
+// If Baseline is set, successful runs outside its duration band are flagged.
+// If Events is set, each run outcome is recorded as a Kubernetes Event.
+// If Emitter is set, a run_completed event is written after each run.
 type PollTask struct {
 	Client   *pipeline.Client
 	Endpoint string
 	Log      zerolog.Logger
+	Manifest *manifest.Writer    // optional; nil = no manifests
+	Metrics  *metrics.Metrics    // optional; nil = no metrics
+	Baseline *metrics.Baseline   // optional; nil = no duration alerting
+	Events   *k8sevents.Recorder // optional; nil = no Kubernetes Events
+	Emitter  *runevent.Emitter   // optional; nil = no run_completed events
 }
 
 func (t *PollTask) Name() string { return "poll:" + t.Endpoint }
 
 func (t *PollTask) Run(ctx context.Context) error {
+	jobName := jobNameFromEndpoint(t.Endpoint)
+	if t.Metrics != nil {
+		t.Metrics.RunStarted(jobName)
+	}
+
+	triggeredAt := time.Now()
 	result := t.Client.TriggerAll(ctx, t.Endpoint)
+	if result.Skipped {
+		if t.Metrics != nil {
+			t.Metrics.RunSkipped(jobName)
+		}
+		t.Log.Warn().Msg("poll_skipped_already_running")
+		return nil
+	}
+	recordRunInfo(ctx, result)
+	if t.Metrics != nil {
+		t.Metrics.RunFinished(jobName, result.Success, time.Since(triggeredAt), result.Attempts)
+	}
+
+	if t.Events != nil {
+		t.Events.RunFinished(jobName, result.Success, time.Since(triggeredAt), result.Error)
+	}
+
+	if t.Manifest != nil || t.Emitter != nil {
+		m := manifestFor(t.Endpoint, triggeredAt, time.Now(), result)
+		if t.Manifest != nil {
+			t.Manifest.Write(m)
+		}
+		if t.Emitter != nil {
+			t.Emitter.RunCompleted(runEventFor(m))
+		}
+	}
+
 	if !result.Success {
 		return fmt.Errorf("poll failed after %d attempts: %w", result.Attempts, result.Error)
 	}
+	checkDuration(t.Log, t.Metrics, t.Baseline, jobName, result.Duration)
 	t.Log.Info().
 		Str("job_id", result.JobID).
 		Int("attempts", result.Attempts).
+		Bool("degraded", result.Degraded).
 		Dur("duration", result.Duration).
+		Float64("duration_seconds", result.Duration.Seconds()).
 		Msg("poll_succeeded")
 	return nil
 }
@@ -0,0 +1,27 @@
+package task
+
+import "context"
+
+// RunInfo carries details of a finished run from a task back to the
+// scheduler, which keeps them in the job's run history.
+type RunInfo struct {
+	SlowestPipeline        string  // pipeline that dominated the run; empty if unknown
+	SlowestPipelineSeconds float64 // its duration
+	DryRun                 bool    // DRY_RUN: nothing was sent to the backend
+	Degraded               bool    // succeeded, but some pipelines ended in a "warn" status
+}
+
+type runInfoKey struct{}
+
+// WithRunInfo returns a context carrying a fresh RunInfo for a task to fill.
+func WithRunInfo(ctx context.Context) (context.Context, *RunInfo) {
+	info := &RunInfo{}
+	return context.WithValue(ctx, runInfoKey{}, info), info
+}
+
+// runInfoFrom returns the RunInfo attached to ctx, or nil when the task was
+// not run by the scheduler.
+func runInfoFrom(ctx context.Context) *RunInfo {
+	info, _ := ctx.Value(runInfoKey{}).(*RunInfo)
+	return info
+}
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"time"
 
+	"cron-runner/internal/k8sevents"
+	"cron-runner/internal/manifest"
+	"cron-runner/internal/metrics"
 	"cron-runner/internal/pipeline"
 	"cron-runner/internal/reporter"
+	"cron-runner/internal/runevent"
 
 	"github.com/rs/zerolog"
 )
@@ -14,20 +18,44 @@ import (
 // TriggerTask posts to an endpoint once and returns.
 // Retries are handled by the pipeline client.
 // If Reporter is set, an execution report is pushed to the data-platform after each run.
+// If Manifest is set, a per-run manifest file is written after each run.
+// If Metrics is set, run counts, durations and retries are recorded.
+// If Baseline is set, successful runs outside its duration band are flagged.
+// If Events is set, each run outcome is recorded as a Kubernetes Event.
+// If Emitter is set, a run_completed event is written after each run.
 type TriggerTask struct {
 	Client   *pipeline.Client
 	Endpoint string
 	Log      zerolog.Logger
-	Reporter *reporter.Reporter // optional; nil = no push reporting
+	Reporter *reporter.Reporter  // optional; nil = no push reporting
+	Manifest *manifest.Writer    // optional; nil = no manifests
+	Metrics  *metrics.Metrics    // optional; nil = no metrics
+	Baseline *metrics.Baseline   // optional; nil = no duration alerting
+	Events   *k8sevents.Recorder // optional; nil = no Kubernetes Events
+	Emitter  *runevent.Emitter   // optional; nil = no run_completed events
 }
 
 func (t *TriggerTask) Name() string { return "trigger:" + t.Endpoint }
 
 func (t *TriggerTask) Run(ctx context.Context) error {
+	jobName := jobNameFromEndpoint(t.Endpoint)
+	if t.Metrics != nil {
+		t.Metrics.RunStarted(jobName)
+	}
+
 	triggeredAt := time.Now()
 	result := t.Client.TriggerEndpoint(ctx, t.Endpoint)
 	completedAt := time.Now()
 	durationMs := completedAt.Sub(triggeredAt).Milliseconds()
+	recordRunInfo(ctx, result)
+
+	if t.Metrics != nil {
+		t.Metrics.RunFinished(jobName, result.Success, completedAt.Sub(triggeredAt), result.Attempts)
+	}
+
+	if t.Events != nil {
+		t.Events.RunFinished(jobName, result.Success, completedAt.Sub(triggeredAt), result.Error)
+	}
 
 	if t.Reporter != nil {
 		var httpStatus *int
@@ -45,7 +73,7 @@ func (t *TriggerTask) Run(ctx context.Context) error {
 			res = "failure"
 		}
 		t.Reporter.Report(
-			jobNameFromEndpoint(t.Endpoint),
+			jobName,
 			triggeredAt,
 			completedAt,
 			durationMs,
@@ -57,17 +85,109 @@ func (t *TriggerTask) Run(ctx context.Context) error {
 		)
 	}
 
+	if t.Manifest != nil || t.Emitter != nil {
+		m := manifestFor(t.Endpoint, triggeredAt, completedAt, result)
+		if t.Manifest != nil {
+			t.Manifest.Write(m)
+		}
+		if t.Emitter != nil {
+			t.Emitter.RunCompleted(runEventFor(m))
+		}
+	}
+
 	if !result.Success {
 		return fmt.Errorf("trigger failed after %d attempts: %w", result.Attempts, result.Error)
 	}
+	checkDuration(t.Log, t.Metrics, t.Baseline, jobName, result.Duration)
 	t.Log.Info().
 		Int("attempts", result.Attempts).
 		Int("status_code", result.StatusCode).
 		Dur("duration", result.Duration).
+		Float64("duration_seconds", result.Duration.Seconds()).
 		Msg("trigger_succeeded")
 	return nil
 }
 
+// checkDuration warns, and counts a deviation metric, when a successful run's
+// duration falls outside the baseline band.
+func checkDuration(log zerolog.Logger, m *metrics.Metrics, b *metrics.Baseline, job string, d time.Duration) {
+	if b == nil {
+		return
+	}
+	dev := b.Deviation(d)
+	if dev == "" {
+		return
+	}
+	if m != nil {
+		m.RunDeviated(job, dev)
+	}
+	log.Warn().
+		Str("deviation", dev).
+		Dur("duration", d).
+		Dur("expected_duration", b.Expected).
+		Float64("deviation_pct", b.DeviationPct).
+		Msg("run_duration_deviation")
+}
+
+// manifestFor builds the run manifest for a finished trigger or poll.
+func manifestFor(endpoint string, triggeredAt, completedAt time.Time, result pipeline.TriggerResult) manifest.Manifest {
+	m := manifest.Manifest{
+		JobName:     jobNameFromEndpoint(endpoint),
+		Endpoint:    endpoint,
+		JobID:       result.JobID,
+		TriggeredAt: triggeredAt,
+		CompletedAt: completedAt,
+		DurationMs:  completedAt.Sub(triggeredAt).Milliseconds(),
+		Result:      "success",
+		HTTPStatus:  result.StatusCode,
+		Attempts:    result.Attempts,
+	}
+	if !result.Success {
+		m.Result = "failure"
+	} else if result.Degraded {
+		m.Result = "degraded"
+	}
+	if result.Error != nil {
+		m.Error = result.Error.Error()
+	}
+	if result.JobDetails != nil {
+		m.Pipelines = result.JobDetails.Results
+	}
+	return m
+}
+
+// runEventFor builds the run_completed event for a finished run from its
+// manifest.
+func runEventFor(m manifest.Manifest) runevent.Event {
+	return runevent.Event{
+		Job:         m.JobName,
+		Endpoint:    m.Endpoint,
+		JobID:       m.JobID,
+		Result:      m.Result,
+		Success:     m.Result != "failure",
+		Attempts:    m.Attempts,
+		HTTPStatus:  m.HTTPStatus,
+		TriggeredAt: m.TriggeredAt,
+		CompletedAt: m.CompletedAt,
+		DurationMs:  m.DurationMs,
+		Error:       m.Error,
+	}
+}
+
+// recordRunInfo passes the slowest pipeline of a finished run, and whether
+// it was a dry run, back to the scheduler's run history.
+func recordRunInfo(ctx context.Context, result pipeline.TriggerResult) {
+	info := runInfoFrom(ctx)
+	if info == nil {
+		return
+	}
+	info.DryRun = result.DryRun
+	info.Degraded = result.Degraded
+	if name, secs, ok := result.JobDetails.Slowest(); ok {
+		info.SlowestPipeline, info.SlowestPipelineSeconds = name, secs
+	}
+}
+
 // jobNameFromEndpoint extracts a short job name from the endpoint path.
 // e.g. "/v1/internal/pipelines/pre-game" → "pre-game"
 func jobNameFromEndpoint(endpoint string) string {
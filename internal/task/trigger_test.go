@@ -0,0 +1,60 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cron-runner/internal/config"
+	"cron-runner/internal/notify"
+	"cron-runner/internal/pipeline"
+
+	"github.com/rs/zerolog"
+)
+
+func TestTriggerTaskFailureNotifiesWebhook(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "backend down", http.StatusInternalServerError)
+	}))
+	defer backend.Close()
+
+	got := make(chan notify.Failure, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var f notify.Failure
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			t.Errorf("bad webhook body: %v", err)
+		}
+		got <- f
+	}))
+	defer hook.Close()
+
+	client, err := pipeline.NewClient(&config.Config{
+		BackendURL:        backend.URL,
+		PipelineAuth:      "test-token",
+		RequestTimeout:    time.Second,
+		FailureWebhookURL: hook.URL,
+		RunMetadata:       map[string]string{"trigger_source": "cron"},
+	}, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tk := &TriggerTask{Client: client, Endpoint: "/v1/internal/pipelines/post-game", Log: zerolog.Nop()}
+	if err := tk.Run(context.Background()); err == nil {
+		t.Fatalf("expected the scheduled run to fail")
+	}
+	client.WaitNotifications(context.Background())
+
+	select {
+	case f := <-got:
+		if f.Endpoint != tk.Endpoint || f.StatusCode != http.StatusInternalServerError || f.Metadata["trigger_source"] != "cron" {
+			t.Fatalf("unexpected failure notification %+v", f)
+		}
+	default:
+		t.Fatalf("expected a failure notification for the scheduled run")
+	}
+}
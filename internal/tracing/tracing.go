@@ -0,0 +1,76 @@
+// Package tracing sets up OpenTelemetry tracing and, optionally, run result
+// log records. It is opt-in: without an OTLP endpoint the global tracer and
+// logger providers stay the default no-ops, so the spans and records created
+// throughout the runner cost next to nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/rs/zerolog"
+)
+
+const serviceName = "cron-runner"
+
+// Setup installs a global tracer provider exporting spans over OTLP/HTTP to
+// endpoint (e.g. http://otel-collector:4318), plus the W3C trace-context and
+// baggage propagators used for outgoing requests. The other OTEL_EXPORTER_OTLP_*
+// variables (headers, timeout, ...) are read by the exporter as usual.
+// With an empty endpoint only the propagators are installed, so baggage sent
+// to /trigger still reaches the backend. The returned shutdown flushes
+// buffered spans and must be called before exit.
+func Setup(ctx context.Context, endpoint string, log zerolog.Logger) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Warn().Err(err).Msg("tracing export error")
+	}))
+	log.Info().Str("endpoint", endpoint).Msg("OpenTelemetry tracing enabled")
+	return tp.Shutdown, nil
+}
+
+// SetupLogs installs a global logger provider exporting OpenTelemetry log
+// records over OTLP/HTTP to endpoint, batched like spans. Records emitted
+// with a span in their context carry its trace and span IDs. The returned
+// shutdown flushes buffered records and must be called before exit.
+func SetupLogs(ctx context.Context, endpoint string, log zerolog.Logger) (shutdown func(context.Context) error, err error) {
+	exporter, err := otlploghttp.New(ctx, otlploghttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP log exporter: %w", err)
+	}
+
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+		sdklog.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	global.SetLoggerProvider(lp)
+
+	log.Info().Str("endpoint", endpoint).Msg("OpenTelemetry run result logs enabled")
+	return lp.Shutdown, nil
+}
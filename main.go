@@ -2,63 +2,472 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sync"
 	"syscall"
+	"time"
 
 	"cron-runner/internal/config"
 	"cron-runner/internal/jobs"
+	"cron-runner/internal/k8sevents"
 	"cron-runner/internal/logger"
+	"cron-runner/internal/manifest"
+	"cron-runner/internal/metrics"
 	"cron-runner/internal/pipeline"
+	"cron-runner/internal/redact"
 	"cron-runner/internal/reporter"
+	"cron-runner/internal/resources"
+	"cron-runner/internal/runevent"
 	"cron-runner/internal/scheduler"
 	"cron-runner/internal/server"
+	"cron-runner/internal/tracing"
+
+	"github.com/rs/zerolog"
 )
 
+// version and commit are stamped in at build time with
+// -ldflags "-X main.version=... -X main.commit=...". When empty, the
+// values the Go toolchain embedded are reported instead.
+var version, commit string
+
 func main() {
-	cfg, err := config.Load()
+	pipelineName := flag.String("pipeline", "", "run this single pipeline once and exit instead of starting the scheduler")
+	printRuns := flag.Int("print-schedule", 0, "print the next N fire times of every job and exit")
+	configPath := flag.String("config", "", "YAML config file; env vars override its values (default $CONFIG_FILE)")
+	outputPath := flag.String("output", "", "with -pipeline, write the run result as JSON to this file (- for stdout)")
+	dryRun := flag.Bool("dry-run", false, "log the requests runs would send instead of calling the backend (also $DRY_RUN)")
+	flag.Parse()
+
+	if *printRuns > 0 {
+		defs := jobs.RegisterAll(jobs.Deps{Log: zerolog.Nop()})
+		if err := printSchedule(os.Stdout, defs, time.Now(), *printRuns); err != nil {
+			os.Stderr.WriteString("Schedule error: " + err.Error() + "\n")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	path := *configPath
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	cfg, err := config.LoadFile(path)
 	if err != nil {
 		os.Stderr.WriteString("Configuration error: " + err.Error() + "\n")
 		os.Exit(1)
 	}
+	if *dryRun {
+		cfg.DryRun = true
+	}
 
-	log := logger.New(cfg.LogLevel, cfg.LogJSON)
+	log := logger.WithMetadata(logger.New(logOutput(*outputPath), cfg.LogLevel, cfg.LogFormat, cfg.LogDurationUnit, redact.New(cfg.RedactKeys), cfg.Secrets()...), cfg.RunMetadata)
 	log.Info().
 		Str("backend_url", cfg.BackendURL).
 		Str("http_port", cfg.HTTPPort).
+		Str("bind_addr", cfg.BindAddr).
 		Dur("drain_timeout", cfg.DrainTimeout).
 		Msg("cron-runner starting")
+	if cfg.DryRun {
+		log.Warn().Msg("DRY RUN: no requests will be sent to the backend")
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.OtelEndpoint, log)
+	if err != nil {
+		log.Warn().Err(err).Msg("tracing disabled")
+		shutdownTracing = func(context.Context) error { return nil }
+	}
+	if cfg.OtelResultLogs {
+		if shutdownLogs, err := tracing.SetupLogs(context.Background(), cfg.OtelEndpoint, log); err != nil {
+			log.Warn().Err(err).Msg("run result logs disabled")
+		} else {
+			shutdownTraces := shutdownTracing
+			shutdownTracing = func(ctx context.Context) error {
+				return errors.Join(shutdownTraces(ctx), shutdownLogs(ctx))
+			}
+		}
+	}
+
+	client, err := pipeline.NewClient(cfg, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create pipeline client")
+	}
+
+	// Each startup check request is bounded by REQUEST_TIMEOUT; the checks
+	// as a whole may take longer while they retry an unreachable backend
+	// (STARTUP_CHECK_RETRIES), until SIGTERM.
+	startupCtx, stopStartup := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	if cfg.MinBackendVersion != "" && !cfg.DryRun {
+		if err := client.CheckBackendVersion(startupCtx, cfg.BackendVersionPath, cfg.MinBackendVersion); err != nil {
+			log.Fatal().Err(err).Msg("unsupported backend version")
+		}
+	}
+	if cfg.TokenIntrospectPath != "" && !cfg.DryRun {
+		if err := client.CheckTokenScope(startupCtx, cfg.TokenIntrospectPath, cfg.TokenRequiredScope); err != nil {
+			log.Fatal().Err(err).Msg("backend token check failed")
+		}
+	}
+	stopStartup()
+
+	if *pipelineName != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+		m := metrics.New()
+		m.SetBuildInfo(version, commit)
+		code := runPipeline(ctx, client, m, *pipelineName, *outputPath, log)
+		stop()
+		if cfg.PushgatewayURL != "" {
+			pushMetrics(m, cfg.PushgatewayURL, *pipelineName, cfg.PushgatewayTimeout, log)
+		}
+		waitCtx, cancel := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+		client.WaitNotifications(waitCtx)
+		shutdownTracing(waitCtx)
+		cancel()
+		os.Exit(code)
+	}
 
-	client := pipeline.NewClient(cfg, log)
-	rep := reporter.New(cfg.BackendURL, cfg.PipelineAuth, log)
+	// Dry runs never reach the backend, so there is nothing to report.
+	var rep *reporter.Reporter
+	if !cfg.DryRun {
+		// Session-auth mode has no bearer token: the client's transport
+		// carries the session cookie instead.
+		rep = reporter.New(cfg.BackendURL, cfg.APIBasePath, cfg.PipelineAuth, client.Transport(), cfg.RunMetadata, log)
+	}
+
+	var mw *manifest.Writer
+	if cfg.RunManifestDir != "" {
+		mw = manifest.New(cfg.RunManifestDir, cfg, log)
+	}
+
+	var ev *k8sevents.Recorder
+	if cfg.K8sEventsEnabled {
+		if ev, err = k8sevents.InCluster(log); err != nil {
+			log.Warn().Err(err).Msg("kubernetes events disabled")
+		}
+	}
+
+	var re *runevent.Emitter
+	if cfg.RunEventsEnabled {
+		// Run errors can quote backend responses; scrub them like log lines.
+		re = runevent.New(redact.NewWriter(os.Stdout, redact.New(cfg.RedactKeys), cfg.Secrets()...), cfg.RunMetadata, log)
+	}
+
+	m := metrics.New()
+	m.SetBuildInfo(version, commit)
+
+	var bl *metrics.Baseline
+	if cfg.ExpectedDuration > 0 {
+		bl = &metrics.Baseline{Expected: cfg.ExpectedDuration, DeviationPct: cfg.DurationDeviationPct}
+	}
+
+	rc := resources.New(resources.Limits{
+		DiskPath:    cfg.ResourceDiskPath,
+		MinDiskFree: uint64(cfg.ResourceMinDiskFreeMB) << 20,
+		MaxMemory:   uint64(cfg.ResourceMaxMemoryMB) << 20,
+	})
+
+	sched := scheduler.New(log, scheduler.Config{
+		CooldownAfter: cfg.CooldownAfter,
+		CooldownBase:  cfg.CooldownBase,
+		CooldownMax:   cfg.CooldownMax,
+		HistorySize:   cfg.HistorySize,
 
-	sched := scheduler.New(log)
-	for _, def := range jobs.RegisterAll(client, rep, log) {
+		WatchdogMaxInterval: cfg.WatchdogMaxInterval,
+		Resources:           rc,
+		StateFile:           cfg.StateFile,
+		CompressState:       cfg.PersistCompress,
+	})
+	deps := jobs.Deps{
+		Client:   client,
+		Reporter: rep,
+		Manifest: mw,
+		Metrics:  m,
+		Baseline: bl,
+		Events:   ev,
+		Emitter:  re,
+		Log:      log,
+	}
+	for _, def := range jobs.RegisterAll(deps) {
 		if err := sched.Register(def); err != nil {
 			log.Fatal().Err(err).Str("job", def.Name).Msg("failed to register job")
 		}
 	}
 
-	srv := server.New(cfg.HTTPPort, sched, log)
+	var pprofPort string
+	if cfg.EnablePprof {
+		pprofPort = cfg.PprofPort
+	}
+	srv := server.New(server.Config{
+		Port:                   cfg.HTTPPort,
+		BindAddr:               cfg.BindAddr,
+		TriggerToken:           cfg.TriggerAuthToken,
+		TriggerDebounce:        cfg.TriggerDebounce,
+		RunMetadata:            cfg.RunMetadata,
+		MaxConsecutiveFailures: cfg.MaxConsecutiveFailures,
+		ProbeCacheTTL:          cfg.ProbeCacheTTL,
+		UnixSocket:             cfg.HTTPUnixSocket,
+		Resources:              rc,
+		StuckThreshold:         cfg.LivenessStuckThreshold,
+		PprofPort:              pprofPort,
+		PprofBindAddr:          cfg.PprofBindAddr,
+	}, sched, client, m, log)
 	go srv.Start()
 
 	sched.Start()
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
-	<-quit
 
-	log.Info().Msg("shutdown signal received")
+	// SIGUSR1 triggers an immediate run of every job, for manual ops
+	// in environments without HTTP access.
+	runSignal := make(chan os.Signal, 1)
+	signal.Notify(runSignal, syscall.SIGUSR1)
+	go func() {
+		for range runSignal {
+			runAllNow(sched, log)
+		}
+	}()
+
+	// SIGQUIT logs a state snapshot, then falls through to Go's default
+	// goroutine stack dump and exit, so a wedged pod can be inspected.
+	quitSignal := make(chan os.Signal, 1)
+	signal.Notify(quitSignal, syscall.SIGQUIT)
+	go func() {
+		<-quitSignal
+		dumpState(sched, client, log)
+		signal.Reset(syscall.SIGQUIT)
+		syscall.Kill(os.Getpid(), syscall.SIGQUIT)
+	}()
+
+	serveUntilShutdown(cfg, quit, srv, sched, log)
 
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.DrainTimeout)
 	defer cancel()
-
-	if err := sched.Shutdown(ctx); err != nil {
-		log.Error().Err(err).Msg("scheduler shutdown error")
+	client.WaitNotifications(ctx)
+	if ev != nil {
+		ev.Wait(ctx)
 	}
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Error().Err(err).Msg("http server shutdown error")
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error().Err(err).Msg("tracing shutdown error")
 	}
 
 	log.Info().Msg("shutdown complete")
 }
+
+// logOutput is where log lines go: stdout, unless "-output -" claims stdout
+// for the run result, in which case stderr, so the result stays parseable.
+func logOutput(output string) io.Writer {
+	if output == "-" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// runAllNow triggers an immediate run of every registered job.
+func runAllNow(sched *scheduler.Scheduler, log zerolog.Logger) {
+	log.Info().Msg("run signal received, triggering all jobs")
+	for _, st := range sched.Statuses() {
+		if err := sched.RunNow(st.Name); err != nil {
+			log.Error().Err(err).Str("job", st.Name).Msg("failed to trigger job")
+			continue
+		}
+		log.Info().Str("job", st.Name).Msg("job triggered by signal")
+	}
+}
+
+// runPipeline triggers a single named pipeline, waits for it to finish and
+// returns the process exit code: 0 on success, 1 otherwise. If output is
+// set, the result is also written there as JSON (see writeRunOutput).
+func runPipeline(ctx context.Context, client *pipeline.Client, m *metrics.Metrics, name, output string, log zerolog.Logger) int {
+	// An invalid name never starts a run and would only add a junk job label.
+	record := pipeline.ValidPipelineName(name)
+	if record {
+		m.RunStarted(name)
+	}
+	result := client.TriggerOne(ctx, name)
+	if record {
+		if result.Skipped {
+			m.RunSkipped(name)
+		} else {
+			m.RunFinished(name, result.Success, result.Duration, result.Attempts)
+		}
+	}
+	if output != "" {
+		if err := writeRunOutput(output, os.Stdout, name, result); err != nil {
+			log.Error().Err(err).Str("output", output).Msg("failed to write run output")
+		}
+	}
+	if !result.Success {
+		log.Error().Err(result.Error).Str("pipeline", name).Msg("pipeline run failed")
+		return 1
+	}
+	log.Info().
+		Str("pipeline", name).
+		Str("job_id", result.JobID).
+		Dur("duration", result.Duration).
+		Msg("pipeline run succeeded")
+	return 0
+}
+
+// pushMetrics pushes m to the Pushgateway before a -pipeline run exits,
+// waiting at most timeout so an unreachable gateway cannot hold up the exit.
+// A failed push is logged, never fatal: the run's exit code stands.
+func pushMetrics(m *metrics.Metrics, gatewayURL, job string, timeout time.Duration, log zerolog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := m.Push(ctx, gatewayURL, job); err != nil {
+		log.Warn().Err(err).Str("job", job).Msg("failed to push metrics")
+		return
+	}
+	log.Info().Str("job", job).Msg("metrics pushed")
+}
+
+// runOutput is the machine-readable result of a -pipeline run.
+type runOutput struct {
+	Pipeline   string                             `json:"pipeline"`
+	RunID      string                             `json:"run_id,omitempty"`
+	JobID      string                             `json:"job_id,omitempty"`
+	Success    bool                               `json:"success"`
+	Degraded   bool                               `json:"degraded"`
+	DryRun     bool                               `json:"dry_run,omitempty"`
+	Attempts   int                                `json:"attempts"`
+	StatusCode int                                `json:"status_code,omitempty"`
+	DurationMs int64                              `json:"duration_ms"`
+	Error      string                             `json:"error,omitempty"`
+	Results    map[string]pipeline.PipelineResult `json:"results,omitempty"`
+}
+
+// writeRunOutput writes result as JSON to path, or as one line to stdout
+// when path is "-". The file is written to a temp name and renamed so a
+// reader never sees a partial result.
+func writeRunOutput(path string, stdout io.Writer, name string, result pipeline.TriggerResult) error {
+	out := runOutput{
+		Pipeline:   name,
+		RunID:      result.RunID,
+		JobID:      result.JobID,
+		Success:    result.Success,
+		Degraded:   result.Degraded,
+		DryRun:     result.DryRun,
+		Attempts:   result.Attempts,
+		StatusCode: result.StatusCode,
+		DurationMs: result.Duration.Milliseconds(),
+	}
+	if result.Error != nil {
+		out.Error = result.Error.Error()
+	}
+	if result.JobDetails != nil {
+		out.Results = result.JobDetails.Results
+	}
+
+	if path == "-" {
+		return json.NewEncoder(stdout).Encode(out)
+	}
+	body, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".run-output-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(body, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// printSchedule writes the next n fire times after from of each job, in UTC.
+func printSchedule(w io.Writer, defs []scheduler.JobDef, from time.Time, n int) error {
+	for _, def := range defs {
+		runs, err := scheduler.NextRuns(def.Schedule, def.WithSeconds, from, n)
+		if err != nil {
+			return fmt.Errorf("job %s: %w", def.Name, err)
+		}
+		fmt.Fprintf(w, "%s (%s)\n", def.Name, def.Schedule)
+		for _, t := range runs {
+			fmt.Fprintf(w, "  %s\n", t.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// dumpState logs a snapshot of the service's runtime state for debugging.
+func dumpState(sched *scheduler.Scheduler, client *pipeline.Client, log zerolog.Logger) {
+	log.Warn().
+		Str("uptime", sched.Uptime()).
+		Int("in_flight", sched.InFlight()).
+		Bool("pipeline_job_running", client.Busy()).
+		Int("goroutines", runtime.NumGoroutine()).
+		Interface("jobs", sched.Statuses()).
+		Msg("state dump")
+}
+
+// serveUntilShutdown blocks until quit delivers a signal or MAX_LIFETIME
+// expires, then stops srv and sched.
+func serveUntilShutdown(cfg *config.Config, quit <-chan os.Signal, srv *server.Server, sched *scheduler.Scheduler, log zerolog.Logger) {
+	expired := make(chan struct{})
+	if cfg.MaxLifetime > 0 {
+		log.Info().
+			Time("restart_at", time.Now().Add(cfg.MaxLifetime)).
+			Msg("max lifetime set, planned restart")
+		go func() {
+			waitLifetime(cfg.MaxLifetime, lifetimeRecheck, func() bool { return sched.InFlight() > 0 })
+			close(expired)
+		}()
+	}
+
+	select {
+	case <-quit:
+		log.Info().Msg("shutdown signal received")
+	case <-expired:
+		log.Info().Msg("max lifetime reached, shutting down for restart")
+	}
+
+	srv.MarkShuttingDown()
+
+	// Stop the listener and cancel /trigger runs alongside the scheduler
+	// drain, each with its own DRAIN_TIMEOUT, so a slow scheduled job cannot
+	// keep the server accepting requests or eat the runs' drain time.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("http server shutdown error")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+		defer cancel()
+		if err := sched.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("scheduler shutdown error")
+		}
+	}()
+	wg.Wait()
+}
+
+// lifetimeRecheck is how often an expired lifetime re-checks for in-flight runs.
+const lifetimeRecheck = 5 * time.Second
+
+// waitLifetime blocks until lifetime has elapsed and busy reports false,
+// re-checking every recheck so an in-flight run is never interrupted.
+func waitLifetime(lifetime, recheck time.Duration, busy func() bool) {
+	time.Sleep(lifetime)
+	for busy() {
+		time.Sleep(recheck)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cron-runner/internal/config"
+	"cron-runner/internal/metrics"
+	"cron-runner/internal/pipeline"
+	"cron-runner/internal/scheduler"
+	"cron-runner/internal/server"
+
+	"github.com/rs/zerolog"
+)
+
+func TestWaitLifetimeReturnsAfterLifetime(t *testing.T) {
+	start := time.Now()
+	waitLifetime(20*time.Millisecond, time.Millisecond, func() bool { return false })
+
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("expected to wait at least 20ms, waited %v", elapsed)
+	}
+}
+
+func TestWaitLifetimeWaitsForInFlightRun(t *testing.T) {
+	var checks atomic.Int32
+	busy := func() bool { return checks.Add(1) <= 3 }
+
+	done := make(chan struct{})
+	go func() {
+		waitLifetime(time.Millisecond, time.Millisecond, busy)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected shutdown once the run finished")
+	}
+	if n := checks.Load(); n != 4 {
+		t.Fatalf("expected 4 busy checks, got %d", n)
+	}
+}
+
+func TestServeUntilShutdownStopsOnMaxLifetime(t *testing.T) {
+	var buf bytes.Buffer
+	log := zerolog.New(zerolog.SyncWriter(&buf))
+	sched := scheduler.New(log, scheduler.Config{})
+	client, err := pipeline.NewClient(&config.Config{BackendURL: "http://example.test"}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := server.New(server.Config{BindAddr: "127.0.0.1", Port: "0"}, sched, client, metrics.New(), log)
+	served := make(chan struct{})
+	go func() {
+		srv.Start()
+		close(served)
+	}()
+	sched.Start()
+
+	done := make(chan struct{})
+	go func() {
+		cfg := &config.Config{MaxLifetime: 20 * time.Millisecond, DrainTimeout: time.Second}
+		serveUntilShutdown(cfg, make(chan os.Signal), srv, sched, log)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected shutdown once MAX_LIFETIME expired")
+	}
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the HTTP server to stop")
+	}
+	for _, msg := range []string{"max lifetime reached", "trigger_runs_drained", "scheduler_stopped"} {
+		if !strings.Contains(buf.String(), msg) {
+			t.Fatalf("expected %q in the logs, got:\n%s", msg, buf.String())
+		}
+	}
+}
+
+type signalTask struct {
+	ran chan struct{}
+}
+
+func (t *signalTask) Name() string { return "signal-test" }
+
+func (t *signalTask) Run(ctx context.Context) error {
+	t.ran <- struct{}{}
+	return nil
+}
+
+func TestRunAllNowTriggersJobs(t *testing.T) {
+	log := zerolog.Nop()
+	sched := scheduler.New(log, scheduler.Config{})
+	task := &signalTask{ran: make(chan struct{}, 1)}
+	if err := sched.Register(scheduler.JobDef{
+		Name:      "signal-test",
+		Schedule:  "0 0 1 1 *",
+		Singleton: true,
+		Task:      task,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sched.Start()
+	defer sched.Shutdown(context.Background())
+
+	runAllNow(sched, log)
+
+	select {
+	case <-task.ran:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected job to run after run signal")
+	}
+}
+
+func TestRunPipelineExitCode(t *testing.T) {
+	var startPath string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			startPath = r.URL.Path
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed"}}`)
+	}))
+	defer backend.Close()
+
+	log := zerolog.Nop()
+	client, err := pipeline.NewClient(&config.Config{
+		BackendURL:          backend.URL,
+		PipelineAuth:        "test-token",
+		RequestTimeout:      time.Second,
+		PollInitialInterval: time.Millisecond,
+		PollMaxInterval:     time.Millisecond,
+		PollMaxWaitTime:     time.Second,
+	}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runPipeline(context.Background(), client, metrics.New(), "post-game", "", log); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	if startPath != "/v1/internal/pipelines/post-game" {
+		t.Fatalf("expected per-pipeline endpoint, got %q", startPath)
+	}
+	if code := runPipeline(context.Background(), client, metrics.New(), "../all", "", log); code != 1 {
+		t.Fatalf("expected exit code 1 for an invalid name, got %d", code)
+	}
+}
+
+func TestRunPipelineWritesOutput(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed","results":{"games":{"pipeline_name":"games","status":"success","duration_seconds":2.5,"records_processed":40}}}}`)
+	}))
+	defer backend.Close()
+
+	log := zerolog.Nop()
+	client, err := pipeline.NewClient(&config.Config{
+		BackendURL:          backend.URL,
+		PipelineAuth:        "test-token",
+		RequestTimeout:      time.Second,
+		PollInitialInterval: time.Millisecond,
+		PollMaxInterval:     time.Millisecond,
+		PollMaxWaitTime:     time.Second,
+	}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "result.json")
+	if code := runPipeline(context.Background(), client, metrics.New(), "post-game", path, log); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	var out runOutput
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if !out.Success || out.JobID != "job-1" || out.Pipeline != "post-game" || out.Attempts != 1 || out.RunID == "" {
+		t.Fatalf("unexpected output: %+v", out)
+	}
+	if r := out.Results["games"]; r.Status != "success" || r.RecordsProcessed != 40 {
+		t.Fatalf("expected per-pipeline results, got %+v", out.Results)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("expected only the output file, got %d entries", len(entries))
+	}
+
+	// A failed run still writes its result and exits 1.
+	if code := runPipeline(context.Background(), client, metrics.New(), "../all", path, log); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	data, _ = os.ReadFile(path)
+	out = runOutput{}
+	json.Unmarshal(data, &out)
+	if out.Success || !strings.Contains(out.Error, "invalid pipeline name") {
+		t.Fatalf("expected failed result with error, got %+v", out)
+	}
+
+	var stdout bytes.Buffer
+	if err := writeRunOutput("-", &stdout, "post-game", pipeline.TriggerResult{Success: true, JobID: "job-2"}); err != nil {
+		t.Fatalf("write to stdout: %v", err)
+	}
+	if !strings.HasPrefix(stdout.String(), `{"pipeline":"post-game","job_id":"job-2","success":true`) || strings.Count(stdout.String(), "\n") != 1 {
+		t.Fatalf("expected one JSON line on stdout, got %q", stdout.String())
+	}
+}
+
+func TestLogOutputMovesToStderrWhenResultOnStdout(t *testing.T) {
+	if logOutput("-") != os.Stderr {
+		t.Fatalf("expected logs on stderr with -output -")
+	}
+	for _, output := range []string{"", "result.json"} {
+		if logOutput(output) != os.Stdout {
+			t.Fatalf("expected logs on stdout with -output %q", output)
+		}
+	}
+}
+
+func TestDumpStateLogsSnapshot(t *testing.T) {
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	sched := scheduler.New(log, scheduler.Config{})
+	if err := sched.Register(scheduler.JobDef{
+		Name:     "dump-test",
+		Schedule: "0 0 1 1 *",
+		Task:     &signalTask{ran: make(chan struct{}, 1)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	client, err := pipeline.NewClient(&config.Config{BackendURL: "http://example.test"}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+
+	dumpState(sched, client, log)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid log line %q: %v", buf.String(), err)
+	}
+	if entry["message"] != "state dump" {
+		t.Fatalf("expected state dump message, got %v", entry["message"])
+	}
+	for _, field := range []string{"uptime", "in_flight", "pipeline_job_running", "goroutines", "jobs"} {
+		if _, ok := entry[field]; !ok {
+			t.Fatalf("expected field %q in %v", field, entry)
+		}
+	}
+	if g, _ := entry["goroutines"].(float64); g < 1 {
+		t.Fatalf("expected a positive goroutine count, got %v", entry["goroutines"])
+	}
+	jobs, _ := entry["jobs"].([]any)
+	if len(jobs) != 1 {
+		t.Fatalf("expected 1 job in the dump, got %v", entry["jobs"])
+	}
+}
+
+func TestPrintSchedule(t *testing.T) {
+	defs := []scheduler.JobDef{
+		{Name: "pre-game", Schedule: "0/15 14-23,0-1 * * *"},
+		{Name: "live-stats", Schedule: "*/30 * 16-23,0-6 * * *", WithSeconds: true},
+	}
+	from := time.Date(2025, 1, 10, 16, 0, 10, 0, time.UTC)
+
+	var buf bytes.Buffer
+	if err := printSchedule(&buf, defs, from, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `pre-game (0/15 14-23,0-1 * * *)
+  2025-01-10T16:15:00Z
+  2025-01-10T16:30:00Z
+live-stats (*/30 * 16-23,0-6 * * *)
+  2025-01-10T16:00:30Z
+  2025-01-10T16:01:00Z
+`
+	if buf.String() != want {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestPrintScheduleRejectsInvalidExpression(t *testing.T) {
+	defs := []scheduler.JobDef{{Name: "broken", Schedule: "0 25 * * *"}}
+	err := printSchedule(io.Discard, defs, time.Now(), 1)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("expected an error naming the job, got %v", err)
+	}
+}
+
+func TestRunPipelinePushesMetricsOnExit(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"data":{"job_id":"job-1"}}`)
+			return
+		}
+		io.WriteString(w, `{"data":{"job_id":"job-1","status":"completed"}}`)
+	}))
+	defer backend.Close()
+
+	var pushes atomic.Int32
+	var pushed string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/metrics/job/post-game" {
+			t.Errorf("unexpected push %s %s", r.Method, r.URL.Path)
+		}
+		b, _ := io.ReadAll(r.Body)
+		pushed = string(b)
+		pushes.Add(1)
+	}))
+	defer gateway.Close()
+
+	log := zerolog.Nop()
+	client, err := pipeline.NewClient(&config.Config{
+		BackendURL:          backend.URL,
+		PipelineAuth:        "test-token",
+		RequestTimeout:      time.Second,
+		PollInitialInterval: time.Millisecond,
+		PollMaxInterval:     time.Millisecond,
+		PollMaxWaitTime:     time.Second,
+	}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.New()
+	if code := runPipeline(context.Background(), client, m, "post-game", "", log); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	pushMetrics(m, gateway.URL, "post-game", time.Second, log)
+
+	if pushes.Load() != 1 {
+		t.Fatalf("expected one push, got %d", pushes.Load())
+	}
+	if !strings.Contains(pushed, `cron_runner_runs_total{result="success"} 1`) {
+		t.Fatalf("expected the run in the pushed metrics, got:\n%s", pushed)
+	}
+
+	// An unresponsive gateway is abandoned after the timeout.
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hung.Close()
+	defer close(release)
+	start := time.Now()
+	pushMetrics(m, hung.URL, "post-game", 50*time.Millisecond, log)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the push to give up after its timeout, took %v", elapsed)
+	}
+}